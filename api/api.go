@@ -17,6 +17,9 @@
 package api
 
 import (
+	"context"
+	"crypto/subtle"
+	stderrors "errors"
 	"fmt"
 	"github.com/cbrgm/authproxy/api/errors"
 	"github.com/cbrgm/authproxy/api/v1/models"
@@ -26,17 +29,72 @@ import (
 	"github.com/cbrgm/authproxy/internal"
 	"github.com/cbrgm/authproxy/provider"
 	"github.com/go-chi/chi"
+	chimiddleware "github.com/go-chi/chi/middleware"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/metrics"
 	"github.com/go-kit/kit/metrics/prometheus"
 	"github.com/go-openapi/loads"
 	restful "github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/swag"
 	prom "github.com/prometheus/client_golang/prometheus"
+	"net"
 	"net/http"
+	"strings"
+	"time"
 )
 
+const (
+	// TokenReviewKind is the kind of TokenReview objects
+	TokenReviewKind = "TokenReview"
+	// AuthenticationV1 is the authentication.k8s.io/v1 apiVersion
+	AuthenticationV1 = "authentication.k8s.io/v1"
+	// AuthenticationV1beta1 is the authentication.k8s.io/v1beta1 apiVersion
+	AuthenticationV1beta1 = "authentication.k8s.io/v1beta1"
+
+	// SubjectAccessReviewKind is the kind of SubjectAccessReview objects
+	SubjectAccessReviewKind = "SubjectAccessReview"
+	// AuthorizationV1 is the authorization.k8s.io/v1 apiVersion
+	AuthorizationV1 = "authorization.k8s.io/v1"
+	// AuthorizationV1beta1 is the authorization.k8s.io/v1beta1 apiVersion
+	AuthorizationV1beta1 = "authorization.k8s.io/v1beta1"
+
+	// ScopesExtraKey is the extra key holding the scopes of a token, they are returned as scope by /introspect
+	ScopesExtraKey = "scopes"
+
+	// unsupportedTokenReview is the error of requests which are no TokenReview of a supported apiVersion
+	unsupportedTokenReview = "unsupported apiVersion or kind, expected a TokenReview of " + AuthenticationV1 + " or " + AuthenticationV1beta1
+)
+
+// Config represents the api configuration parameters
+type Config struct {
+	// Audiences rejects tokens not valid for at least one of the audiences, if set
+	Audiences []string
+	// Authorizer decides about SubjectAccessReviews, the provider is used if it implements provider.Authorizer
+	Authorizer provider.Authorizer
+	// IntrospectionClients maps the client ids allowed to introspect tokens to their secrets.
+	// Without clients every introspection request is unauthorized
+	IntrospectionClients map[string]string
+	// CacheTTL and CacheNegativeTTL enable caching authenticated and unauthenticated decisions of tokens
+	CacheTTL         time.Duration
+	CacheNegativeTTL time.Duration
+	// CacheMaxEntries and CacheMaxBytes limit the number and estimated memory of cached decisions (optional)
+	CacheMaxEntries int
+	CacheMaxBytes   int
+	// LockoutUserFailures and LockoutClientIPFailures are the failed logins of a username or client IP
+	// within LockoutWindow before further logins are delayed with exponential backoff, 0 disables tracking them
+	LockoutUserFailures     int
+	LockoutClientIPFailures int
+	LockoutWindow           time.Duration
+	// LockoutDuration is the longest delay logins are rejected for (optional)
+	LockoutDuration time.Duration
+	// LockoutStore holds the failure counters, an in-memory store is used if unset
+	LockoutStore internal.LockoutStore
+	// Breaker enforces timeouts of provider calls and fails fast while the provider is broken (optional)
+	Breaker *internal.Breaker
+}
+
 // NewV1 returns a new configured authproxy v1 multiplexer to be used by a router
-func NewV1(prv *provider.Provider, logger log.Logger) (*chi.Mux, error) {
+func NewV1(prv provider.ContextProvider, cfg Config, logger log.Logger) (*chi.Mux, error) {
 	router := chi.NewRouter()
 
 	// load the metrics
@@ -63,13 +121,51 @@ func NewV1(prv *provider.Provider, logger log.Logger) (*chi.Mux, error) {
 	// initialize services
 	var sv internal.Service
 	sv = internal.NewService(prv)
+	if cfg.Breaker != nil {
+		sv = internal.NewBreakerService(cfg.Breaker, sv)
+	}
+	sv = internal.NewSingleflightService(sv)
+	if cfg.CacheTTL > 0 || cfg.CacheNegativeTTL > 0 {
+		sv = internal.NewCacheService(internal.CacheConfig{
+			TTL:         cfg.CacheTTL,
+			NegativeTTL: cfg.CacheNegativeTTL,
+			MaxEntries:  cfg.CacheMaxEntries,
+			MaxBytes:    cfg.CacheMaxBytes,
+		}, apiMetrics.CacheLookups, apiMetrics.CacheEvictions, sv)
+	}
 	sv = internal.NewLoggingService(log.WithPrefix(logger, "service", "provider"), sv)
+	if cfg.LockoutUserFailures > 0 || cfg.LockoutClientIPFailures > 0 {
+		sv = internal.NewLockoutService(internal.LockoutConfig{
+			UserFailures:     cfg.LockoutUserFailures,
+			ClientIPFailures: cfg.LockoutClientIPFailures,
+			Window:           cfg.LockoutWindow,
+			Duration:         cfg.LockoutDuration,
+			Store:            cfg.LockoutStore,
+		}, sv)
+	}
 	sv = internal.NewMetricsService(apiMetrics.LoginAttempts, sv)
+	if len(cfg.Audiences) > 0 {
+		sv = internal.NewAudienceService(cfg.Audiences, sv)
+	}
+
+	authz := cfg.Authorizer
+	if authz == nil {
+		authz, _ = prv.(provider.Authorizer)
+	}
+
+	var asv internal.AuthorizationService
+	if authz != nil {
+		asv = internal.NewAuthorizationService(authz)
+		asv = internal.NewLoggingAuthorizationService(log.WithPrefix(logger, "service", "authorizer"), asv)
+		asv = internal.NewMetricsAuthorizationService(apiMetrics.AuthorizationDecisions, asv)
+	}
 
 	// initialize handlers
 
 	api.AuthAuthenticateHandler = NewAuthenticationHandler(sv)
 	api.AuthLoginHandler = NewLoginHandler(sv)
+	api.AuthAuthorizeHandler = NewAuthorizationHandler(asv)
+	api.AuthIntrospectHandler = NewIntrospectionHandler(sv, cfg.IntrospectionClients)
 
 	router.Mount("/", api.Serve(nil))
 
@@ -79,7 +175,10 @@ func NewV1(prv *provider.Provider, logger log.Logger) (*chi.Mux, error) {
 
 // APIMetrics represents all authproxy metrics
 type APIMetrics struct {
-	LoginAttempts metrics.Counter
+	LoginAttempts          metrics.Counter
+	AuthorizationDecisions metrics.Counter
+	CacheLookups           metrics.Counter
+	CacheEvictions         metrics.Counter
 }
 
 // apiMetrics returns new metrics for metrics endpoint
@@ -91,8 +190,26 @@ func apiMetrics() *APIMetrics {
 			Namespace: namespace,
 			Subsystem: "authentication",
 			Name:      "login_attempts_total",
-			Help:      "Number of login attempts that succeeded and failed",
+			Help:      "Number of login attempts that succeeded, failed and were rejected because of too many failures",
 		}, []string{"status"}),
+		AuthorizationDecisions: prometheus.NewCounterFrom(prom.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "authorization",
+			Name:      "decisions_total",
+			Help:      "Number of authorization decisions by outcome",
+		}, []string{"decision"}),
+		CacheLookups: prometheus.NewCounterFrom(prom.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "lookups_total",
+			Help:      "Number of authentication decisions looked up in the cache by hit or miss",
+		}, []string{"result"}),
+		CacheEvictions: prometheus.NewCounterFrom(prom.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "evictions_total",
+			Help:      "Number of authentication decisions evicted from the cache because they expired or the cache was full",
+		}, []string{"reason"}),
 	}
 }
 
@@ -100,47 +217,344 @@ func apiMetrics() *APIMetrics {
 func NewAuthenticationHandler(sv internal.Service) auth.AuthenticateHandlerFunc {
 	return func(params auth.AuthenticateParams) restful.Responder {
 		request := params.Body
-		tokenReview, err := sv.Authenticate(request.Spec.Token)
+		if !isSupportedTokenReview(request) {
+			// never echo an unknown apiVersion, the message names the supported ones instead
+			apiVersion := request.APIVersion
+			if !isSupportedAuthenticationVersion(apiVersion) {
+				apiVersion = AuthenticationV1
+			}
+			err := errors.NewBadRequest(unsupportedTokenReview)
+			return auth.NewAuthenticateBadRequest().WithPayload(errorResponse(apiVersion, err))
+		}
 
-		if errors.IsUnauthorized(err) {
-			tokenReview = defaultResponse()
-			return auth.NewAuthenticateUnauthorized().WithPayload(tokenReview)
+		tokenReview, err := sv.Authenticate(params.HTTPRequest.Context(), provider.AuthenticateRequest{
+			Token:     request.Spec.Token,
+			Audiences: request.Spec.Audiences,
+			Metadata:  requestMetadata(params.HTTPRequest),
+		})
+		if err == nil && tokenReview == nil {
+			err = errors.NewInternalError(fmt.Errorf("provider returned no TokenReview"))
 		}
 
-		if errors.IsInternalError(err) || err != nil {
-			tokenReview = defaultResponse()
-			return auth.NewLoginInternalServerError().WithPayload(tokenReview)
+		if err != nil {
+			err = typedError(err)
+			payload := errorResponse(request.APIVersion, err)
+
+			switch errors.ReasonForError(err) {
+			case http.StatusBadRequest:
+				return auth.NewAuthenticateBadRequest().WithPayload(payload)
+			case http.StatusUnauthorized:
+				return auth.NewAuthenticateUnauthorized().WithPayload(payload)
+			case http.StatusForbidden:
+				return auth.NewAuthenticateForbidden().WithPayload(payload)
+			case http.StatusTooManyRequests:
+				return auth.NewAuthenticateTooManyRequests().
+					WithRetryAfter(int64(errors.RetryAfterSeconds(err))).
+					WithPayload(payload)
+			case http.StatusServiceUnavailable:
+				return auth.NewAuthenticateServiceUnavailable().WithPayload(payload)
+			default:
+				return auth.NewAuthenticateInternalServerError().WithPayload(payload)
+			}
 		}
 
-		return auth.NewAuthenticateOK().WithPayload(tokenReview)
+		// reply with the apiVersion the caller sent, regardless of the provider's choice
+		response := *tokenReview
+		response.APIVersion = request.APIVersion
+		response.Kind = TokenReviewKind
+
+		return auth.NewAuthenticateOK().WithPayload(&response)
 	}
 }
 
 // NewLoginHandler returns a new handler for /login endpoint
 func NewLoginHandler(sv internal.Service) auth.LoginHandlerFunc {
 	return func(params auth.LoginParams, user *models.Principal) restful.Responder {
-		tokenReview, err := sv.Login(user.Username, user.Password)
+		tokenReview, err := sv.Login(params.HTTPRequest.Context(), provider.LoginRequest{
+			Username: user.Username,
+			Password: user.Password,
+			Metadata: requestMetadata(params.HTTPRequest),
+		})
+		if err == nil && tokenReview == nil {
+			err = errors.NewInternalError(fmt.Errorf("provider returned no TokenReview"))
+		}
+
+		if err != nil {
+			err = typedError(err)
+			payload := errorResponse(AuthenticationV1beta1, err)
 
-		if errors.IsUnauthorized(err) {
-			tokenReview = defaultResponse()
-			return auth.NewLoginUnauthorized().WithPayload(tokenReview)
+			switch errors.ReasonForError(err) {
+			case http.StatusBadRequest:
+				return auth.NewLoginBadRequest().WithPayload(payload)
+			case http.StatusUnauthorized:
+				return auth.NewLoginUnauthorized().WithPayload(payload)
+			case http.StatusForbidden:
+				return auth.NewLoginForbidden().WithPayload(payload)
+			case http.StatusTooManyRequests:
+				return auth.NewLoginTooManyRequests().
+					WithRetryAfter(int64(errors.RetryAfterSeconds(err))).
+					WithPayload(payload)
+			case http.StatusServiceUnavailable:
+				return auth.NewLoginServiceUnavailable().WithPayload(payload)
+			default:
+				return auth.NewLoginInternalServerError().WithPayload(payload)
+			}
 		}
 
-		if errors.IsInternalError(err) || err != nil {
-			tokenReview = defaultResponse()
-			return auth.NewLoginInternalServerError().WithPayload(tokenReview)
+		return auth.NewLoginOK().WithPayload(tokenReview)
+	}
+}
+
+// NewAuthorizationHandler returns a new handler for /authorize endpoint
+// Without an authorization service every request is answered with no opinion
+func NewAuthorizationHandler(sv internal.AuthorizationService) auth.AuthorizeHandlerFunc {
+	return func(params auth.AuthorizeParams) restful.Responder {
+		request := params.Body
+		if !isSupportedSubjectAccessReview(request) {
+			payload := accessReviewResponse(request, &models.SubjectAccessReviewStatus{
+				EvaluationError: "unsupported apiVersion or kind, or invalid attributes",
+			})
+			return auth.NewAuthorizeBadRequest().WithPayload(payload)
+		}
+
+		if sv == nil {
+			return auth.NewAuthorizeOK().WithPayload(accessReviewResponse(request, &models.SubjectAccessReviewStatus{
+				Reason: "no authorizer configured",
+			}))
+		}
+
+		status, err := sv.Authorize(params.HTTPRequest.Context(), provider.AuthorizeRequest{
+			Spec:     request.Spec,
+			Metadata: requestMetadata(params.HTTPRequest),
+		})
+		if err == nil && status == nil {
+			err = errors.NewInternalError(fmt.Errorf("authorizer returned no status"))
+		}
+
+		if err != nil {
+			err = typedError(err)
+			payload := accessReviewResponse(request, &models.SubjectAccessReviewStatus{
+				EvaluationError: errors.SafeMessage(err),
+			})
+
+			switch errors.ReasonForError(err) {
+			case http.StatusBadRequest:
+				return auth.NewAuthorizeBadRequest().WithPayload(payload)
+			case http.StatusServiceUnavailable:
+				return auth.NewAuthorizeServiceUnavailable().WithPayload(payload)
+			default:
+				return auth.NewAuthorizeInternalServerError().WithPayload(payload)
+			}
 		}
 
-		return auth.NewAuthenticateOK().WithPayload(tokenReview)
+		return auth.NewAuthorizeOK().WithPayload(accessReviewResponse(request, status))
 	}
 }
 
-func defaultResponse() *models.TokenReviewRequest {
+// NewIntrospectionHandler returns a new handler for /introspect endpoint (RFC 7662).
+// Clients authenticate with their client id and secret using basic auth.
+func NewIntrospectionHandler(sv internal.Service, clients map[string]string) auth.IntrospectHandlerFunc {
+	return func(params auth.IntrospectParams, client *models.Principal) restful.Responder {
+		if !isIntrospectionClient(clients, client) {
+			return auth.NewIntrospectUnauthorized().
+				WithWWWAuthenticate(`Basic realm="authproxy"`).
+				WithPayload(&models.IntrospectionError{
+					Error:            "invalid_client",
+					ErrorDescription: "client authentication failed",
+				})
+		}
+
+		tokenReview, err := sv.Authenticate(params.HTTPRequest.Context(), provider.AuthenticateRequest{
+			Token:    params.Token,
+			Metadata: requestMetadata(params.HTTPRequest),
+		})
+		if err == nil && tokenReview == nil {
+			err = errors.NewInternalError(fmt.Errorf("provider returned no TokenReview"))
+		}
+
+		if err != nil {
+			err = typedError(err)
+			payload := &models.IntrospectionError{ErrorDescription: errors.SafeMessage(err)}
+
+			switch errors.ReasonForError(err) {
+			case http.StatusBadRequest:
+				payload.Error = "invalid_request"
+				return auth.NewIntrospectBadRequest().WithPayload(payload)
+			case http.StatusUnauthorized, http.StatusForbidden:
+				// rejected tokens are inactive, not an error of the introspection request
+				return auth.NewIntrospectOK().WithPayload(&models.IntrospectionResponse{Active: swag.Bool(false)})
+			case http.StatusTooManyRequests:
+				payload.Error = "temporarily_unavailable"
+				return auth.NewIntrospectTooManyRequests().
+					WithRetryAfter(int64(errors.RetryAfterSeconds(err))).
+					WithPayload(payload)
+			case http.StatusServiceUnavailable:
+				payload.Error = "temporarily_unavailable"
+				return auth.NewIntrospectServiceUnavailable().WithPayload(payload)
+			default:
+				payload.Error = "server_error"
+				return auth.NewIntrospectInternalServerError().WithPayload(payload)
+			}
+		}
+
+		return auth.NewIntrospectOK().WithPayload(introspectionResponse(tokenReview))
+	}
+}
+
+// isIntrospectionClient checks the client credentials in constant time
+func isIntrospectionClient(clients map[string]string, client *models.Principal) bool {
+	if client == nil || client.Username == "" {
+		return false
+	}
+	secret, ok := clients[client.Username]
+	if !ok || secret == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(client.Password)) == 1
+}
+
+// introspectionResponse converts a TokenReview to an introspection response
+func introspectionResponse(trr *models.TokenReviewRequest) *models.IntrospectionResponse {
+	status := trr.Status
+	if status == nil || !status.Authenticated {
+		return &models.IntrospectionResponse{Active: swag.Bool(false)}
+	}
+
+	response := &models.IntrospectionResponse{
+		Active: swag.Bool(true),
+		Aud:    status.Audiences,
+		Exp:    status.Expiration,
+	}
+
+	if user := status.User; user != nil {
+		response.Sub = user.UID
+		if response.Sub == "" {
+			response.Sub = user.Username
+		}
+		response.Username = user.Username
+		response.Scope = scope(user.Extra)
+		response.Groups = user.Groups
+		response.Extra = user.Extra
+	}
+
+	return response
+}
+
+// scope returns the space separated scopes of the ScopesExtraKey extra, the string values of
+// map[string][]string and map[string]interface{} extras are supported
+func scope(extra interface{}) string {
+	var scopes []string
+
+	switch e := extra.(type) {
+	case map[string][]string:
+		scopes = e[ScopesExtraKey]
+	case map[string]interface{}:
+		switch v := e[ScopesExtraKey].(type) {
+		case []string:
+			scopes = v
+		case []interface{}:
+			for _, item := range v {
+				scopes = append(scopes, fmt.Sprint(item))
+			}
+		case string:
+			scopes = strings.Fields(v)
+		}
+	}
+
+	return strings.Join(scopes, " ")
+}
+
+// typedError converts err to a typed error.
+// Expired or cancelled requests are treated as unavailable backends.
+func typedError(err error) error {
+	if errors.ReasonForError(err) != 0 {
+		return err
+	}
+	if stderrors.Is(err, context.DeadlineExceeded) || stderrors.Is(err, context.Canceled) {
+		return errors.NewServiceUnavailable(err)
+	}
+	return errors.NewInternalError(err)
+}
+
+// requestMetadata extracts information about the client from a request
+func requestMetadata(r *http.Request) provider.RequestMetadata {
+	md := provider.RequestMetadata{
+		RequestID: chimiddleware.GetReqID(r.Context()),
+		UserAgent: r.UserAgent(),
+		ClientIP:  r.RemoteAddr,
+	}
+
+	if md.RequestID == "" {
+		md.RequestID = r.Header.Get("X-Request-Id")
+	}
+
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		md.ClientIP = host
+	}
+
+	if r.TLS != nil {
+		md.PeerCertificates = r.TLS.PeerCertificates
+	}
+
+	return md
+}
+
+// isSupportedTokenReview checks whether a TokenReview has a known apiVersion and kind
+func isSupportedTokenReview(trr *models.TokenReviewRequest) bool {
+	if trr.Kind != TokenReviewKind || trr.Spec == nil {
+		return false
+	}
+	return isSupportedAuthenticationVersion(trr.APIVersion)
+}
+
+// isSupportedAuthenticationVersion checks whether apiVersion is a known authentication.k8s.io version
+func isSupportedAuthenticationVersion(apiVersion string) bool {
+	switch apiVersion {
+	case AuthenticationV1, AuthenticationV1beta1:
+		return true
+	}
+	return false
+}
+
+// isSupportedSubjectAccessReview checks whether a SubjectAccessReview has a known apiVersion and kind
+// and exactly one of resource and non resource attributes
+func isSupportedSubjectAccessReview(sar *models.SubjectAccessReview) bool {
+	if sar.Kind != SubjectAccessReviewKind || sar.Spec == nil {
+		return false
+	}
+	if (sar.Spec.ResourceAttributes == nil) == (sar.Spec.NonResourceAttributes == nil) {
+		return false
+	}
+	switch sar.APIVersion {
+	case AuthorizationV1, AuthorizationV1beta1:
+		return true
+	}
+	return false
+}
+
+// accessReviewResponse returns a SubjectAccessReview answering the request with the given status
+func accessReviewResponse(request *models.SubjectAccessReview, status *models.SubjectAccessReviewStatus) *models.SubjectAccessReview {
+	return &models.SubjectAccessReview{
+		APIVersion: request.APIVersion,
+		Kind:       SubjectAccessReviewKind,
+		Status:     status,
+	}
+}
+
+// defaultResponse returns an unauthenticated TokenReview for the given apiVersion
+func defaultResponse(apiVersion string) *models.TokenReviewRequest {
 	return &models.TokenReviewRequest{
-		APIVersion: "authentication.k8s.io/v1beta1",
-		Kind:       "TokenReview",
+		APIVersion: apiVersion,
+		Kind:       TokenReviewKind,
 		Status: &models.TokenReviewStatus{
 			Authenticated: false,
 		},
 	}
 }
+
+// errorResponse returns an unauthenticated TokenReview carrying the redacted reason of err
+func errorResponse(apiVersion string, err error) *models.TokenReviewRequest {
+	trr := defaultResponse(apiVersion)
+	trr.Status.Error = errors.SafeMessage(err)
+	return trr
+}
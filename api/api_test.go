@@ -0,0 +1,310 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/api/v1/restapi/operations/auth"
+	"github.com/cbrgm/authproxy/internal"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/fake"
+	"github.com/go-kit/kit/log"
+	"github.com/go-openapi/runtime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var (
+	testRouterOnce sync.Once
+	testRouter     http.Handler
+	testRouterErr  error
+)
+
+// newTestRouter returns a router serving the fake provider.
+// The router is shared by all tests because the api metrics can only be registered once.
+func newTestRouter(t *testing.T) http.Handler {
+	testRouterOnce.Do(func() {
+		testRouter, testRouterErr = NewV1(provider.NewContextAdapter(fake.NewFakeProvider()), Config{
+			IntrospectionClients: map[string]string{"resource-server": "secret"},
+		}, log.NewNopLogger())
+	})
+	if testRouterErr != nil {
+		t.Fatal(testRouterErr)
+	}
+	return testRouter
+}
+
+func TestAuthenticateVersionNegotiation(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		apiVersion string
+		kind       string
+		status     int
+		response   string
+	}{
+		{apiVersion: AuthenticationV1, kind: TokenReviewKind, status: http.StatusOK, response: AuthenticationV1},
+		{apiVersion: AuthenticationV1beta1, kind: TokenReviewKind, status: http.StatusOK, response: AuthenticationV1beta1},
+		{apiVersion: "authentication.k8s.io/v2", kind: TokenReviewKind, status: http.StatusBadRequest, response: AuthenticationV1},
+		{apiVersion: "<script>", kind: TokenReviewKind, status: http.StatusBadRequest, response: AuthenticationV1},
+		{apiVersion: AuthenticationV1beta1, kind: "SubjectAccessReview", status: http.StatusBadRequest, response: AuthenticationV1beta1},
+	}
+
+	for _, tt := range tests {
+		body := `{"apiVersion":"` + tt.apiVersion + `","kind":"` + tt.kind + `","spec":{"token":"AbCdEf123456"}}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/authenticate", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d", tt.apiVersion, tt.kind, tt.status, rec.Code)
+			continue
+		}
+
+		var trr models.TokenReviewRequest
+		if err := json.NewDecoder(rec.Body).Decode(&trr); err != nil {
+			t.Fatal(err)
+		}
+		if trr.APIVersion != tt.response {
+			t.Errorf("expected apiVersion %s, got %s", tt.response, trr.APIVersion)
+		}
+		if rec.Code == http.StatusBadRequest && trr.Status.Error != unsupportedTokenReview {
+			t.Errorf("%s: expected fixed error message, got %q", tt.apiVersion, trr.Status.Error)
+		}
+	}
+}
+
+type errorProvider struct {
+	err error
+}
+
+func (p *errorProvider) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	return nil, p.err
+}
+
+func (p *errorProvider) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	return nil, p.err
+}
+
+func TestAuthenticateErrorMapping(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		reason string
+	}{
+		{err: errors.NewBadRequest("malformed token"), status: http.StatusBadRequest, reason: "malformed token"},
+		{err: errors.NewUnauthorized("token expired"), status: http.StatusUnauthorized, reason: "token expired"},
+		{err: errors.NewForbidden("user disabled"), status: http.StatusForbidden, reason: "user disabled"},
+		{err: errors.NewTooManyRequests("slow down", 5), status: http.StatusTooManyRequests, reason: "slow down"},
+		{err: errors.NewServiceUnavailable(fmt.Errorf("ldap down")), status: http.StatusServiceUnavailable, reason: "Service Unavailable"},
+		{err: fmt.Errorf("secret connection string"), status: http.StatusInternalServerError, reason: "Internal Server Error"},
+		{err: context.DeadlineExceeded, status: http.StatusServiceUnavailable, reason: "Service Unavailable"},
+	}
+
+	for _, tt := range tests {
+		handler := NewAuthenticationHandler(internal.NewService(&errorProvider{err: tt.err}))
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/authenticate", nil)
+		rec := httptest.NewRecorder()
+
+		handler(auth.AuthenticateParams{
+			HTTPRequest: req,
+			Body: &models.TokenReviewRequest{
+				APIVersion: AuthenticationV1,
+				Kind:       TokenReviewKind,
+				Spec:       &models.TokenReviewSpec{Token: "AbCdEf123456"},
+			},
+		}).WriteResponse(rec, runtime.JSONProducer())
+
+		if rec.Code != tt.status {
+			t.Errorf("%v: expected status %d, got %d", tt.err, tt.status, rec.Code)
+		}
+
+		var trr models.TokenReviewRequest
+		if err := json.NewDecoder(rec.Body).Decode(&trr); err != nil {
+			t.Fatal(err)
+		}
+		if trr.Status.Error != tt.reason {
+			t.Errorf("%v: expected error %q, got %q", tt.err, tt.reason, trr.Status.Error)
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	handler := NewAuthorizationHandler(internal.NewAuthorizationService(fake.NewFakeProvider()))
+
+	tests := []struct {
+		review  *models.SubjectAccessReview
+		status  int
+		allowed bool
+	}{
+		{
+			review: &models.SubjectAccessReview{
+				APIVersion: AuthorizationV1,
+				Kind:       SubjectAccessReviewKind,
+				Spec: &models.SubjectAccessReviewSpec{
+					User:               "foo",
+					ResourceAttributes: &models.ResourceAttributes{Verb: "get", Resource: "pods"},
+				},
+			},
+			status:  http.StatusOK,
+			allowed: true,
+		},
+		{
+			review: &models.SubjectAccessReview{
+				APIVersion: AuthorizationV1beta1,
+				Kind:       SubjectAccessReviewKind,
+				Spec: &models.SubjectAccessReviewSpec{
+					User:                  "bar",
+					NonResourceAttributes: &models.NonResourceAttributes{Verb: "get", Path: "/healthz"},
+				},
+			},
+			status:  http.StatusOK,
+			allowed: false,
+		},
+		{
+			review: &models.SubjectAccessReview{
+				APIVersion: AuthorizationV1,
+				Kind:       SubjectAccessReviewKind,
+				Spec: &models.SubjectAccessReviewSpec{
+					User:                  "foo",
+					ResourceAttributes:    &models.ResourceAttributes{Verb: "get", Resource: "pods"},
+					NonResourceAttributes: &models.NonResourceAttributes{Verb: "get", Path: "/healthz"},
+				},
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			review: &models.SubjectAccessReview{
+				APIVersion: AuthenticationV1,
+				Kind:       SubjectAccessReviewKind,
+				Spec: &models.SubjectAccessReviewSpec{
+					User:               "foo",
+					ResourceAttributes: &models.ResourceAttributes{Verb: "get", Resource: "pods"},
+				},
+			},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+
+		handler(auth.AuthorizeParams{
+			HTTPRequest: httptest.NewRequest(http.MethodPost, "/v1/authorize", nil),
+			Body:        tt.review,
+		}).WriteResponse(rec, runtime.JSONProducer())
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.review.Spec.User, tt.status, rec.Code)
+			continue
+		}
+
+		var sar models.SubjectAccessReview
+		if err := json.NewDecoder(rec.Body).Decode(&sar); err != nil {
+			t.Fatal(err)
+		}
+		if sar.APIVersion != tt.review.APIVersion {
+			t.Errorf("expected apiVersion %s, got %s", tt.review.APIVersion, sar.APIVersion)
+		}
+		if sar.Status.Allowed != tt.allowed {
+			t.Errorf("%s: expected allowed to be %v", tt.review.Spec.User, tt.allowed)
+		}
+	}
+}
+
+func TestIntrospect(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		client string
+		secret string
+		token  string
+		status int
+		active bool
+	}{
+		{client: "resource-server", secret: "secret", token: "AbCdEf123456", status: http.StatusOK, active: true},
+		{client: "resource-server", secret: "secret", token: "invalid", status: http.StatusOK, active: false},
+		{client: "resource-server", secret: "wrong", token: "AbCdEf123456", status: http.StatusUnauthorized},
+		{client: "unknown", secret: "secret", token: "AbCdEf123456", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		body := url.Values{"token": {tt.token}}.Encode()
+		req := httptest.NewRequest(http.MethodPost, "/v1/introspect", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(tt.client, tt.secret)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s/%s: expected status %d, got %d: %s", tt.client, tt.token, tt.status, rec.Code, rec.Body)
+			continue
+		}
+		if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != `Basic realm="authproxy"` {
+			t.Errorf("%s: expected WWW-Authenticate challenge, got %q", tt.client, rec.Header().Get("WWW-Authenticate"))
+		}
+		if rec.Code != http.StatusOK {
+			continue
+		}
+
+		var response models.IntrospectionResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		if response.Active == nil || *response.Active != tt.active {
+			t.Errorf("%s: expected active %v, got %v", tt.token, tt.active, response.Active)
+		}
+	}
+}
+
+func TestIntrospectionResponse(t *testing.T) {
+	response := introspectionResponse(&models.TokenReviewRequest{
+		Status: &models.TokenReviewStatus{
+			Authenticated: true,
+			User:          &models.UserInfo{Username: "foo", UID: "1", Groups: []string{"developers"}, Extra: map[string][]string{"scopes": {"read", "write"}}},
+			Audiences:     []string{"api"},
+			Expiration:    1565000000,
+		},
+	})
+
+	if !*response.Active || response.Sub != "1" || response.Username != "foo" || response.Exp != 1565000000 {
+		t.Errorf("unexpected introspection response %+v", response)
+	}
+	if len(response.Groups) != 1 || len(response.Aud) != 1 {
+		t.Errorf("expected groups and audiences to be returned, got %+v", response)
+	}
+	if response.Scope != "read write" {
+		t.Errorf("expected scopes to be returned as scope, got %q", response.Scope)
+	}
+
+	// extras decoded from JSON
+	if s := scope(map[string]interface{}{"scopes": []interface{}{"read"}}); s != "read" {
+		t.Errorf("expected scope of decoded extra, got %q", s)
+	}
+}
+
+func TestParseIntrospectionClients(t *testing.T) {
+	clients, err := ParseIntrospectionClients(strings.NewReader("# resource servers\nbilling:s3cr3t\n\nreports:pass:word\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clients) != 2 || clients["billing"] != "s3cr3t" || clients["reports"] != "pass:word" {
+		t.Errorf("unexpected clients %v", clients)
+	}
+
+	for _, invalid := range []string{"billing", "billing:", ":s3cr3t", "billing:a\nbilling:b"} {
+		if _, err := ParseIntrospectionClients(strings.NewReader(invalid)); err == nil {
+			t.Errorf("expected %q to be invalid", invalid)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// LoadIntrospectionClients reads the clients allowed to introspect tokens from a file.
+// Every line contains a client id and its secret separated by a colon, empty lines and lines starting with # are ignored
+func LoadIntrospectionClients(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseIntrospectionClients(f)
+}
+
+// ParseIntrospectionClients parses introspection clients in the format of LoadIntrospectionClients
+func ParseIntrospectionClients(r io.Reader) (map[string]string, error) {
+	clients := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(text, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("line %d: expected client_id:secret", line)
+		}
+		if _, ok := clients[parts[0]]; ok {
+			return nil, fmt.Errorf("line %d: duplicate client %q", line, parts[0])
+		}
+
+		clients[parts[0]] = parts[1]
+	}
+
+	return clients, scanner.Err()
+}
@@ -17,8 +17,9 @@
 package errors
 
 import (
-	"net/http"
+	"errors"
 	"fmt"
+	"net/http"
 )
 
 // APIStatus is exposed by errors that can be converted to an api.Status object
@@ -27,9 +28,23 @@ type APIStatus interface {
 	Status() int
 }
 
+// Sentinel errors to be used with errors.Is, they match any StatusError with the same http status.
+var (
+	ErrBadRequest         = &StatusError{HTTPStatus: http.StatusBadRequest, Message: http.StatusText(http.StatusBadRequest)}
+	ErrUnauthorized       = &StatusError{HTTPStatus: http.StatusUnauthorized, Message: http.StatusText(http.StatusUnauthorized)}
+	ErrForbidden          = &StatusError{HTTPStatus: http.StatusForbidden, Message: http.StatusText(http.StatusForbidden)}
+	ErrTooManyRequests    = &StatusError{HTTPStatus: http.StatusTooManyRequests, Message: http.StatusText(http.StatusTooManyRequests)}
+	ErrInternalError      = &StatusError{HTTPStatus: http.StatusInternalServerError, Message: http.StatusText(http.StatusInternalServerError)}
+	ErrServiceUnavailable = &StatusError{HTTPStatus: http.StatusServiceUnavailable, Message: http.StatusText(http.StatusServiceUnavailable)}
+)
+
 type StatusError struct {
 	HTTPStatus int
 	Message    string
+	// RetryAfterSeconds is the time a client should wait before retrying, if set
+	RetryAfterSeconds int
+	// Err is the underlying cause, it is never exposed to clients
+	Err error
 }
 
 func (e *StatusError) Error() string {
@@ -42,15 +57,60 @@ func (e *StatusError) Status() int {
 	return e.HTTPStatus
 }
 
+// Unwrap returns the underlying cause of e, if any.
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is a StatusError with the same http status as e.
+func (e *StatusError) Is(target error) bool {
+	t, ok := target.(*StatusError)
+	return ok && t.HTTPStatus == e.HTTPStatus
+}
+
 // ReasonForError returns the HTTP status for a particular error.
 func ReasonForError(err error) int {
-	switch t := err.(type) {
-	case APIStatus:
-		return t.Status()
+	var status APIStatus
+	if errors.As(err, &status) {
+		return status.Status()
+	}
+	return 0
+}
+
+// SafeMessage returns a message for err which can be exposed to clients.
+// Messages of server side errors are redacted as they might contain sensitive details.
+func SafeMessage(err error) string {
+	var se *StatusError
+	if !errors.As(err, &se) {
+		return http.StatusText(http.StatusInternalServerError)
+	}
+	if se.HTTPStatus >= http.StatusInternalServerError || se.Message == "" {
+		return http.StatusText(se.HTTPStatus)
+	}
+	return se.Message
+}
+
+// RetryAfterSeconds returns the seconds a client should wait before retrying, 0 if unknown.
+func RetryAfterSeconds(err error) int {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.RetryAfterSeconds
 	}
 	return 0
 }
 
+// NewBadRequest returns an error indicating the request is invalid and cannot be processed.
+func NewBadRequest(reason string) *StatusError {
+	msg := reason
+	if len(msg) == 0 {
+		msg = "bad request"
+	}
+	return &StatusError{
+		HTTPStatus: http.StatusBadRequest,
+		Message:    msg,
+	}
+}
+
 // NewUnauthorized returns an error indicating the client is not authorized to perform the requested
 // action.
 func NewUnauthorized(reason string) *StatusError {
@@ -64,21 +124,77 @@ func NewUnauthorized(reason string) *StatusError {
 	}
 }
 
+// NewForbidden returns an error indicating the client is known but not allowed to perform the requested action.
+func NewForbidden(reason string) *StatusError {
+	msg := reason
+	if len(msg) == 0 {
+		msg = "forbidden"
+	}
+	return &StatusError{
+		HTTPStatus: http.StatusForbidden,
+		Message:    msg,
+	}
+}
+
+// NewTooManyRequests returns an error indicating the client has sent too many requests
+// and should retry after the given amount of seconds.
+func NewTooManyRequests(reason string, retryAfterSeconds int) *StatusError {
+	msg := reason
+	if len(msg) == 0 {
+		msg = "too many requests"
+	}
+	return &StatusError{
+		HTTPStatus:        http.StatusTooManyRequests,
+		Message:           msg,
+		RetryAfterSeconds: retryAfterSeconds,
+	}
+}
+
 // NewInternalError returns an error indicating the item is invalid and cannot be processed.
 func NewInternalError(err error) *StatusError {
 	return &StatusError{
 		HTTPStatus: http.StatusInternalServerError,
 		Message:    fmt.Sprintf("Internal error occurred: %v", err),
+		Err:        err,
 	}
 }
 
+// NewServiceUnavailable returns an error indicating a backend required to process the request is unavailable.
+func NewServiceUnavailable(err error) *StatusError {
+	return &StatusError{
+		HTTPStatus: http.StatusServiceUnavailable,
+		Message:    fmt.Sprintf("Service unavailable: %v", err),
+		Err:        err,
+	}
+}
+
+// IsBadRequest determines if err is an error which indicates that the request is invalid.
+func IsBadRequest(err error) bool {
+	return ReasonForError(err) == http.StatusBadRequest
+}
+
 // IsUnauthorized determines if err is an error which indicates that the request is unauthorized and
 // requires authentication by the user.
 func IsUnauthorized(err error) bool {
 	return ReasonForError(err) == http.StatusUnauthorized
 }
 
+// IsForbidden determines if err is an error which indicates that the request is forbidden.
+func IsForbidden(err error) bool {
+	return ReasonForError(err) == http.StatusForbidden
+}
+
+// IsTooManyRequests determines if err is an error which indicates that the client sent too many requests.
+func IsTooManyRequests(err error) bool {
+	return ReasonForError(err) == http.StatusTooManyRequests
+}
+
 // IsInternalError determines if err is an error which indicates an internal server error.
 func IsInternalError(err error) bool {
 	return ReasonForError(err) == http.StatusInternalServerError
 }
+
+// IsServiceUnavailable determines if err is an error which indicates that a backend is unavailable.
+func IsServiceUnavailable(err error) bool {
+	return ReasonForError(err) == http.StatusServiceUnavailable
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"net/http"
 	"errors"
+	"fmt"
 )
 
 func TestErrorNew(t *testing.T) {
@@ -15,3 +16,53 @@ func TestErrorNew(t *testing.T) {
 		t.Errorf("expected to be %v", http.StatusUnauthorized)
 	}
 }
+
+func TestErrorIsAs(t *testing.T) {
+	tests := []struct {
+		err      error
+		sentinel error
+		status   int
+	}{
+		{err: NewBadRequest("message"), sentinel: ErrBadRequest, status: http.StatusBadRequest},
+		{err: NewUnauthorized("message"), sentinel: ErrUnauthorized, status: http.StatusUnauthorized},
+		{err: NewForbidden("message"), sentinel: ErrForbidden, status: http.StatusForbidden},
+		{err: NewTooManyRequests("message", 10), sentinel: ErrTooManyRequests, status: http.StatusTooManyRequests},
+		{err: NewInternalError(errors.New("message")), sentinel: ErrInternalError, status: http.StatusInternalServerError},
+		{err: NewServiceUnavailable(errors.New("message")), sentinel: ErrServiceUnavailable, status: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		wrapped := fmt.Errorf("wrapped: %w", tt.err)
+
+		if !errors.Is(wrapped, tt.sentinel) {
+			t.Errorf("expected %v to be %v", wrapped, tt.sentinel)
+		}
+
+		var se *StatusError
+		if !errors.As(wrapped, &se) || se.Status() != tt.status {
+			t.Errorf("expected %v to be a StatusError with status %v", wrapped, tt.status)
+		}
+
+		if ReasonForError(wrapped) != tt.status {
+			t.Errorf("expected reason %v, got %v", tt.status, ReasonForError(wrapped))
+		}
+	}
+
+	if errors.Is(NewForbidden("message"), ErrUnauthorized) {
+		t.Errorf("expected forbidden not to be unauthorized")
+	}
+}
+
+func TestSafeMessage(t *testing.T) {
+	if msg := SafeMessage(NewInternalError(errors.New("ldap password is secret"))); msg != http.StatusText(http.StatusInternalServerError) {
+		t.Errorf("expected internal error to be redacted, got %q", msg)
+	}
+
+	if msg := SafeMessage(errors.New("untyped")); msg != http.StatusText(http.StatusInternalServerError) {
+		t.Errorf("expected untyped error to be redacted, got %q", msg)
+	}
+
+	if msg := SafeMessage(NewUnauthorized("token expired")); msg != "token expired" {
+		t.Errorf("expected unauthorized message, got %q", msg)
+	}
+}
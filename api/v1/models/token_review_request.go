@@ -16,7 +16,7 @@ import (
 // swagger:model TokenReviewRequest
 type TokenReviewRequest struct {
 
-	// api version
+	// Either authentication.k8s.io/v1 or authentication.k8s.io/v1beta1
 	APIVersion string `json:"apiVersion,omitempty"`
 
 	// kind
@@ -15,6 +15,9 @@ import (
 // swagger:model TokenReviewSpec
 type TokenReviewSpec struct {
 
+	// A list of the identifiers that the resource server presented with the token identifies as
+	Audiences []string `json:"audiences"`
+
 	// token
 	Token string `json:"token,omitempty"`
 }
@@ -16,9 +16,18 @@ import (
 // swagger:model TokenReviewStatus
 type TokenReviewStatus struct {
 
+	// The audiences the token is valid for, a subset of the audiences in the spec
+	Audiences []string `json:"audiences"`
+
 	// Authenticated is true if the token is valid
 	Authenticated bool `json:"authenticated,omitempty"`
 
+	// Error indicates that the token couldn't be checked
+	Error string `json:"error,omitempty"`
+
+	// Unix time in seconds the token expires at, if known by the provider. Not part of the Kubernetes API
+	Expiration int64 `json:"expiration,omitempty"`
+
 	// user
 	User *UserInfo `json:"user,omitempty"`
 }
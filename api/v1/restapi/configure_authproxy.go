@@ -34,6 +34,8 @@ func configureAPI(api *operations.AuthproxyAPI) http.Handler {
 
 	api.JSONConsumer = runtime.JSONConsumer()
 
+	api.UrlformConsumer = runtime.DiscardConsumer
+
 	api.JSONProducer = runtime.JSONProducer()
 
 	// Applies when the Authorization header is set with the Basic scheme
@@ -51,6 +53,16 @@ func configureAPI(api *operations.AuthproxyAPI) http.Handler {
 			return middleware.NotImplemented("operation auth.Authenticate has not yet been implemented")
 		})
 	}
+	if api.AuthAuthorizeHandler == nil {
+		api.AuthAuthorizeHandler = auth.AuthorizeHandlerFunc(func(params auth.AuthorizeParams) middleware.Responder {
+			return middleware.NotImplemented("operation auth.Authorize has not yet been implemented")
+		})
+	}
+	if api.AuthIntrospectHandler == nil {
+		api.AuthIntrospectHandler = auth.IntrospectHandlerFunc(func(params auth.IntrospectParams, principal *models.Principal) middleware.Responder {
+			return middleware.NotImplemented("operation auth.Introspect has not yet been implemented")
+		})
+	}
 	if api.AuthLoginHandler == nil {
 		api.AuthLoginHandler = auth.LoginHandlerFunc(func(params auth.LoginParams, principal *models.Principal) middleware.Responder {
 			return middleware.NotImplemented("operation auth.Login has not yet been implemented")
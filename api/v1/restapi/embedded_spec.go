@@ -70,17 +70,180 @@ func init() {
               "$ref": "#/definitions/TokenReviewRequest"
             }
           },
+          "400": {
+            "description": "bad request (unsupported apiVersion or kind)",
+            "schema": {
+              "$ref": "#/definitions/TokenReviewRequest"
+            }
+          },
           "401": {
             "description": "unauthorized",
             "schema": {
               "$ref": "#/definitions/TokenReviewRequest"
             }
           },
+          "403": {
+            "description": "forbidden",
+            "schema": {
+              "$ref": "#/definitions/TokenReviewRequest"
+            }
+          },
+          "429": {
+            "description": "too many requests",
+            "schema": {
+              "$ref": "#/definitions/TokenReviewRequest"
+            },
+            "headers": {
+              "Retry-After": {
+                "description": "seconds to wait before retrying",
+                "type": "integer"
+              }
+            }
+          },
           "500": {
             "description": "internal server error",
             "schema": {
               "$ref": "#/definitions/TokenReviewRequest"
             }
+          },
+          "503": {
+            "description": "service unavailable",
+            "schema": {
+              "$ref": "#/definitions/TokenReviewRequest"
+            }
+          }
+        }
+      }
+    },
+    "/authorize": {
+      "post": {
+        "description": "authorizes users",
+        "consumes": [
+          "application/json"
+        ],
+        "produces": [
+          "application/json"
+        ],
+        "tags": [
+          "auth"
+        ],
+        "summary": "verifies user permissions",
+        "operationId": "authorize",
+        "parameters": [
+          {
+            "description": "SubjectAccessReview object that needs to be verified",
+            "name": "body",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "$ref": "#/definitions/SubjectAccessReview"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK (authorization decision made)",
+            "schema": {
+              "$ref": "#/definitions/SubjectAccessReview"
+            }
+          },
+          "400": {
+            "description": "bad request (unsupported apiVersion or kind)",
+            "schema": {
+              "$ref": "#/definitions/SubjectAccessReview"
+            }
+          },
+          "500": {
+            "description": "internal server error",
+            "schema": {
+              "$ref": "#/definitions/SubjectAccessReview"
+            }
+          },
+          "503": {
+            "description": "service unavailable",
+            "schema": {
+              "$ref": "#/definitions/SubjectAccessReview"
+            }
+          }
+        }
+      }
+    },
+    "/introspect": {
+      "post": {
+        "security": [
+          {
+            "basicAuth": []
+          }
+        ],
+        "description": "OAuth2 token introspection (RFC 7662), clients authenticate with their client credentials",
+        "consumes": [
+          "application/x-www-form-urlencoded"
+        ],
+        "produces": [
+          "application/json"
+        ],
+        "tags": [
+          "auth"
+        ],
+        "summary": "introspects tokens for OAuth2 resource servers",
+        "operationId": "introspect",
+        "parameters": [
+          {
+            "type": "string",
+            "description": "The token to be introspected",
+            "name": "token",
+            "in": "formData",
+            "required": true
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK (the token is active or not)",
+            "schema": {
+              "$ref": "#/definitions/IntrospectionResponse"
+            }
+          },
+          "400": {
+            "description": "bad request",
+            "schema": {
+              "$ref": "#/definitions/IntrospectionError"
+            }
+          },
+          "401": {
+            "description": "unauthorized (invalid client credentials)",
+            "schema": {
+              "$ref": "#/definitions/IntrospectionError"
+            },
+            "headers": {
+              "WWW-Authenticate": {
+                "description": "the authentication scheme clients have to use (RFC 6749)",
+                "type": "string"
+              }
+            }
+          },
+          "429": {
+            "description": "too many requests",
+            "schema": {
+              "$ref": "#/definitions/IntrospectionError"
+            },
+            "headers": {
+              "Retry-After": {
+                "description": "seconds to wait before retrying",
+                "type": "integer"
+              }
+            }
+          },
+          "500": {
+            "description": "internal server error",
+            "schema": {
+              "$ref": "#/definitions/IntrospectionError"
+            }
+          },
+          "503": {
+            "description": "service unavailable",
+            "schema": {
+              "$ref": "#/definitions/IntrospectionError"
+            }
           }
         }
       }
@@ -105,23 +268,138 @@ func init() {
               "$ref": "#/definitions/TokenReviewRequest"
             }
           },
+          "400": {
+            "description": "bad request",
+            "schema": {
+              "$ref": "#/definitions/TokenReviewRequest"
+            }
+          },
           "401": {
             "description": "unauthorized",
             "schema": {
               "$ref": "#/definitions/TokenReviewRequest"
             }
           },
+          "403": {
+            "description": "forbidden",
+            "schema": {
+              "$ref": "#/definitions/TokenReviewRequest"
+            }
+          },
+          "429": {
+            "description": "too many requests",
+            "schema": {
+              "$ref": "#/definitions/TokenReviewRequest"
+            },
+            "headers": {
+              "Retry-After": {
+                "description": "seconds to wait before retrying",
+                "type": "integer"
+              }
+            }
+          },
           "500": {
             "description": "internal server error",
             "schema": {
               "$ref": "#/definitions/TokenReviewRequest"
             }
+          },
+          "503": {
+            "description": "service unavailable",
+            "schema": {
+              "$ref": "#/definitions/TokenReviewRequest"
+            }
           }
         }
       }
     }
   },
   "definitions": {
+    "IntrospectionError": {
+      "description": "IntrospectionError is returned if a token couldn't be introspected",
+      "type": "object",
+      "properties": {
+        "error": {
+          "description": "The OAuth2 error code",
+          "type": "string",
+          "example": "invalid_client"
+        },
+        "error_description": {
+          "description": "A human readable description of the error",
+          "type": "string"
+        }
+      }
+    },
+    "IntrospectionResponse": {
+      "description": "IntrospectionResponse is the OAuth2 token introspection response (RFC 7662)",
+      "type": "object",
+      "required": [
+        "active"
+      ],
+      "properties": {
+        "active": {
+          "description": "Active is true if the token is valid",
+          "type": "boolean",
+          "example": "true"
+        },
+        "aud": {
+          "description": "The audiences the token is valid for",
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        },
+        "exp": {
+          "description": "Unix time in seconds the token expires at, if known",
+          "type": "integer",
+          "format": "int64",
+          "example": 1565000000
+        },
+        "extra": {
+          "description": "Any additional information provided by the authenticator",
+          "type": "object",
+          "additionalProperties": true
+        },
+        "groups": {
+          "description": "The names of groups the user is a part of",
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        },
+        "scope": {
+          "description": "The space separated scopes of the token, taken from the scopes extra of the user",
+          "type": "string",
+          "example": "read write"
+        },
+        "sub": {
+          "description": "The subject of the token, the uid of the user or the username if the user has no uid",
+          "type": "string",
+          "example": "43"
+        },
+        "username": {
+          "description": "The name of the user",
+          "type": "string",
+          "example": "foo"
+        }
+      }
+    },
+    "NonResourceAttributes": {
+      "description": "NonResourceAttributes describes a request to a non resource path of the apiserver",
+      "type": "object",
+      "properties": {
+        "path": {
+          "description": "The URL path of the request",
+          "type": "string",
+          "example": "/healthz"
+        },
+        "verb": {
+          "description": "The standard HTTP verb",
+          "type": "string",
+          "example": "get"
+        }
+      }
+    },
     "Principal": {
       "description": "Principal contains information about the user",
       "type": "object",
@@ -138,13 +416,139 @@ func init() {
         }
       }
     },
+    "ResourceAttributes": {
+      "description": "ResourceAttributes describes a request to a resource of the apiserver",
+      "type": "object",
+      "properties": {
+        "group": {
+          "description": "The API group of the resource",
+          "type": "string",
+          "example": "apps"
+        },
+        "name": {
+          "description": "The name of the resource being requested",
+          "type": "string",
+          "example": "nginx"
+        },
+        "namespace": {
+          "description": "The namespace of the action being requested, empty for cluster scoped resources",
+          "type": "string",
+          "example": "default"
+        },
+        "resource": {
+          "description": "One of the existing resource types",
+          "type": "string",
+          "example": "deployments"
+        },
+        "subresource": {
+          "description": "One of the existing resource types",
+          "type": "string",
+          "example": "status"
+        },
+        "verb": {
+          "description": "A kubernetes resource API verb, like get, list, watch, create, update, delete, proxy",
+          "type": "string",
+          "example": "get"
+        },
+        "version": {
+          "description": "The API version of the resource",
+          "type": "string",
+          "example": "v1"
+        }
+      }
+    },
+    "SubjectAccessReview": {
+      "description": "SubjectAccessReview is issued by K8s to this service to check whether a user can perform an action",
+      "type": "object",
+      "properties": {
+        "apiVersion": {
+          "description": "Either authorization.k8s.io/v1 or authorization.k8s.io/v1beta1",
+          "type": "string",
+          "example": "authorization.k8s.io/v1"
+        },
+        "kind": {
+          "type": "string",
+          "example": "SubjectAccessReview"
+        },
+        "spec": {
+          "$ref": "#/definitions/SubjectAccessReviewSpec"
+        },
+        "status": {
+          "$ref": "#/definitions/SubjectAccessReviewStatus"
+        }
+      }
+    },
+    "SubjectAccessReviewSpec": {
+      "description": "SubjectAccessReviewSpec contains the user and the action to be authorized. Exactly one of resourceAttributes and nonResourceAttributes must be set",
+      "type": "object",
+      "properties": {
+        "extra": {
+          "description": "Any additional information provided by the authenticator",
+          "type": "object",
+          "additionalProperties": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          }
+        },
+        "groups": {
+          "description": "The groups you're testing for",
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        },
+        "nonResourceAttributes": {
+          "$ref": "#/definitions/NonResourceAttributes"
+        },
+        "resourceAttributes": {
+          "$ref": "#/definitions/ResourceAttributes"
+        },
+        "uid": {
+          "description": "Information about the requesting user",
+          "type": "string",
+          "example": "43"
+        },
+        "user": {
+          "description": "The user you're testing for",
+          "type": "string",
+          "example": "foo"
+        }
+      }
+    },
+    "SubjectAccessReviewStatus": {
+      "description": "SubjectAccessReviewStatus is the result of the authorization request",
+      "type": "object",
+      "properties": {
+        "allowed": {
+          "description": "Allowed is true if the action would be allowed",
+          "type": "boolean",
+          "example": "true"
+        },
+        "denied": {
+          "description": "Denied is true if the action would be denied, otherwise other authorizers may allow the action",
+          "type": "boolean",
+          "example": "false"
+        },
+        "evaluationError": {
+          "description": "EvaluationError indicates that some error occurred during the authorization check",
+          "type": "string"
+        },
+        "reason": {
+          "description": "Reason is optional. It indicates why a request was allowed or denied",
+          "type": "string"
+        }
+      }
+    },
     "TokenReviewRequest": {
       "description": "TokenReviewRequest is issued by K8s to this service",
       "type": "object",
       "properties": {
         "apiVersion": {
+          "description": "Either authentication.k8s.io/v1 or authentication.k8s.io/v1beta1",
           "type": "string",
-          "example": "authentication.k8s.io/v1beta1"
+          "example": "authentication.k8s.io/v1"
         },
         "kind": {
           "type": "string",
@@ -162,6 +566,13 @@ func init() {
       "description": "TokenReviewSpec contains the token being reviewed",
       "type": "object",
       "properties": {
+        "audiences": {
+          "description": "A list of the identifiers that the resource server presented with the token identifies as",
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        },
         "token": {
           "type": "string",
           "example": "12354234123141"
@@ -172,11 +583,28 @@ func init() {
       "description": "TokenReviewStatus is the result of the token authentication request",
       "type": "object",
       "properties": {
+        "audiences": {
+          "description": "The audiences the token is valid for, a subset of the audiences in the spec",
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        },
         "authenticated": {
           "description": "Authenticated is true if the token is valid",
           "type": "boolean",
           "example": "true"
         },
+        "error": {
+          "description": "Error indicates that the token couldn't be checked",
+          "type": "string"
+        },
+        "expiration": {
+          "description": "Unix time in seconds the token expires at, if known by the provider. Not part of the Kubernetes API",
+          "type": "integer",
+          "format": "int64",
+          "example": 1565000000
+        },
         "user": {
           "$ref": "#/definitions/UserInfo"
         }
@@ -286,17 +714,180 @@ func init() {
               "$ref": "#/definitions/TokenReviewRequest"
             }
           },
+          "400": {
+            "description": "bad request (unsupported apiVersion or kind)",
+            "schema": {
+              "$ref": "#/definitions/TokenReviewRequest"
+            }
+          },
           "401": {
             "description": "unauthorized",
             "schema": {
               "$ref": "#/definitions/TokenReviewRequest"
             }
           },
+          "403": {
+            "description": "forbidden",
+            "schema": {
+              "$ref": "#/definitions/TokenReviewRequest"
+            }
+          },
+          "429": {
+            "description": "too many requests",
+            "schema": {
+              "$ref": "#/definitions/TokenReviewRequest"
+            },
+            "headers": {
+              "Retry-After": {
+                "description": "seconds to wait before retrying",
+                "type": "integer"
+              }
+            }
+          },
           "500": {
             "description": "internal server error",
             "schema": {
               "$ref": "#/definitions/TokenReviewRequest"
             }
+          },
+          "503": {
+            "description": "service unavailable",
+            "schema": {
+              "$ref": "#/definitions/TokenReviewRequest"
+            }
+          }
+        }
+      }
+    },
+    "/authorize": {
+      "post": {
+        "description": "authorizes users",
+        "consumes": [
+          "application/json"
+        ],
+        "produces": [
+          "application/json"
+        ],
+        "tags": [
+          "auth"
+        ],
+        "summary": "verifies user permissions",
+        "operationId": "authorize",
+        "parameters": [
+          {
+            "description": "SubjectAccessReview object that needs to be verified",
+            "name": "body",
+            "in": "body",
+            "required": true,
+            "schema": {
+              "$ref": "#/definitions/SubjectAccessReview"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK (authorization decision made)",
+            "schema": {
+              "$ref": "#/definitions/SubjectAccessReview"
+            }
+          },
+          "400": {
+            "description": "bad request (unsupported apiVersion or kind)",
+            "schema": {
+              "$ref": "#/definitions/SubjectAccessReview"
+            }
+          },
+          "500": {
+            "description": "internal server error",
+            "schema": {
+              "$ref": "#/definitions/SubjectAccessReview"
+            }
+          },
+          "503": {
+            "description": "service unavailable",
+            "schema": {
+              "$ref": "#/definitions/SubjectAccessReview"
+            }
+          }
+        }
+      }
+    },
+    "/introspect": {
+      "post": {
+        "security": [
+          {
+            "basicAuth": []
+          }
+        ],
+        "description": "OAuth2 token introspection (RFC 7662), clients authenticate with their client credentials",
+        "consumes": [
+          "application/x-www-form-urlencoded"
+        ],
+        "produces": [
+          "application/json"
+        ],
+        "tags": [
+          "auth"
+        ],
+        "summary": "introspects tokens for OAuth2 resource servers",
+        "operationId": "introspect",
+        "parameters": [
+          {
+            "type": "string",
+            "description": "The token to be introspected",
+            "name": "token",
+            "in": "formData",
+            "required": true
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK (the token is active or not)",
+            "schema": {
+              "$ref": "#/definitions/IntrospectionResponse"
+            }
+          },
+          "400": {
+            "description": "bad request",
+            "schema": {
+              "$ref": "#/definitions/IntrospectionError"
+            }
+          },
+          "401": {
+            "description": "unauthorized (invalid client credentials)",
+            "schema": {
+              "$ref": "#/definitions/IntrospectionError"
+            },
+            "headers": {
+              "WWW-Authenticate": {
+                "description": "the authentication scheme clients have to use (RFC 6749)",
+                "type": "string"
+              }
+            }
+          },
+          "429": {
+            "description": "too many requests",
+            "schema": {
+              "$ref": "#/definitions/IntrospectionError"
+            },
+            "headers": {
+              "Retry-After": {
+                "description": "seconds to wait before retrying",
+                "type": "integer"
+              }
+            }
+          },
+          "500": {
+            "description": "internal server error",
+            "schema": {
+              "$ref": "#/definitions/IntrospectionError"
+            }
+          },
+          "503": {
+            "description": "service unavailable",
+            "schema": {
+              "$ref": "#/definitions/IntrospectionError"
+            }
           }
         }
       }
@@ -321,23 +912,138 @@ func init() {
               "$ref": "#/definitions/TokenReviewRequest"
             }
           },
+          "400": {
+            "description": "bad request",
+            "schema": {
+              "$ref": "#/definitions/TokenReviewRequest"
+            }
+          },
           "401": {
             "description": "unauthorized",
             "schema": {
               "$ref": "#/definitions/TokenReviewRequest"
             }
           },
+          "403": {
+            "description": "forbidden",
+            "schema": {
+              "$ref": "#/definitions/TokenReviewRequest"
+            }
+          },
+          "429": {
+            "description": "too many requests",
+            "schema": {
+              "$ref": "#/definitions/TokenReviewRequest"
+            },
+            "headers": {
+              "Retry-After": {
+                "description": "seconds to wait before retrying",
+                "type": "integer"
+              }
+            }
+          },
           "500": {
             "description": "internal server error",
             "schema": {
               "$ref": "#/definitions/TokenReviewRequest"
             }
+          },
+          "503": {
+            "description": "service unavailable",
+            "schema": {
+              "$ref": "#/definitions/TokenReviewRequest"
+            }
           }
         }
       }
     }
   },
   "definitions": {
+    "IntrospectionError": {
+      "description": "IntrospectionError is returned if a token couldn't be introspected",
+      "type": "object",
+      "properties": {
+        "error": {
+          "description": "The OAuth2 error code",
+          "type": "string",
+          "example": "invalid_client"
+        },
+        "error_description": {
+          "description": "A human readable description of the error",
+          "type": "string"
+        }
+      }
+    },
+    "IntrospectionResponse": {
+      "description": "IntrospectionResponse is the OAuth2 token introspection response (RFC 7662)",
+      "type": "object",
+      "required": [
+        "active"
+      ],
+      "properties": {
+        "active": {
+          "description": "Active is true if the token is valid",
+          "type": "boolean",
+          "example": "true"
+        },
+        "aud": {
+          "description": "The audiences the token is valid for",
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        },
+        "exp": {
+          "description": "Unix time in seconds the token expires at, if known",
+          "type": "integer",
+          "format": "int64",
+          "example": 1565000000
+        },
+        "extra": {
+          "description": "Any additional information provided by the authenticator",
+          "type": "object",
+          "additionalProperties": true
+        },
+        "groups": {
+          "description": "The names of groups the user is a part of",
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        },
+        "scope": {
+          "description": "The space separated scopes of the token, taken from the scopes extra of the user",
+          "type": "string",
+          "example": "read write"
+        },
+        "sub": {
+          "description": "The subject of the token, the uid of the user or the username if the user has no uid",
+          "type": "string",
+          "example": "43"
+        },
+        "username": {
+          "description": "The name of the user",
+          "type": "string",
+          "example": "foo"
+        }
+      }
+    },
+    "NonResourceAttributes": {
+      "description": "NonResourceAttributes describes a request to a non resource path of the apiserver",
+      "type": "object",
+      "properties": {
+        "path": {
+          "description": "The URL path of the request",
+          "type": "string",
+          "example": "/healthz"
+        },
+        "verb": {
+          "description": "The standard HTTP verb",
+          "type": "string",
+          "example": "get"
+        }
+      }
+    },
     "Principal": {
       "description": "Principal contains information about the user",
       "type": "object",
@@ -354,13 +1060,139 @@ func init() {
         }
       }
     },
+    "ResourceAttributes": {
+      "description": "ResourceAttributes describes a request to a resource of the apiserver",
+      "type": "object",
+      "properties": {
+        "group": {
+          "description": "The API group of the resource",
+          "type": "string",
+          "example": "apps"
+        },
+        "name": {
+          "description": "The name of the resource being requested",
+          "type": "string",
+          "example": "nginx"
+        },
+        "namespace": {
+          "description": "The namespace of the action being requested, empty for cluster scoped resources",
+          "type": "string",
+          "example": "default"
+        },
+        "resource": {
+          "description": "One of the existing resource types",
+          "type": "string",
+          "example": "deployments"
+        },
+        "subresource": {
+          "description": "One of the existing resource types",
+          "type": "string",
+          "example": "status"
+        },
+        "verb": {
+          "description": "A kubernetes resource API verb, like get, list, watch, create, update, delete, proxy",
+          "type": "string",
+          "example": "get"
+        },
+        "version": {
+          "description": "The API version of the resource",
+          "type": "string",
+          "example": "v1"
+        }
+      }
+    },
+    "SubjectAccessReview": {
+      "description": "SubjectAccessReview is issued by K8s to this service to check whether a user can perform an action",
+      "type": "object",
+      "properties": {
+        "apiVersion": {
+          "description": "Either authorization.k8s.io/v1 or authorization.k8s.io/v1beta1",
+          "type": "string",
+          "example": "authorization.k8s.io/v1"
+        },
+        "kind": {
+          "type": "string",
+          "example": "SubjectAccessReview"
+        },
+        "spec": {
+          "$ref": "#/definitions/SubjectAccessReviewSpec"
+        },
+        "status": {
+          "$ref": "#/definitions/SubjectAccessReviewStatus"
+        }
+      }
+    },
+    "SubjectAccessReviewSpec": {
+      "description": "SubjectAccessReviewSpec contains the user and the action to be authorized. Exactly one of resourceAttributes and nonResourceAttributes must be set",
+      "type": "object",
+      "properties": {
+        "extra": {
+          "description": "Any additional information provided by the authenticator",
+          "type": "object",
+          "additionalProperties": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          }
+        },
+        "groups": {
+          "description": "The groups you're testing for",
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        },
+        "nonResourceAttributes": {
+          "$ref": "#/definitions/NonResourceAttributes"
+        },
+        "resourceAttributes": {
+          "$ref": "#/definitions/ResourceAttributes"
+        },
+        "uid": {
+          "description": "Information about the requesting user",
+          "type": "string",
+          "example": "43"
+        },
+        "user": {
+          "description": "The user you're testing for",
+          "type": "string",
+          "example": "foo"
+        }
+      }
+    },
+    "SubjectAccessReviewStatus": {
+      "description": "SubjectAccessReviewStatus is the result of the authorization request",
+      "type": "object",
+      "properties": {
+        "allowed": {
+          "description": "Allowed is true if the action would be allowed",
+          "type": "boolean",
+          "example": "true"
+        },
+        "denied": {
+          "description": "Denied is true if the action would be denied, otherwise other authorizers may allow the action",
+          "type": "boolean",
+          "example": "false"
+        },
+        "evaluationError": {
+          "description": "EvaluationError indicates that some error occurred during the authorization check",
+          "type": "string"
+        },
+        "reason": {
+          "description": "Reason is optional. It indicates why a request was allowed or denied",
+          "type": "string"
+        }
+      }
+    },
     "TokenReviewRequest": {
       "description": "TokenReviewRequest is issued by K8s to this service",
       "type": "object",
       "properties": {
         "apiVersion": {
+          "description": "Either authentication.k8s.io/v1 or authentication.k8s.io/v1beta1",
           "type": "string",
-          "example": "authentication.k8s.io/v1beta1"
+          "example": "authentication.k8s.io/v1"
         },
         "kind": {
           "type": "string",
@@ -378,6 +1210,13 @@ func init() {
       "description": "TokenReviewSpec contains the token being reviewed",
       "type": "object",
       "properties": {
+        "audiences": {
+          "description": "A list of the identifiers that the resource server presented with the token identifies as",
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        },
         "token": {
           "type": "string",
           "example": "12354234123141"
@@ -388,11 +1227,28 @@ func init() {
       "description": "TokenReviewStatus is the result of the token authentication request",
       "type": "object",
       "properties": {
+        "audiences": {
+          "description": "The audiences the token is valid for, a subset of the audiences in the spec",
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        },
         "authenticated": {
           "description": "Authenticated is true if the token is valid",
           "type": "boolean",
           "example": "true"
         },
+        "error": {
+          "description": "Error indicates that the token couldn't be checked",
+          "type": "string"
+        },
+        "expiration": {
+          "description": "Unix time in seconds the token expires at, if known by the provider. Not part of the Kubernetes API",
+          "type": "integer",
+          "format": "int64",
+          "example": 1565000000
+        },
         "user": {
           "$ref": "#/definitions/UserInfo"
         }
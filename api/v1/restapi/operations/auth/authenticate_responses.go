@@ -9,6 +9,7 @@ import (
 	"net/http"
 
 	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/swag"
 
 	models "github.com/cbrgm/authproxy/api/v1/models"
 )
@@ -57,6 +58,50 @@ func (o *AuthenticateOK) WriteResponse(rw http.ResponseWriter, producer runtime.
 	}
 }
 
+// AuthenticateBadRequestCode is the HTTP code returned for type AuthenticateBadRequest
+const AuthenticateBadRequestCode int = 400
+
+/*AuthenticateBadRequest bad request (unsupported apiVersion or kind)
+
+swagger:response authenticateBadRequest
+*/
+type AuthenticateBadRequest struct {
+
+	/*
+	  In: Body
+	*/
+	Payload *models.TokenReviewRequest `json:"body,omitempty"`
+}
+
+// NewAuthenticateBadRequest creates AuthenticateBadRequest with default headers values
+func NewAuthenticateBadRequest() *AuthenticateBadRequest {
+
+	return &AuthenticateBadRequest{}
+}
+
+// WithPayload adds the payload to the authenticate bad request response
+func (o *AuthenticateBadRequest) WithPayload(payload *models.TokenReviewRequest) *AuthenticateBadRequest {
+	o.Payload = payload
+	return o
+}
+
+// SetPayload sets the payload to the authenticate bad request response
+func (o *AuthenticateBadRequest) SetPayload(payload *models.TokenReviewRequest) {
+	o.Payload = payload
+}
+
+// WriteResponse to the client
+func (o *AuthenticateBadRequest) WriteResponse(rw http.ResponseWriter, producer runtime.Producer) {
+
+	rw.WriteHeader(400)
+	if o.Payload != nil {
+		payload := o.Payload
+		if err := producer.Produce(rw, payload); err != nil {
+			panic(err) // let the recovery middleware deal with this
+		}
+	}
+}
+
 // AuthenticateUnauthorizedCode is the HTTP code returned for type AuthenticateUnauthorized
 const AuthenticateUnauthorizedCode int = 401
 
@@ -101,6 +146,115 @@ func (o *AuthenticateUnauthorized) WriteResponse(rw http.ResponseWriter, produce
 	}
 }
 
+// AuthenticateForbiddenCode is the HTTP code returned for type AuthenticateForbidden
+const AuthenticateForbiddenCode int = 403
+
+/*AuthenticateForbidden forbidden
+
+swagger:response authenticateForbidden
+*/
+type AuthenticateForbidden struct {
+
+	/*
+	  In: Body
+	*/
+	Payload *models.TokenReviewRequest `json:"body,omitempty"`
+}
+
+// NewAuthenticateForbidden creates AuthenticateForbidden with default headers values
+func NewAuthenticateForbidden() *AuthenticateForbidden {
+
+	return &AuthenticateForbidden{}
+}
+
+// WithPayload adds the payload to the authenticate forbidden response
+func (o *AuthenticateForbidden) WithPayload(payload *models.TokenReviewRequest) *AuthenticateForbidden {
+	o.Payload = payload
+	return o
+}
+
+// SetPayload sets the payload to the authenticate forbidden response
+func (o *AuthenticateForbidden) SetPayload(payload *models.TokenReviewRequest) {
+	o.Payload = payload
+}
+
+// WriteResponse to the client
+func (o *AuthenticateForbidden) WriteResponse(rw http.ResponseWriter, producer runtime.Producer) {
+
+	rw.WriteHeader(403)
+	if o.Payload != nil {
+		payload := o.Payload
+		if err := producer.Produce(rw, payload); err != nil {
+			panic(err) // let the recovery middleware deal with this
+		}
+	}
+}
+
+// AuthenticateTooManyRequestsCode is the HTTP code returned for type AuthenticateTooManyRequests
+const AuthenticateTooManyRequestsCode int = 429
+
+/*AuthenticateTooManyRequests too many requests
+
+swagger:response authenticateTooManyRequests
+*/
+type AuthenticateTooManyRequests struct {
+	/*seconds to wait before retrying
+	 */
+	RetryAfter int64 `json:"Retry-After"`
+
+	/*
+	  In: Body
+	*/
+	Payload *models.TokenReviewRequest `json:"body,omitempty"`
+}
+
+// NewAuthenticateTooManyRequests creates AuthenticateTooManyRequests with default headers values
+func NewAuthenticateTooManyRequests() *AuthenticateTooManyRequests {
+
+	return &AuthenticateTooManyRequests{}
+}
+
+// WithRetryAfter adds the retryAfter to the authenticate too many requests response
+func (o *AuthenticateTooManyRequests) WithRetryAfter(retryAfter int64) *AuthenticateTooManyRequests {
+	o.RetryAfter = retryAfter
+	return o
+}
+
+// SetRetryAfter sets the retryAfter to the authenticate too many requests response
+func (o *AuthenticateTooManyRequests) SetRetryAfter(retryAfter int64) {
+	o.RetryAfter = retryAfter
+}
+
+// WithPayload adds the payload to the authenticate too many requests response
+func (o *AuthenticateTooManyRequests) WithPayload(payload *models.TokenReviewRequest) *AuthenticateTooManyRequests {
+	o.Payload = payload
+	return o
+}
+
+// SetPayload sets the payload to the authenticate too many requests response
+func (o *AuthenticateTooManyRequests) SetPayload(payload *models.TokenReviewRequest) {
+	o.Payload = payload
+}
+
+// WriteResponse to the client
+func (o *AuthenticateTooManyRequests) WriteResponse(rw http.ResponseWriter, producer runtime.Producer) {
+
+	// response header Retry-After
+
+	retryAfter := swag.FormatInt64(o.RetryAfter)
+	if retryAfter != "" {
+		rw.Header().Set("Retry-After", retryAfter)
+	}
+
+	rw.WriteHeader(429)
+	if o.Payload != nil {
+		payload := o.Payload
+		if err := producer.Produce(rw, payload); err != nil {
+			panic(err) // let the recovery middleware deal with this
+		}
+	}
+}
+
 // AuthenticateInternalServerErrorCode is the HTTP code returned for type AuthenticateInternalServerError
 const AuthenticateInternalServerErrorCode int = 500
 
@@ -144,3 +298,47 @@ func (o *AuthenticateInternalServerError) WriteResponse(rw http.ResponseWriter,
 		}
 	}
 }
+
+// AuthenticateServiceUnavailableCode is the HTTP code returned for type AuthenticateServiceUnavailable
+const AuthenticateServiceUnavailableCode int = 503
+
+/*AuthenticateServiceUnavailable service unavailable
+
+swagger:response authenticateServiceUnavailable
+*/
+type AuthenticateServiceUnavailable struct {
+
+	/*
+	  In: Body
+	*/
+	Payload *models.TokenReviewRequest `json:"body,omitempty"`
+}
+
+// NewAuthenticateServiceUnavailable creates AuthenticateServiceUnavailable with default headers values
+func NewAuthenticateServiceUnavailable() *AuthenticateServiceUnavailable {
+
+	return &AuthenticateServiceUnavailable{}
+}
+
+// WithPayload adds the payload to the authenticate service unavailable response
+func (o *AuthenticateServiceUnavailable) WithPayload(payload *models.TokenReviewRequest) *AuthenticateServiceUnavailable {
+	o.Payload = payload
+	return o
+}
+
+// SetPayload sets the payload to the authenticate service unavailable response
+func (o *AuthenticateServiceUnavailable) SetPayload(payload *models.TokenReviewRequest) {
+	o.Payload = payload
+}
+
+// WriteResponse to the client
+func (o *AuthenticateServiceUnavailable) WriteResponse(rw http.ResponseWriter, producer runtime.Producer) {
+
+	rw.WriteHeader(503)
+	if o.Payload != nil {
+		payload := o.Payload
+		if err := producer.Produce(rw, payload); err != nil {
+			panic(err) // let the recovery middleware deal with this
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 
 	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/swag"
 
 	models "github.com/cbrgm/authproxy/api/v1/models"
 )
@@ -57,6 +58,50 @@ func (o *LoginOK) WriteResponse(rw http.ResponseWriter, producer runtime.Produce
 	}
 }
 
+// LoginBadRequestCode is the HTTP code returned for type LoginBadRequest
+const LoginBadRequestCode int = 400
+
+/*LoginBadRequest bad request
+
+swagger:response loginBadRequest
+*/
+type LoginBadRequest struct {
+
+	/*
+	  In: Body
+	*/
+	Payload *models.TokenReviewRequest `json:"body,omitempty"`
+}
+
+// NewLoginBadRequest creates LoginBadRequest with default headers values
+func NewLoginBadRequest() *LoginBadRequest {
+
+	return &LoginBadRequest{}
+}
+
+// WithPayload adds the payload to the login bad request response
+func (o *LoginBadRequest) WithPayload(payload *models.TokenReviewRequest) *LoginBadRequest {
+	o.Payload = payload
+	return o
+}
+
+// SetPayload sets the payload to the login bad request response
+func (o *LoginBadRequest) SetPayload(payload *models.TokenReviewRequest) {
+	o.Payload = payload
+}
+
+// WriteResponse to the client
+func (o *LoginBadRequest) WriteResponse(rw http.ResponseWriter, producer runtime.Producer) {
+
+	rw.WriteHeader(400)
+	if o.Payload != nil {
+		payload := o.Payload
+		if err := producer.Produce(rw, payload); err != nil {
+			panic(err) // let the recovery middleware deal with this
+		}
+	}
+}
+
 // LoginUnauthorizedCode is the HTTP code returned for type LoginUnauthorized
 const LoginUnauthorizedCode int = 401
 
@@ -101,6 +146,115 @@ func (o *LoginUnauthorized) WriteResponse(rw http.ResponseWriter, producer runti
 	}
 }
 
+// LoginForbiddenCode is the HTTP code returned for type LoginForbidden
+const LoginForbiddenCode int = 403
+
+/*LoginForbidden forbidden
+
+swagger:response loginForbidden
+*/
+type LoginForbidden struct {
+
+	/*
+	  In: Body
+	*/
+	Payload *models.TokenReviewRequest `json:"body,omitempty"`
+}
+
+// NewLoginForbidden creates LoginForbidden with default headers values
+func NewLoginForbidden() *LoginForbidden {
+
+	return &LoginForbidden{}
+}
+
+// WithPayload adds the payload to the login forbidden response
+func (o *LoginForbidden) WithPayload(payload *models.TokenReviewRequest) *LoginForbidden {
+	o.Payload = payload
+	return o
+}
+
+// SetPayload sets the payload to the login forbidden response
+func (o *LoginForbidden) SetPayload(payload *models.TokenReviewRequest) {
+	o.Payload = payload
+}
+
+// WriteResponse to the client
+func (o *LoginForbidden) WriteResponse(rw http.ResponseWriter, producer runtime.Producer) {
+
+	rw.WriteHeader(403)
+	if o.Payload != nil {
+		payload := o.Payload
+		if err := producer.Produce(rw, payload); err != nil {
+			panic(err) // let the recovery middleware deal with this
+		}
+	}
+}
+
+// LoginTooManyRequestsCode is the HTTP code returned for type LoginTooManyRequests
+const LoginTooManyRequestsCode int = 429
+
+/*LoginTooManyRequests too many requests
+
+swagger:response loginTooManyRequests
+*/
+type LoginTooManyRequests struct {
+	/*seconds to wait before retrying
+	 */
+	RetryAfter int64 `json:"Retry-After"`
+
+	/*
+	  In: Body
+	*/
+	Payload *models.TokenReviewRequest `json:"body,omitempty"`
+}
+
+// NewLoginTooManyRequests creates LoginTooManyRequests with default headers values
+func NewLoginTooManyRequests() *LoginTooManyRequests {
+
+	return &LoginTooManyRequests{}
+}
+
+// WithRetryAfter adds the retryAfter to the login too many requests response
+func (o *LoginTooManyRequests) WithRetryAfter(retryAfter int64) *LoginTooManyRequests {
+	o.RetryAfter = retryAfter
+	return o
+}
+
+// SetRetryAfter sets the retryAfter to the login too many requests response
+func (o *LoginTooManyRequests) SetRetryAfter(retryAfter int64) {
+	o.RetryAfter = retryAfter
+}
+
+// WithPayload adds the payload to the login too many requests response
+func (o *LoginTooManyRequests) WithPayload(payload *models.TokenReviewRequest) *LoginTooManyRequests {
+	o.Payload = payload
+	return o
+}
+
+// SetPayload sets the payload to the login too many requests response
+func (o *LoginTooManyRequests) SetPayload(payload *models.TokenReviewRequest) {
+	o.Payload = payload
+}
+
+// WriteResponse to the client
+func (o *LoginTooManyRequests) WriteResponse(rw http.ResponseWriter, producer runtime.Producer) {
+
+	// response header Retry-After
+
+	retryAfter := swag.FormatInt64(o.RetryAfter)
+	if retryAfter != "" {
+		rw.Header().Set("Retry-After", retryAfter)
+	}
+
+	rw.WriteHeader(429)
+	if o.Payload != nil {
+		payload := o.Payload
+		if err := producer.Produce(rw, payload); err != nil {
+			panic(err) // let the recovery middleware deal with this
+		}
+	}
+}
+
 // LoginInternalServerErrorCode is the HTTP code returned for type LoginInternalServerError
 const LoginInternalServerErrorCode int = 500
 
@@ -144,3 +298,47 @@ func (o *LoginInternalServerError) WriteResponse(rw http.ResponseWriter, produce
 		}
 	}
 }
+
+// LoginServiceUnavailableCode is the HTTP code returned for type LoginServiceUnavailable
+const LoginServiceUnavailableCode int = 503
+
+/*LoginServiceUnavailable service unavailable
+
+swagger:response loginServiceUnavailable
+*/
+type LoginServiceUnavailable struct {
+
+	/*
+	  In: Body
+	*/
+	Payload *models.TokenReviewRequest `json:"body,omitempty"`
+}
+
+// NewLoginServiceUnavailable creates LoginServiceUnavailable with default headers values
+func NewLoginServiceUnavailable() *LoginServiceUnavailable {
+
+	return &LoginServiceUnavailable{}
+}
+
+// WithPayload adds the payload to the login service unavailable response
+func (o *LoginServiceUnavailable) WithPayload(payload *models.TokenReviewRequest) *LoginServiceUnavailable {
+	o.Payload = payload
+	return o
+}
+
+// SetPayload sets the payload to the login service unavailable response
+func (o *LoginServiceUnavailable) SetPayload(payload *models.TokenReviewRequest) {
+	o.Payload = payload
+}
+
+// WriteResponse to the client
+func (o *LoginServiceUnavailable) WriteResponse(rw http.ResponseWriter, producer runtime.Producer) {
+
+	rw.WriteHeader(503)
+	if o.Payload != nil {
+		payload := o.Payload
+		if err := producer.Produce(rw, payload); err != nil {
+			panic(err) // let the recovery middleware deal with this
+		}
+	}
+}
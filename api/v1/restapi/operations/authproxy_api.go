@@ -40,10 +40,17 @@ func NewAuthproxyAPI(spec *loads.Document) *AuthproxyAPI {
 		APIKeyAuthenticator: security.APIKeyAuth,
 		BearerAuthenticator: security.BearerAuth,
 		JSONConsumer:        runtime.JSONConsumer(),
+		UrlformConsumer:     runtime.DiscardConsumer,
 		JSONProducer:        runtime.JSONProducer(),
 		AuthAuthenticateHandler: auth.AuthenticateHandlerFunc(func(params auth.AuthenticateParams) middleware.Responder {
 			return middleware.NotImplemented("operation AuthAuthenticate has not yet been implemented")
 		}),
+		AuthAuthorizeHandler: auth.AuthorizeHandlerFunc(func(params auth.AuthorizeParams) middleware.Responder {
+			return middleware.NotImplemented("operation AuthAuthorize has not yet been implemented")
+		}),
+		AuthIntrospectHandler: auth.IntrospectHandlerFunc(func(params auth.IntrospectParams, principal *models.Principal) middleware.Responder {
+			return middleware.NotImplemented("operation AuthIntrospect has not yet been implemented")
+		}),
 		AuthLoginHandler: auth.LoginHandlerFunc(func(params auth.LoginParams, principal *models.Principal) middleware.Responder {
 			return middleware.NotImplemented("operation AuthLogin has not yet been implemented")
 		}),
@@ -82,6 +89,8 @@ type AuthproxyAPI struct {
 
 	// JSONConsumer registers a consumer for a "application/json" mime type
 	JSONConsumer runtime.Consumer
+	// UrlformConsumer registers a consumer for a "application/x-www-form-urlencoded" mime type
+	UrlformConsumer runtime.Consumer
 
 	// JSONProducer registers a producer for a "application/json" mime type
 	JSONProducer runtime.Producer
@@ -95,6 +104,10 @@ type AuthproxyAPI struct {
 
 	// AuthAuthenticateHandler sets the operation handler for the authenticate operation
 	AuthAuthenticateHandler auth.AuthenticateHandler
+	// AuthAuthorizeHandler sets the operation handler for the authorize operation
+	AuthAuthorizeHandler auth.AuthorizeHandler
+	// AuthIntrospectHandler sets the operation handler for the introspect operation
+	AuthIntrospectHandler auth.IntrospectHandler
 	// AuthLoginHandler sets the operation handler for the login operation
 	AuthLoginHandler auth.LoginHandler
 
@@ -156,6 +169,10 @@ func (o *AuthproxyAPI) Validate() error {
 		unregistered = append(unregistered, "JSONConsumer")
 	}
 
+	if o.UrlformConsumer == nil {
+		unregistered = append(unregistered, "UrlformConsumer")
+	}
+
 	if o.JSONProducer == nil {
 		unregistered = append(unregistered, "JSONProducer")
 	}
@@ -168,6 +185,14 @@ func (o *AuthproxyAPI) Validate() error {
 		unregistered = append(unregistered, "auth.AuthenticateHandler")
 	}
 
+	if o.AuthAuthorizeHandler == nil {
+		unregistered = append(unregistered, "auth.AuthorizeHandler")
+	}
+
+	if o.AuthIntrospectHandler == nil {
+		unregistered = append(unregistered, "auth.IntrospectHandler")
+	}
+
 	if o.AuthLoginHandler == nil {
 		unregistered = append(unregistered, "auth.LoginHandler")
 	}
@@ -219,6 +244,8 @@ func (o *AuthproxyAPI) ConsumersFor(mediaTypes []string) map[string]runtime.Cons
 
 		case "application/json":
 			result["application/json"] = o.JSONConsumer
+		case "application/x-www-form-urlencoded":
+			result["application/x-www-form-urlencoded"] = o.UrlformConsumer
 
 		}
 
@@ -287,6 +314,16 @@ func (o *AuthproxyAPI) initHandlerCache() {
 	}
 	o.handlers["POST"]["/authenticate"] = auth.NewAuthenticate(o.context, o.AuthAuthenticateHandler)
 
+	if o.handlers["POST"] == nil {
+		o.handlers["POST"] = make(map[string]http.Handler)
+	}
+	o.handlers["POST"]["/authorize"] = auth.NewAuthorize(o.context, o.AuthAuthorizeHandler)
+
+	if o.handlers["POST"] == nil {
+		o.handlers["POST"] = make(map[string]http.Handler)
+	}
+	o.handlers["POST"]["/introspect"] = auth.NewIntrospect(o.context, o.AuthIntrospectHandler)
+
 	if o.handlers["POST"] == nil {
 		o.handlers["POST"] = make(map[string]http.Handler)
 	}
@@ -23,17 +23,22 @@ import (
 	"errors"
 	"fmt"
 	"github.com/cbrgm/authproxy/api"
+	"github.com/cbrgm/authproxy/internal"
 	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/jwt"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics/prometheus"
 	"github.com/oklog/run"
 	prom "github.com/prometheus/client_golang/prometheus"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -46,12 +51,46 @@ type ProxyConfig struct {
 	TLSClientCA     string
 	LogJSON         bool
 	LogLevel        string
+	Audiences       []string
+	// IntrospectionClients maps the client ids allowed to use the introspection endpoint to their secrets
+	IntrospectionClients map[string]string
+	// CacheTTL and CacheNegativeTTL enable caching authenticated and unauthenticated decisions of tokens
+	CacheTTL         time.Duration
+	CacheNegativeTTL time.Duration
+	// CacheMaxEntries and CacheMaxBytes limit the number and estimated memory of cached decisions (optional)
+	CacheMaxEntries int
+	CacheMaxBytes   int
+	// LockoutUserFailures and LockoutClientIPFailures are the failed logins of a username or client IP
+	// within LockoutWindow before further logins are delayed with exponential backoff, 0 disables tracking them
+	LockoutUserFailures     int
+	LockoutClientIPFailures int
+	LockoutWindow           time.Duration
+	// LockoutDuration is the longest delay logins are rejected for (optional)
+	LockoutDuration time.Duration
+	// LockoutStore holds the failure counters, an in-memory store is used if unset
+	LockoutStore internal.LockoutStore
+	// RateLimits limit the requests of every client to the public endpoints (optional)
+	RateLimits []RateLimit
+	// MaxInFlight is the number of requests served at once by the public endpoints,
+	// further requests are answered with 503 Service Unavailable. 0 disables the limit
+	MaxInFlight int
+	// ProviderTimeout is the timeout of every provider call, 0 disables it
+	ProviderTimeout time.Duration
+	// BreakerFailures is the number of consecutive failed provider calls opening the circuit breaker,
+	// calls fail fast with 503 until a probe call after BreakerOpenDuration succeeds. 0 disables the breaker
+	BreakerFailures     int
+	BreakerOpenDuration time.Duration
 }
 
 // Proxy represents the authproxy instance
 type Proxy struct {
-	Provider provider.Provider
-	Config   ProxyConfig
+	Provider        provider.Provider
+	ContextProvider provider.ContextProvider
+	// Authorizer decides about SubjectAccessReviews, optional if the provider implements provider.Authorizer
+	Authorizer provider.Authorizer
+	// Issuer publishes the OpenID discovery document and JWKS of issued tokens, optional if the provider is a *jwt.Issuer
+	Issuer *jwt.Issuer
+	Config ProxyConfig
 }
 
 // NewConfiguration returns a new default configuration
@@ -75,6 +114,14 @@ func NewWithProvider(provider provider.Provider, cfg ProxyConfig) *Proxy {
 	}
 }
 
+// NewWithContextProvider returns a new proxy instance using a context aware provider implementation as backend
+func NewWithContextProvider(provider provider.ContextProvider, cfg ProxyConfig) *Proxy {
+	return &Proxy{
+		ContextProvider: provider,
+		Config:          cfg,
+	}
+}
+
 // ListenAndServe starts the proxy
 func (p *Proxy) ListenAndServe() error {
 
@@ -88,23 +135,81 @@ func (p *Proxy) ListenAndServe() error {
 	if p.Config.TLSClientCA == "" {
 		return errors.New("invalid config: no client ca cert for HTTPS")
 	}
-	if p.Provider == nil {
+	if p.Provider == nil && p.ContextProvider == nil {
 		return errors.New("invalid config: no provider registered")
 	}
 
+	prv := p.ContextProvider
+	if prv == nil {
+		prv = provider.NewContextAdapter(p.Provider)
+	}
+
 	// initialize logger
 	logger := newLogger(p.Config.LogJSON, p.Config.LogLevel)
 	logger = log.WithPrefix(logger, "app", "authproxy")
 
+	var breaker *internal.Breaker
+	if p.Config.ProviderTimeout > 0 || p.Config.BreakerFailures > 0 {
+		breaker = internal.NewBreaker(internal.BreakerConfig{
+			Timeout:      p.Config.ProviderTimeout,
+			Failures:     p.Config.BreakerFailures,
+			OpenDuration: p.Config.BreakerOpenDuration,
+		}, prometheus.NewGaugeFrom(prom.GaugeOpts{
+			Namespace: "authproxy",
+			Subsystem: "provider",
+			Name:      "circuit_breaker_state",
+			Help:      "State of the circuit breaker around provider calls, 1 for the current state",
+		}, []string{"state"}))
+	}
+
 	var gr run.Group
 	{
-		apiV1, err := api.NewV1(&p.Provider, log.WithPrefix(logger, "component", "api"))
+		apiConfig := api.Config{
+			Audiences:               p.Config.Audiences,
+			Authorizer:              p.authorizer(),
+			IntrospectionClients:    p.Config.IntrospectionClients,
+			CacheTTL:                p.Config.CacheTTL,
+			CacheNegativeTTL:        p.Config.CacheNegativeTTL,
+			CacheMaxEntries:         p.Config.CacheMaxEntries,
+			CacheMaxBytes:           p.Config.CacheMaxBytes,
+			LockoutUserFailures:     p.Config.LockoutUserFailures,
+			LockoutClientIPFailures: p.Config.LockoutClientIPFailures,
+			LockoutWindow:           p.Config.LockoutWindow,
+			LockoutDuration:         p.Config.LockoutDuration,
+			LockoutStore:            p.Config.LockoutStore,
+			Breaker:                 breaker,
+		}
+
+		apiV1, err := api.NewV1(prv, apiConfig, log.WithPrefix(logger, "component", "api"))
 		if err != nil {
 			return err
 		}
 
+		limiter, err := rateLimiterMiddleware(p.Config.RateLimits, p.Config.MaxInFlight, prometheus.NewCounterFrom(prom.CounterOpts{
+			Namespace: "authproxy",
+			Subsystem: "http",
+			Name:      "rejected_requests_total",
+			Help:      "Number of requests rejected because a client exceeded its rate limit or too many requests were in flight",
+		}, []string{"path", "reason"}))
+		if err != nil {
+			return fmt.Errorf("invalid config: %v", err)
+		}
+
 		router := chi.NewRouter()
+		router.Use(middleware.RequestID)
 		router.Use(requestLogger(logger))
+		router.Use(limiter)
+
+		// publish the keys of issued tokens so other services can verify them offline
+		if issuer := p.issuer(); issuer != nil {
+			router.Method(http.MethodGet, jwt.JWKSPath, issuer.JWKSHandler())
+			if issuer.Discoverable() {
+				router.Method(http.MethodGet, "/.well-known/openid-configuration", issuer.DiscoveryHandler())
+			} else {
+				level.Info(logger).Log("msg", "not serving the OpenID discovery document, the token issuer is no https URL")
+			}
+		}
+
 		router.Mount("/", apiV1)
 
 		//parse certificates from cert and key file for the authproxy server
@@ -129,6 +234,11 @@ func (p *Proxy) ListenAndServe() error {
 		}
 
 		tlsConfig.ClientCAs = cPool
+		// client certificates are only verified to identify the clients of cert keyed rate limits,
+		// clients presenting certificates of other CAs fail the handshake then
+		if keyedByCert(p.Config.RateLimits) {
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
 
 		server := http.Server{
 			Addr:      p.Config.HTTPAddr,
@@ -139,12 +249,37 @@ func (p *Proxy) ListenAndServe() error {
 		// private router initialization
 
 		privateRouter := chi.NewRouter()
-		privateRouter.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
-			fmt.Fprintln(w, http.StatusText(http.StatusOK))
-		})
+		privateRouter.Get("/healthz", healthzHandler(breaker))
+		privateRouter.Get("/readyz", readyzHandler(breaker))
 
 		privateRouter.Mount("/metrics", prom.UninstrumentedHandler())
 
+		// list signing keys of issued tokens, they are rotated on SIGHUP as the internal api is not authenticated
+		if issuer := p.issuer(); issuer != nil {
+			if keys, ok := issuer.KeySet().(*jwt.Manager); ok {
+				privateRouter.Handle("/admin/keys", keys.AdminHandler())
+
+				hup := make(chan os.Signal, 1)
+				done := make(chan struct{})
+				signal.Notify(hup, syscall.SIGHUP)
+				gr.Add(func() error {
+					for {
+						select {
+						case <-hup:
+							if err := keys.Rotate(); err != nil {
+								level.Warn(logger).Log("msg", "signing key rotation skipped", "err", err)
+							}
+						case <-done:
+							return nil
+						}
+					}
+				}, func(err error) {
+					signal.Stop(hup)
+					close(done)
+				})
+			}
+		}
+
 		privateServer := &http.Server{
 			Addr:    p.Config.HTTPPrivateAddr,
 			Handler: privateRouter,
@@ -178,6 +313,53 @@ func (p *Proxy) ListenAndServe() error {
 	return nil
 }
 
+// healthzHandler reports whether authproxy is alive. It answers 200 regardless of the provider,
+// restarting authproxy doesn't help an unavailable backend, the breaker state is reported in the body
+func healthzHandler(breaker *internal.Breaker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, http.StatusText(http.StatusOK))
+		if breaker != nil {
+			fmt.Fprintf(w, "provider circuit breaker: %s\n", breaker.State())
+		}
+	}
+}
+
+// readyzHandler reports whether authproxy can answer requests, it answers 503 while the circuit to the provider is open
+func readyzHandler(breaker *internal.Breaker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if breaker != nil && breaker.State() == internal.BreakerOpen {
+			http.Error(w, "provider circuit breaker is open", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, http.StatusText(http.StatusOK))
+	}
+}
+
+// authorizer returns the configured authorizer or the provider if it implements provider.Authorizer
+func (p *Proxy) authorizer() provider.Authorizer {
+	if p.Authorizer != nil {
+		return p.Authorizer
+	}
+	if authz, ok := p.ContextProvider.(provider.Authorizer); ok {
+		return authz
+	}
+	if authz, ok := p.Provider.(provider.Authorizer); ok {
+		return authz
+	}
+	return nil
+}
+
+// issuer returns the configured token issuer or the provider if it is a *jwt.Issuer
+func (p *Proxy) issuer() *jwt.Issuer {
+	if p.Issuer != nil {
+		return p.Issuer
+	}
+	if issuer, ok := p.ContextProvider.(*jwt.Issuer); ok {
+		return issuer
+	}
+	return nil
+}
+
 // requestLogger proxies incoming requests and logs them
 func requestLogger(logger log.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
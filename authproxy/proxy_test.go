@@ -0,0 +1,75 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package authproxy
+
+import (
+	"context"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/internal"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/go-kit/kit/metrics/discard"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// downProvider fails every call like an unreachable backend
+type downProvider struct{}
+
+func (downProvider) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	return nil, fmt.Errorf("connection refused")
+}
+
+func (downProvider) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	return nil, fmt.Errorf("connection refused")
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	breaker := internal.NewBreaker(internal.BreakerConfig{Failures: 1, OpenDuration: time.Minute}, discard.NewGauge())
+	s := internal.NewBreakerService(breaker, downProvider{})
+
+	get := func(h http.HandlerFunc) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec
+	}
+
+	if rec := get(readyzHandler(breaker)); rec.Code != http.StatusOK {
+		t.Errorf("expected closed circuit to be ready, got %d", rec.Code)
+	}
+
+	s.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "foo"})
+	if breaker.State() != internal.BreakerOpen {
+		t.Fatalf("expected circuit to be open, got %s", breaker.State())
+	}
+
+	// an unavailable backend makes authproxy unready but never restarts it
+	if rec := get(readyzHandler(breaker)); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected open circuit not to be ready, got %d", rec.Code)
+	}
+	rec := get(healthzHandler(breaker))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "provider circuit breaker: open") {
+		t.Errorf("expected healthz to stay alive and report the open circuit, got %d: %s", rec.Code, rec.Body)
+	}
+
+	if rec := get(healthzHandler(nil)); rec.Code != http.StatusOK {
+		t.Errorf("expected healthz without breaker to be alive, got %d", rec.Code)
+	}
+}
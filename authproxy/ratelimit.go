@@ -0,0 +1,274 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package authproxy
+
+import (
+	"fmt"
+	"github.com/go-kit/kit/metrics"
+	"golang.org/x/time/rate"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// RateLimitKeyCert identifies clients by the subject of their client certificate, falling back to the remote IP
+	RateLimitKeyCert = "cert"
+	// RateLimitKeyIP identifies clients by their remote IP
+	RateLimitKeyIP = "ip"
+	// RateLimitKeyHeader identifies clients by the value of a header, e.g. header:X-Client-Id, falling back to the remote IP
+	RateLimitKeyHeader = "header:"
+
+	// rateLimitPurgeInterval limits how often the buckets of idle clients are removed
+	rateLimitPurgeInterval = time.Minute
+	// rateLimitMaxBuckets limits the buckets of each limit keyed by a header, clients can send arbitrary header values.
+	// Once it is reached new header values share the bucket of their remote IP
+	rateLimitMaxBuckets = 10000
+)
+
+// RateLimit limits the requests every client may send to an endpoint with a token bucket
+type RateLimit struct {
+	// Path is the request path the limit applies to, e.g. /v1/login, the limit applies to all paths if empty
+	Path string
+	// Rate is the number of requests per second every client may send on average
+	Rate float64
+	// Burst is the number of requests every client may send at once
+	Burst int
+	// Key identifies clients: cert, ip or header:<name> (default: cert)
+	Key string
+}
+
+// ParseRateLimit parses a rate limit of comma separated key=value pairs,
+// e.g. path=/v1/login,rate=1,burst=5,key=ip
+func ParseRateLimit(s string) (RateLimit, error) {
+	l := RateLimit{Key: RateLimitKeyCert}
+
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 {
+			return RateLimit{}, fmt.Errorf("invalid rate limit %q: expected key=value pairs", s)
+		}
+
+		var err error
+		switch kv[0] {
+		case "path":
+			l.Path = kv[1]
+		case "rate":
+			l.Rate, err = strconv.ParseFloat(kv[1], 64)
+		case "burst":
+			l.Burst, err = strconv.Atoi(kv[1])
+		case "key":
+			l.Key = kv[1]
+		default:
+			err = fmt.Errorf("unknown option %q", kv[0])
+		}
+		if err != nil {
+			return RateLimit{}, fmt.Errorf("invalid rate limit %q: %v", s, err)
+		}
+	}
+
+	return l, l.validate()
+}
+
+// validate returns an error if the limit can't be applied
+func (l RateLimit) validate() error {
+	if l.Rate <= 0 || l.Burst <= 0 {
+		return fmt.Errorf("invalid rate limit: rate and burst must be positive")
+	}
+	switch {
+	case l.Key == "", l.Key == RateLimitKeyCert, l.Key == RateLimitKeyIP:
+	case strings.HasPrefix(l.Key, RateLimitKeyHeader) && len(l.Key) > len(RateLimitKeyHeader):
+	default:
+		return fmt.Errorf("invalid rate limit: unknown key %q, expected cert, ip or header:<name>", l.Key)
+	}
+	return nil
+}
+
+// keyedByCert returns true if any of the limits identifies clients by their client certificate
+func keyedByCert(limits []RateLimit) bool {
+	for _, l := range limits {
+		if l.Key == "" || l.Key == RateLimitKeyCert {
+			return true
+		}
+	}
+	return false
+}
+
+// client returns the key of the client sending the request and the key of its remote IP
+func (l RateLimit) client(r *http.Request) (key, ip string) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip = "ip:" + host
+
+	switch {
+	case l.Key == "" || l.Key == RateLimitKeyCert:
+		if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+			return "cert:" + r.TLS.PeerCertificates[0].Subject.String(), ip
+		}
+	case strings.HasPrefix(l.Key, RateLimitKeyHeader):
+		if v := r.Header.Get(l.Key[len(RateLimitKeyHeader):]); v != "" {
+			return "header:" + v, ip
+		}
+	}
+
+	return ip, ip
+}
+
+// rateLimiter holds the token buckets of the clients of a limit
+type rateLimiter struct {
+	limit RateLimit
+	idle  time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	headers int
+	purged  time.Time
+}
+
+type bucket struct {
+	limiter *rate.Limiter
+	seen    time.Time
+}
+
+func newRateLimiter(l RateLimit) *rateLimiter {
+	// a bucket idle for this long is full again and can be recreated on demand
+	idle := time.Duration(float64(l.Burst) / l.Rate * float64(time.Second))
+	if idle < rateLimitPurgeInterval {
+		idle = rateLimitPurgeInterval
+	}
+
+	return &rateLimiter{limit: l, idle: idle, buckets: map[string]*bucket{}}
+}
+
+// reserve takes a token from the bucket of the client, ok is false if the client has to wait for the returned delay.
+// Clients identified by a header share the bucket of their IP once the header buckets reached rateLimitMaxBuckets
+func (l *rateLimiter) reserve(client, ip string, now time.Time) (*rate.Reservation, time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.purged) >= rateLimitPurgeInterval {
+		for key, b := range l.buckets {
+			if now.Sub(b.seen) >= l.idle {
+				l.remove(key)
+			}
+		}
+		l.purged = now
+	}
+
+	b, ok := l.buckets[client]
+	if !ok && isHeaderKey(client) && l.headers >= rateLimitMaxBuckets {
+		client = ip
+		b, ok = l.buckets[client]
+	}
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(l.limit.Rate), l.limit.Burst)}
+		l.buckets[client] = b
+		if isHeaderKey(client) {
+			l.headers++
+		}
+	}
+	b.seen = now
+
+	r := b.limiter.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return nil, delay, false
+	}
+	return r, 0, true
+}
+
+// remove deletes the bucket of a client, the caller must hold the lock
+func (l *rateLimiter) remove(client string) {
+	if isHeaderKey(client) {
+		l.headers--
+	}
+	delete(l.buckets, client)
+}
+
+// isHeaderKey reports whether the client was identified by a header value
+func isHeaderKey(client string) bool {
+	return strings.HasPrefix(client, "header:")
+}
+
+// rateLimiterMiddleware rejects requests of clients exceeding their rate limits with 429 and
+// sheds requests exceeding the in-flight limit with 503. Rejected requests are counted by reason and by the path
+// of the limit, "all" for limits of all paths and shed requests, so clients can't create arbitrary label values.
+func rateLimiterMiddleware(limits []RateLimit, maxInFlight int, rejected metrics.Counter) (func(next http.Handler) http.Handler, error) {
+	limiters := make([]*rateLimiter, 0, len(limits))
+	for _, l := range limits {
+		if err := l.validate(); err != nil {
+			return nil, err
+		}
+		limiters = append(limiters, newRateLimiter(l))
+	}
+
+	var inFlight chan struct{}
+	if maxInFlight > 0 {
+		inFlight = make(chan struct{}, maxInFlight)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			now := time.Now()
+
+			var reserved []*rate.Reservation
+			for _, l := range limiters {
+				if l.limit.Path != "" && l.limit.Path != r.URL.Path {
+					continue
+				}
+
+				client, ip := l.limit.client(r)
+				res, delay, ok := l.reserve(client, ip, now)
+				if !ok {
+					// tokens taken from the buckets of other limits are returned
+					for _, res := range reserved {
+						res.CancelAt(now)
+					}
+					path := l.limit.Path
+					if path == "" {
+						path = "all"
+					}
+					rejected.With("path", path, "reason", "rate_limited").Add(1)
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+					http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+					return
+				}
+				reserved = append(reserved, res)
+			}
+
+			if inFlight != nil {
+				select {
+				case inFlight <- struct{}{}:
+					defer func() { <-inFlight }()
+				default:
+					rejected.With("path", "all", "reason", "overloaded").Add(1)
+					w.Header().Set("Retry-After", "1")
+					http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
@@ -0,0 +1,230 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package authproxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"github.com/go-kit/kit/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCounter records the values of a counter by label values
+type testCounter struct {
+	mu     *sync.Mutex
+	values map[string]float64
+	lvs    []string
+}
+
+func newTestCounter() *testCounter {
+	return &testCounter{mu: &sync.Mutex{}, values: map[string]float64{}}
+}
+
+func (c *testCounter) With(labelValues ...string) metrics.Counter {
+	return &testCounter{mu: c.mu, values: c.values, lvs: append(append([]string(nil), c.lvs...), labelValues...)}
+}
+
+func (c *testCounter) Add(delta float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[strings.Join(c.lvs, "=")] += delta
+}
+
+func (c *testCounter) value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[strings.Join(labelValues, "=")]
+}
+
+func TestParseRateLimit(t *testing.T) {
+	l, err := ParseRateLimit("path=/v1/login, rate=0.5, burst=5, key=header:X-Client-Id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := RateLimit{Path: "/v1/login", Rate: 0.5, Burst: 5, Key: "header:X-Client-Id"}
+	if l != expected {
+		t.Errorf("expected %+v, got %+v", expected, l)
+	}
+
+	if l, err = ParseRateLimit("rate=10,burst=20"); err != nil || l.Key != RateLimitKeyCert {
+		t.Errorf("expected clients to be identified by certificate by default, got %+v, %v", l, err)
+	}
+
+	for _, invalid := range []string{"rate=10", "rate=1,burst=1,key=user", "rate=1,burst=1,key=header:", "rate", "rate=1,burst=1,size=2"} {
+		if _, err := ParseRateLimit(invalid); err == nil {
+			t.Errorf("expected rate limit %q to be invalid", invalid)
+		}
+	}
+}
+
+// request sends a request from the client ip with a certificate of the subject, if any, and returns the response
+func request(h http.Handler, path, ip, subject string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, path, nil)
+	r.RemoteAddr = ip + ":12345"
+	if subject != "" {
+		r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: subject}}}}
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	rejected := newTestCounter()
+	middleware, err := rateLimiterMiddleware([]RateLimit{
+		{Rate: 0.001, Burst: 3, Key: RateLimitKeyCert},
+		{Path: "/v1/login", Rate: 0.001, Burst: 1, Key: RateLimitKeyIP},
+	}, 0, rejected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// the login limit applies to the client IP only on its path
+	if w := request(h, "/v1/login", "10.0.0.1", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected first login to pass, got %d", w.Code)
+	}
+	w := request(h, "/v1/login", "10.0.0.1", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected second login to be limited with Retry-After, got %d", w.Code)
+	}
+	if w := request(h, "/v1/authenticate", "10.0.0.1", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected other paths to pass, got %d", w.Code)
+	}
+
+	// a misbehaving client doesn't use up the bucket of the apiserver
+	for i := 0; i < 3; i++ {
+		request(h, "/v1/authenticate", "10.0.0.2", "misbehaving")
+	}
+	if w := request(h, "/v1/authenticate", "10.0.0.2", "misbehaving"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected misbehaving client to be limited, got %d", w.Code)
+	}
+	if w := request(h, "/v1/authenticate", "10.0.0.2", "kube-apiserver"); w.Code != http.StatusOK {
+		t.Fatalf("expected apiserver to pass, got %d", w.Code)
+	}
+
+	if v := rejected.value("path", "/v1/login", "reason", "rate_limited"); v != 1 {
+		t.Errorf("expected 1 rejected login, got %v", v)
+	}
+	if v := rejected.value("path", "all", "reason", "rate_limited"); v != 1 {
+		t.Errorf("expected 1 rejected request of all paths, got %v", v)
+	}
+}
+
+func TestRateLimiterMiddlewareInFlight(t *testing.T) {
+	rejected := newTestCounter()
+	middleware, err := rateLimiterMiddleware(nil, 1, rejected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started, release := make(chan struct{}), make(chan struct{})
+	h := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-release
+		}
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		request(h, "/slow", "10.0.0.1", "")
+		close(done)
+	}()
+	<-started
+
+	if w := request(h, "/v1/authenticate", "10.0.0.2", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected request exceeding the in-flight limit to be shed, got %d", w.Code)
+	}
+	if v := rejected.value("path", "all", "reason", "overloaded"); v != 1 {
+		t.Errorf("expected 1 shed request, got %v", v)
+	}
+
+	close(release)
+	<-done
+	if w := request(h, "/v1/authenticate", "10.0.0.2", ""); w.Code != http.StatusOK {
+		t.Errorf("expected request to pass once the in-flight request finished, got %d", w.Code)
+	}
+}
+
+func TestRateLimiterPurge(t *testing.T) {
+	l := newRateLimiter(RateLimit{Rate: 1, Burst: 1})
+	now := time.Unix(1500000000, 0)
+
+	if _, _, ok := l.reserve("ip:a", "ip:a", now); !ok {
+		t.Fatal("expected first request to pass")
+	}
+	if _, delay, ok := l.reserve("ip:a", "ip:a", now); ok || delay != time.Second {
+		t.Fatalf("expected second request to wait 1s, got %v", delay)
+	}
+
+	l.reserve("ip:b", "ip:b", now.Add(2*rateLimitPurgeInterval))
+	if _, ok := l.buckets["ip:a"]; ok {
+		t.Error("expected bucket of idle client to be removed")
+	}
+}
+
+func TestRateLimiterMaxBuckets(t *testing.T) {
+	l := newRateLimiter(RateLimit{Rate: 1, Burst: 1, Key: RateLimitKeyHeader + "X-Client"})
+	now := time.Unix(1500000000, 0)
+
+	for i := 0; i < rateLimitMaxBuckets; i++ {
+		if _, _, ok := l.reserve(fmt.Sprintf("header:%d", i), "ip:10.0.0.1", now); !ok {
+			t.Fatalf("expected request of client %d to pass", i)
+		}
+	}
+	if _, _, ok := l.reserve("header:new", "ip:10.0.0.1", now); !ok {
+		t.Fatal("expected first request of new client to pass")
+	}
+	if _, _, ok := l.reserve("header:other", "ip:10.0.0.1", now); ok {
+		t.Error("expected new clients to share the bucket of their IP once the limit is reached")
+	}
+	if _, ok := l.buckets["header:new"]; ok {
+		t.Error("expected no bucket for header values beyond the limit")
+	}
+
+	l.reserve("ip:10.0.0.2", "ip:10.0.0.2", now.Add(2*rateLimitPurgeInterval))
+	if l.headers != 0 {
+		t.Errorf("expected idle header buckets to be removed, got %d", l.headers)
+	}
+	if _, _, ok := l.reserve("header:new", "ip:10.0.0.1", now.Add(2*rateLimitPurgeInterval)); !ok {
+		t.Error("expected new client to get its own bucket after purge")
+	}
+	if _, ok := l.buckets["header:new"]; !ok {
+		t.Error("expected bucket for header value after purge")
+	}
+}
+
+func TestKeyedByCert(t *testing.T) {
+	if keyedByCert([]RateLimit{{Key: RateLimitKeyIP}, {Key: RateLimitKeyHeader + "X-Client"}}) {
+		t.Error("expected ip and header keyed limits not to require client certificates")
+	}
+	if !keyedByCert([]RateLimit{{Key: RateLimitKeyIP}, {Key: RateLimitKeyCert}}) {
+		t.Error("expected cert keyed limit to require client certificates")
+	}
+	if keyedByCert(nil) {
+		t.Error("expected no client certificates without limits")
+	}
+}
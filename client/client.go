@@ -31,17 +31,33 @@ import (
 type ClientSet interface {
 	Login(username, password string) (string, error)
 	Authenticate(bearerToken string) (*v1.TokenReviewRequest, error)
+	Authorize(spec *v1.SubjectAccessReviewSpec) (*v1.SubjectAccessReview, error)
 }
 
+const (
+	// AuthenticationV1 is the authentication.k8s.io/v1 TokenReview apiVersion
+	AuthenticationV1 = "authentication.k8s.io/v1"
+	// AuthenticationV1beta1 is the authentication.k8s.io/v1beta1 TokenReview apiVersion
+	AuthenticationV1beta1 = "authentication.k8s.io/v1beta1"
+	// AuthorizationV1 is the authorization.k8s.io/v1 SubjectAccessReview apiVersion
+	AuthorizationV1 = "authorization.k8s.io/v1"
+	// AuthorizationV1beta1 is the authorization.k8s.io/v1beta1 SubjectAccessReview apiVersion
+	AuthorizationV1beta1 = "authorization.k8s.io/v1beta1"
+)
+
 // AuthClientConfig represents the clientSet configuration
 type AuthClientConfig struct {
 	Path string
 	CA   string
+	// APIVersion is the TokenReview apiVersion sent to authproxy (default: authentication.k8s.io/v1beta1).
+	// SubjectAccessReviews are sent with the authorization.k8s.io apiVersion of the same version
+	APIVersion string
 }
 
 // clientSet represents the v1 authproxy client implementation
 type clientSet struct {
-	client *v1.APIClient
+	client     *v1.APIClient
+	apiVersion string
 }
 
 // newClientV1ForConfig returns a new v1 client for a given config
@@ -77,7 +93,16 @@ func NewForConfig(c *AuthClientConfig) (ClientSet, error) {
 		swg.ChangeBasePath(c.Path)
 	}
 
-	cl := clientSet{client: swg}
+	apiVersion := c.APIVersion
+	switch apiVersion {
+	case "":
+		apiVersion = AuthenticationV1beta1
+	case AuthenticationV1, AuthenticationV1beta1:
+	default:
+		return nil, fmt.Errorf("invalid config: unsupported apiVersion %s", apiVersion)
+	}
+
+	cl := clientSet{client: swg, apiVersion: apiVersion}
 
 	var res ClientSet = &cl
 	return res, nil
@@ -119,7 +144,7 @@ func (c *clientSet) Authenticate(bearerToken string) (*v1.TokenReviewRequest, er
 	}
 
 	tokenReview, resp, err := c.client.AuthApi.Authenticate(context.TODO(), v1.TokenReviewRequest{
-		ApiVersion: "authentication.k8s.io/v1beta1",
+		ApiVersion: c.apiVersion,
 		Kind:       "TokenReview",
 		Spec: &v1.TokenReviewSpec{
 			Token: bearerToken,
@@ -136,6 +161,29 @@ func (c *clientSet) Authenticate(bearerToken string) (*v1.TokenReviewRequest, er
 	return &tokenReview, nil
 }
 
+// Authorize checks whether the user of the spec is allowed to perform the action of the spec
+func (c *clientSet) Authorize(spec *v1.SubjectAccessReviewSpec) (*v1.SubjectAccessReview, error) {
+	if spec == nil {
+		return nil, errors.New("invalid arguments: spec is missing")
+	}
+
+	apiVersion := AuthorizationV1beta1
+	if c.apiVersion == AuthenticationV1 {
+		apiVersion = AuthorizationV1
+	}
+
+	accessReview, _, err := c.client.AuthApi.Authorize(context.TODO(), v1.SubjectAccessReview{
+		ApiVersion: apiVersion,
+		Kind:       "SubjectAccessReview",
+		Spec:       spec,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &accessReview, nil
+}
+
 // LoadCAFile loads a single PEM-encoded file from the path specified.
 func LoadCAFile(caFile string) (*x509.CertPool, error) {
 	pool := x509.NewCertPool()
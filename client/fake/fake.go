@@ -84,3 +84,21 @@ func (c *fakeClient) Authenticate(bearerToken string) (*v1.TokenReviewRequest, e
 	}
 	return result, nil
 }
+
+// Authorize authorizes actions performed by the client
+// Every action of a logged in user is allowed
+func (c *fakeClient) Authorize(spec *v1.SubjectAccessReviewSpec) (*v1.SubjectAccessReview, error) {
+	if spec == nil {
+		return nil, errors.New("invalid arguments: spec is missing")
+	}
+
+	_, ok := c.tokens[spec.User]
+
+	return &v1.SubjectAccessReview{
+		ApiVersion: "authorization.k8s.io/v1beta1",
+		Kind:       "SubjectAccessReview",
+		Status: &v1.SubjectAccessReviewStatus{
+			Allowed: ok,
+		},
+	}, nil
+}
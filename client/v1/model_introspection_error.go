@@ -0,0 +1,20 @@
+/*
+ * authproxy OpenAPI
+ *
+ * This is the api documentation for https://github.com/cbrgm/authproxy
+ *
+ * API version: 1.0
+ * Contact: chris@cbrgm.net
+ * Generated by: Swagger Codegen (https://github.com/swagger-api/swagger-codegen.git)
+ */
+
+
+package swagger
+
+// IntrospectionError is returned if a token couldn't be introspected
+type IntrospectionError struct {
+	// The OAuth2 error code
+	Error_ string `json:"error,omitempty"`
+	// A human readable description of the error
+	ErrorDescription string `json:"error_description,omitempty"`
+}
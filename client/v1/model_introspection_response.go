@@ -0,0 +1,32 @@
+/*
+ * authproxy OpenAPI
+ *
+ * This is the api documentation for https://github.com/cbrgm/authproxy
+ *
+ * API version: 1.0
+ * Contact: chris@cbrgm.net
+ * Generated by: Swagger Codegen (https://github.com/swagger-api/swagger-codegen.git)
+ */
+
+
+package swagger
+
+// IntrospectionResponse is the OAuth2 token introspection response (RFC 7662)
+type IntrospectionResponse struct {
+	// Active is true if the token is valid
+	Active bool `json:"active"`
+	// The subject of the token, the uid of the user or the username if the user has no uid
+	Sub string `json:"sub,omitempty"`
+	// The name of the user
+	Username string `json:"username,omitempty"`
+	// The space separated scopes of the token, taken from the scopes extra of the user
+	Scope string `json:"scope,omitempty"`
+	// The names of groups the user is a part of
+	Groups []string `json:"groups,omitempty"`
+	// Any additional information provided by the authenticator
+	Extra *interface{} `json:"extra,omitempty"`
+	// The audiences the token is valid for
+	Aud []string `json:"aud,omitempty"`
+	// Unix time in seconds the token expires at, if known
+	Exp int64 `json:"exp,omitempty"`
+}
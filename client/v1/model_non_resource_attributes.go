@@ -0,0 +1,19 @@
+/*
+ * authproxy OpenAPI
+ *
+ * This is the api documentation for https://github.com/cbrgm/authproxy
+ *
+ * API version: 1.0
+ * Contact: chris@cbrgm.net
+ * Generated by: Swagger Codegen (https://github.com/swagger-api/swagger-codegen.git)
+ */
+
+package swagger
+
+// NonResourceAttributes describes a request to a non resource path of the apiserver
+type NonResourceAttributes struct {
+	// The URL path of the request
+	Path string `json:"path,omitempty"`
+	// The standard HTTP verb
+	Verb string `json:"verb,omitempty"`
+}
@@ -0,0 +1,29 @@
+/*
+ * authproxy OpenAPI
+ *
+ * This is the api documentation for https://github.com/cbrgm/authproxy
+ *
+ * API version: 1.0
+ * Contact: chris@cbrgm.net
+ * Generated by: Swagger Codegen (https://github.com/swagger-api/swagger-codegen.git)
+ */
+
+package swagger
+
+// ResourceAttributes describes a request to a resource of the apiserver
+type ResourceAttributes struct {
+	// The namespace of the action being requested, empty for cluster scoped resources
+	Namespace string `json:"namespace,omitempty"`
+	// A kubernetes resource API verb, like get, list, watch, create, update, delete, proxy
+	Verb string `json:"verb,omitempty"`
+	// The API group of the resource
+	Group string `json:"group,omitempty"`
+	// The API version of the resource
+	Version string `json:"version,omitempty"`
+	// One of the existing resource types
+	Resource string `json:"resource,omitempty"`
+	// One of the existing resource types
+	Subresource string `json:"subresource,omitempty"`
+	// The name of the resource being requested
+	Name string `json:"name,omitempty"`
+}
@@ -0,0 +1,20 @@
+/*
+ * authproxy OpenAPI
+ *
+ * This is the api documentation for https://github.com/cbrgm/authproxy
+ *
+ * API version: 1.0
+ * Contact: chris@cbrgm.net
+ * Generated by: Swagger Codegen (https://github.com/swagger-api/swagger-codegen.git)
+ */
+
+package swagger
+
+// SubjectAccessReview is issued by K8s to this service to check whether a user can perform an action
+type SubjectAccessReview struct {
+	Kind string `json:"kind,omitempty"`
+	// Either authorization.k8s.io/v1 or authorization.k8s.io/v1beta1
+	ApiVersion string `json:"apiVersion,omitempty"`
+	Spec *SubjectAccessReviewSpec `json:"spec,omitempty"`
+	Status *SubjectAccessReviewStatus `json:"status,omitempty"`
+}
@@ -0,0 +1,25 @@
+/*
+ * authproxy OpenAPI
+ *
+ * This is the api documentation for https://github.com/cbrgm/authproxy
+ *
+ * API version: 1.0
+ * Contact: chris@cbrgm.net
+ * Generated by: Swagger Codegen (https://github.com/swagger-api/swagger-codegen.git)
+ */
+
+package swagger
+
+// SubjectAccessReviewSpec contains the user and the action to be authorized. Exactly one of resourceAttributes and nonResourceAttributes must be set
+type SubjectAccessReviewSpec struct {
+	ResourceAttributes *ResourceAttributes `json:"resourceAttributes,omitempty"`
+	NonResourceAttributes *NonResourceAttributes `json:"nonResourceAttributes,omitempty"`
+	// The user you're testing for
+	User string `json:"user,omitempty"`
+	// The groups you're testing for
+	Groups []string `json:"groups,omitempty"`
+	// Information about the requesting user
+	Uid string `json:"uid,omitempty"`
+	// Any additional information provided by the authenticator
+	Extra map[string][]string `json:"extra,omitempty"`
+}
@@ -0,0 +1,23 @@
+/*
+ * authproxy OpenAPI
+ *
+ * This is the api documentation for https://github.com/cbrgm/authproxy
+ *
+ * API version: 1.0
+ * Contact: chris@cbrgm.net
+ * Generated by: Swagger Codegen (https://github.com/swagger-api/swagger-codegen.git)
+ */
+
+package swagger
+
+// SubjectAccessReviewStatus is the result of the authorization request
+type SubjectAccessReviewStatus struct {
+	// Allowed is true if the action would be allowed
+	Allowed bool `json:"allowed,omitempty"`
+	// Denied is true if the action would be denied, otherwise other authorizers may allow the action
+	Denied bool `json:"denied,omitempty"`
+	// Reason is optional. It indicates why a request was allowed or denied
+	Reason string `json:"reason,omitempty"`
+	// EvaluationError indicates that some error occurred during the authorization check
+	EvaluationError string `json:"evaluationError,omitempty"`
+}
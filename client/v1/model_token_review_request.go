@@ -13,6 +13,7 @@ package swagger
 // TokenReviewRequest is issued by K8s to this service
 type TokenReviewRequest struct {
 	Kind string `json:"kind,omitempty"`
+	// Either authentication.k8s.io/v1 or authentication.k8s.io/v1beta1
 	ApiVersion string `json:"apiVersion,omitempty"`
 	Spec *TokenReviewSpec `json:"spec,omitempty"`
 	Status *TokenReviewStatus `json:"status,omitempty"`
@@ -13,4 +13,6 @@ package swagger
 // TokenReviewSpec contains the token being reviewed
 type TokenReviewSpec struct {
 	Token string `json:"token,omitempty"`
+	// A list of the identifiers that the resource server presented with the token identifies as
+	Audiences []string `json:"audiences,omitempty"`
 }
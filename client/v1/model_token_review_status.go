@@ -15,4 +15,10 @@ type TokenReviewStatus struct {
 	// Authenticated is true if the token is valid
 	Authenticated bool `json:"authenticated,omitempty"`
 	User *UserInfo `json:"user,omitempty"`
+	// Error indicates that the token couldn't be checked
+	Error string `json:"error,omitempty"`
+	// The audiences the token is valid for, a subset of the audiences in the spec
+	Audiences []string `json:"audiences,omitempty"`
+	// Unix time in seconds the token expires at, if known by the provider. Not part of the Kubernetes API
+	Expiration int64 `json:"expiration,omitempty"`
 }
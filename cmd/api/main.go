@@ -18,10 +18,28 @@ package main
 
 import (
 	"fmt"
+	"github.com/cbrgm/authproxy/api"
 	"github.com/cbrgm/authproxy/authproxy"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/exec"
 	"github.com/cbrgm/authproxy/provider/fake"
+	"github.com/cbrgm/authproxy/provider/grpc"
+	"github.com/cbrgm/authproxy/provider/htpasswd"
+	"github.com/cbrgm/authproxy/provider/introspection"
+	"github.com/cbrgm/authproxy/provider/jwt"
+	"github.com/cbrgm/authproxy/provider/ldap"
+	"github.com/cbrgm/authproxy/provider/oidc"
+	"github.com/cbrgm/authproxy/provider/policy"
+	"github.com/cbrgm/authproxy/provider/sql"
+	"github.com/cbrgm/authproxy/provider/tokenfile"
+	"github.com/cbrgm/authproxy/provider/union"
+	"github.com/cbrgm/authproxy/provider/webhook"
+	"github.com/go-kit/kit/log"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
 	"github.com/urfave/cli"
 	"os"
+	"time"
 )
 
 const (
@@ -32,10 +50,86 @@ const (
 	FlagTLSClientCA     = "tls-ca-cert"
 	FlagLogJSON         = "log-json"
 	FlagLogLevel        = "log-level"
+	FlagAudiences       = "api-audiences"
+	FlagPolicyFile      = "authorization-policy-file"
+	FlagTokenFile       = "token-auth-file"
+	FlagReloadInterval  = "reload-interval"
+	FlagHtpasswdFile    = "htpasswd-file"
+	FlagHtpasswdGroups  = "htpasswd-groups-file"
+	FlagTokenTTL        = "token-ttl"
+	FlagLDAPURL         = "ldap-url"
+	FlagLDAPStartTLS    = "ldap-start-tls"
+	FlagLDAPCAFile      = "ldap-ca-file"
+	FlagLDAPBindDN      = "ldap-bind-dn"
+	FlagLDAPBindPW      = "ldap-bind-password"
+	FlagLDAPBaseDN      = "ldap-base-dn"
+	FlagLDAPUserFilter  = "ldap-user-filter"
+	FlagLDAPUserAttr    = "ldap-username-attribute"
+	FlagLDAPUIDAttr     = "ldap-uid-attribute"
+	FlagLDAPGroupBase   = "ldap-group-base-dn"
+	FlagLDAPGroupFilter = "ldap-group-filter"
+	FlagSigningKey      = "token-signing-key"
+	FlagTokenIssuer     = "token-issuer"
+	FlagClockSkew       = "token-clock-skew"
+	FlagSigningKeyDir   = "token-signing-key-dir"
+	FlagKeyRotation     = "token-key-rotation-interval"
+	FlagOIDCIssuerURL   = "oidc-issuer-url"
+	FlagOIDCClientID    = "oidc-client-id"
+	FlagOIDCJWKSFile    = "oidc-jwks-file"
+	FlagOIDCUserClaim   = "oidc-username-claim"
+	FlagOIDCUserPrefix  = "oidc-username-prefix"
+	FlagOIDCGroupsClaim = "oidc-groups-claim"
+	FlagOIDCGroupPrefix = "oidc-groups-prefix"
+	FlagOIDCSigningAlgs = "oidc-signing-algs"
+	FlagIntrospectURL   = "introspection-url"
+	FlagIntrospectID    = "introspection-client-id"
+	FlagIntrospectKey   = "introspection-client-secret"
+	FlagIntrospectToken = "introspection-token-url"
+	FlagIntrospectUsers = "introspection-clients-file"
+	FlagFailOnError     = "provider-fail-on-error"
+	FlagExecCommand     = "exec-command"
+	FlagExecArgs        = "exec-args"
+	FlagExecWorker      = "exec-worker"
+	FlagExecTimeout     = "exec-timeout"
+	FlagExecConcurrency = "exec-max-concurrency"
+	FlagGRPCAddress     = "grpc-address"
+	FlagGRPCCAFile      = "grpc-ca-file"
+	FlagGRPCCertFile    = "grpc-cert-file"
+	FlagGRPCKeyFile     = "grpc-key-file"
+	FlagGRPCServerName  = "grpc-server-name"
+	FlagGRPCTimeout     = "grpc-timeout"
+	FlagWebhookURL      = "webhook-url"
+	FlagWebhookLogin    = "webhook-login-url"
+	FlagWebhookCert     = "webhook-tls-cert"
+	FlagWebhookKey      = "webhook-tls-key"
+	FlagWebhookCA       = "webhook-tls-ca-cert"
+	FlagWebhookTimeout  = "webhook-timeout"
+	FlagSQLDriver       = "sql-driver"
+	FlagSQLDSN          = "sql-dsn"
+	FlagSQLMigrate      = "sql-migrate"
+	FlagSQLUserQuery    = "sql-user-query"
+	FlagSQLGroupsQuery  = "sql-groups-query"
+	FlagCacheTTL        = "cache-ttl"
+	FlagCacheNegTTL     = "cache-negative-ttl"
+	FlagCacheEntries    = "cache-max-entries"
+	FlagCacheBytes      = "cache-max-bytes"
+	FlagLockoutUser     = "login-max-user-failures"
+	FlagLockoutIP       = "login-max-client-ip-failures"
+	FlagLockoutWindow   = "login-failure-window"
+	FlagLockoutDuration = "login-lockout-duration"
+	FlagRateLimit       = "rate-limit"
+	FlagMaxInFlight     = "max-requests-inflight"
+	FlagProviderTimeout = "provider-timeout"
+	FlagBreakerFailures = "provider-breaker-failures"
+	FlagBreakerOpen     = "provider-breaker-open-duration"
 
 	EnvHTTPAddr = "API_HTTP_ADDR"
 	EnvLogJSON  = "API_LOG_JSON"
 	EnvLogLevel = "API_LOG_LEVEL"
+
+	EnvLDAPBindPW    = "API_LDAP_BIND_PASSWORD"
+	EnvIntrospectKey = "API_INTROSPECTION_CLIENT_SECRET"
+	EnvSQLDSN        = "API_SQL_DSN"
 )
 
 type apiConf struct {
@@ -46,6 +140,78 @@ type apiConf struct {
 	TLSClientCA     string
 	LogJSON         bool
 	LogLevel        string
+	Audiences       cli.StringSlice
+	PolicyFile      string
+	TokenFile       string
+	ReloadInterval  time.Duration
+	HtpasswdFile    string
+	HtpasswdGroups  string
+	TokenTTL        time.Duration
+	LDAPURL         string
+	LDAPStartTLS    bool
+	LDAPCAFile      string
+	LDAPBindDN      string
+	LDAPBindPW      string
+	LDAPBaseDN      string
+	LDAPUserFilter  string
+	LDAPUserAttr    string
+	LDAPUIDAttr     string
+	LDAPGroupBase   string
+	LDAPGroupFilter string
+	SigningKey      string
+	TokenIssuer     string
+	ClockSkew       time.Duration
+	SigningKeyDir   string
+	KeyRotation     time.Duration
+	OIDCIssuerURL   string
+	OIDCClientID    string
+	OIDCJWKSFile    string
+	OIDCUserClaim   string
+	OIDCUserPrefix  string
+	OIDCGroupsClaim string
+	OIDCGroupPrefix string
+	OIDCSigningAlgs cli.StringSlice
+	IntrospectURL   string
+	IntrospectID    string
+	IntrospectKey   string
+	IntrospectToken string
+	IntrospectUsers string
+	FailOnError     bool
+	ExecCommand     string
+	ExecArgs        cli.StringSlice
+	ExecWorker      bool
+	ExecTimeout     time.Duration
+	ExecConcurrency int
+	GRPCAddress     string
+	GRPCCAFile      string
+	GRPCCertFile    string
+	GRPCKeyFile     string
+	GRPCServerName  string
+	GRPCTimeout     time.Duration
+	WebhookURL      string
+	WebhookLogin    string
+	WebhookCert     string
+	WebhookKey      string
+	WebhookCA       string
+	WebhookTimeout  time.Duration
+	SQLDriver       string
+	SQLDSN          string
+	SQLMigrate      bool
+	SQLUserQuery    string
+	SQLGroupsQuery  string
+	CacheTTL        time.Duration
+	CacheNegTTL     time.Duration
+	CacheEntries    int
+	CacheBytes      int
+	LockoutUser     int
+	LockoutIP       int
+	LockoutWindow   time.Duration
+	LockoutDuration time.Duration
+	RateLimits      cli.StringSlice
+	MaxInFlight     int
+	ProviderTimeout time.Duration
+	BreakerFailures int
+	BreakerOpen     time.Duration
 }
 
 var (
@@ -93,6 +259,388 @@ var (
 			Value:       "info",
 			Destination: &apiConfig.LogLevel,
 		},
+		cli.StringSliceFlag{
+			Name:  FlagAudiences,
+			Usage: "Reject tokens which are not valid for at least one of these audiences (can be repeated)",
+			Value: &apiConfig.Audiences,
+		},
+		cli.StringFlag{
+			Name:        FlagPolicyFile,
+			Usage:       "The YAML or JSON policy file used to authorize SubjectAccessReviews (optional)",
+			Destination: &apiConfig.PolicyFile,
+		},
+		cli.StringFlag{
+			Name:        FlagTokenFile,
+			Usage:       "The static token file in kube-apiserver --token-auth-file format to authenticate tokens with (optional)",
+			Destination: &apiConfig.TokenFile,
+		},
+		cli.DurationFlag{
+			Name:        FlagReloadInterval,
+			Usage:       "The interval the policy, token and user files are checked for changes, 0 disables reloading",
+			Value:       30 * time.Second,
+			Destination: &apiConfig.ReloadInterval,
+		},
+		cli.StringFlag{
+			Name:        FlagHtpasswdFile,
+			Usage:       "The htpasswd file, or YAML user file with argon2id hashes, to check logins against (optional)",
+			Destination: &apiConfig.HtpasswdFile,
+		},
+		cli.StringFlag{
+			Name:        FlagHtpasswdGroups,
+			Usage:       "The htgroup file with the groups of htpasswd users (optional)",
+			Destination: &apiConfig.HtpasswdGroups,
+		},
+		cli.DurationFlag{
+			Name:        FlagTokenTTL,
+			Usage:       "The lifetime of tokens issued after logins",
+			Value:       12 * time.Hour,
+			Destination: &apiConfig.TokenTTL,
+		},
+		cli.StringFlag{
+			Name:        FlagLDAPURL,
+			Usage:       "The LDAP server to authenticate users against, ldap://host:389 or ldaps://host:636 (optional)",
+			Destination: &apiConfig.LDAPURL,
+		},
+		cli.BoolFlag{
+			Name:        FlagLDAPStartTLS,
+			Usage:       "Upgrade ldap:// connections to TLS with StartTLS",
+			Destination: &apiConfig.LDAPStartTLS,
+		},
+		cli.StringFlag{
+			Name:        FlagLDAPCAFile,
+			Usage:       "The CA certificates to verify the LDAP server with, the system roots are used if unset",
+			Destination: &apiConfig.LDAPCAFile,
+		},
+		cli.StringFlag{
+			Name:        FlagLDAPBindDN,
+			Usage:       "The DN of the service account users are searched with, searches anonymously if unset",
+			Destination: &apiConfig.LDAPBindDN,
+		},
+		cli.StringFlag{
+			Name:        FlagLDAPBindPW,
+			EnvVar:      EnvLDAPBindPW,
+			Usage:       "The password of the LDAP service account",
+			Destination: &apiConfig.LDAPBindPW,
+		},
+		cli.StringFlag{
+			Name:        FlagLDAPBaseDN,
+			Usage:       "The DN users are searched in",
+			Destination: &apiConfig.LDAPBaseDN,
+		},
+		cli.StringFlag{
+			Name:        FlagLDAPUserFilter,
+			Usage:       "The filter users are searched with, %s is replaced by the username",
+			Value:       "(uid=%s)",
+			Destination: &apiConfig.LDAPUserFilter,
+		},
+		cli.StringFlag{
+			Name:        FlagLDAPUserAttr,
+			Usage:       "The attribute of users used as username",
+			Value:       "uid",
+			Destination: &apiConfig.LDAPUserAttr,
+		},
+		cli.StringFlag{
+			Name:        FlagLDAPUIDAttr,
+			Usage:       "The attribute of users used as uid, e.g. uidNumber (optional)",
+			Destination: &apiConfig.LDAPUIDAttr,
+		},
+		cli.StringFlag{
+			Name:        FlagLDAPGroupBase,
+			Usage:       "The DN groups are searched in, defaults to the base DN",
+			Destination: &apiConfig.LDAPGroupBase,
+		},
+		cli.StringFlag{
+			Name:        FlagLDAPGroupFilter,
+			Usage:       "The filter groups of a user are searched with, %s is replaced by the user DN, e.g. (member=%s). The memberOf attribute of users is used if unset",
+			Destination: &apiConfig.LDAPGroupFilter,
+		},
+		cli.StringFlag{
+			Name:        FlagSigningKey,
+			Usage:       "The PEM encoded RSA, ECDSA P-256 or Ed25519 private key to issue signed JWTs after logins with (optional)",
+			Destination: &apiConfig.SigningKey,
+		},
+		cli.StringFlag{
+			Name:        FlagTokenIssuer,
+			Usage:       "The issuer (iss claim) of signed JWTs, an https URL enables the OpenID discovery document",
+			Value:       "authproxy",
+			Destination: &apiConfig.TokenIssuer,
+		},
+		cli.DurationFlag{
+			Name:        FlagClockSkew,
+			Usage:       "The clock skew tolerated when validating signed JWTs",
+			Value:       time.Minute,
+			Destination: &apiConfig.ClockSkew,
+		},
+		cli.StringFlag{
+			Name:        FlagSigningKeyDir,
+			Usage:       "The directory of PEM encoded private keys to issue signed JWTs with, keys added later are rotated in (optional)",
+			Destination: &apiConfig.SigningKeyDir,
+		},
+		cli.DurationFlag{
+			Name:        FlagKeyRotation,
+			Usage:       "The interval pending signing keys are promoted, 0 only rotates keys on SIGHUP",
+			Destination: &apiConfig.KeyRotation,
+		},
+		cli.StringFlag{
+			Name:        FlagOIDCIssuerURL,
+			Usage:       "The URL of the OpenID issuer to authenticate ID tokens of (optional)",
+			Destination: &apiConfig.OIDCIssuerURL,
+		},
+		cli.StringFlag{
+			Name:        FlagOIDCClientID,
+			Usage:       "The client id ID tokens must be issued for (required with --oidc-issuer-url)",
+			Destination: &apiConfig.OIDCClientID,
+		},
+		cli.StringFlag{
+			Name:        FlagOIDCJWKSFile,
+			Usage:       "The JWKS file of the OpenID issuer, used instead of fetching the JWKS (optional)",
+			Destination: &apiConfig.OIDCJWKSFile,
+		},
+		cli.StringFlag{
+			Name:        FlagOIDCUserClaim,
+			Usage:       "The claim of ID tokens used as username",
+			Value:       oidc.DefaultUsernameClaim,
+			Destination: &apiConfig.OIDCUserClaim,
+		},
+		cli.StringFlag{
+			Name:        FlagOIDCUserPrefix,
+			Usage:       "The prefix of usernames, defaults to the issuer URL for claims other than email, - disables prefixing",
+			Destination: &apiConfig.OIDCUserPrefix,
+		},
+		cli.StringFlag{
+			Name:        FlagOIDCGroupsClaim,
+			Usage:       "The claim of ID tokens holding the groups (optional)",
+			Destination: &apiConfig.OIDCGroupsClaim,
+		},
+		cli.StringFlag{
+			Name:        FlagOIDCGroupPrefix,
+			Usage:       "The prefix of groups (optional)",
+			Destination: &apiConfig.OIDCGroupPrefix,
+		},
+		cli.StringSliceFlag{
+			Name:  FlagOIDCSigningAlgs,
+			Usage: "The signing algorithms accepted for ID tokens, defaults to RS256 (can be repeated)",
+			Value: &apiConfig.OIDCSigningAlgs,
+		},
+		cli.StringFlag{
+			Name:        FlagIntrospectURL,
+			Usage:       "The OAuth2 token introspection endpoint to authenticate opaque tokens with (optional)",
+			Destination: &apiConfig.IntrospectURL,
+		},
+		cli.StringFlag{
+			Name:        FlagIntrospectID,
+			Usage:       "The client id authproxy authenticates with at the introspection endpoint",
+			Destination: &apiConfig.IntrospectID,
+		},
+		cli.StringFlag{
+			Name:        FlagIntrospectKey,
+			EnvVar:      EnvIntrospectKey,
+			Usage:       "The client secret authproxy authenticates with at the introspection endpoint",
+			Destination: &apiConfig.IntrospectKey,
+		},
+		cli.StringFlag{
+			Name:        FlagIntrospectToken,
+			Usage:       "The token endpoint to get access tokens for the introspection endpoint from, uses basic auth if unset (optional)",
+			Destination: &apiConfig.IntrospectToken,
+		},
+		cli.StringFlag{
+			Name:        FlagIntrospectUsers,
+			Usage:       "The file with client_id:secret lines of resource servers allowed to call /v1/introspect (optional)",
+			Destination: &apiConfig.IntrospectUsers,
+		},
+		cli.BoolFlag{
+			Name:        FlagFailOnError,
+			Usage:       "Stop trying further providers if a provider fails with an internal error instead of skipping it",
+			Destination: &apiConfig.FailOnError,
+		},
+		cli.StringFlag{
+			Name:        FlagExecCommand,
+			Usage:       "The executable to send logins and tokens to as JSON on stdin (optional)",
+			Destination: &apiConfig.ExecCommand,
+		},
+		cli.StringSliceFlag{
+			Name:  FlagExecArgs,
+			Usage: "The arguments passed to the executable (can be repeated)",
+			Value: &apiConfig.ExecArgs,
+		},
+		cli.BoolFlag{
+			Name:        FlagExecWorker,
+			Usage:       "Keep the executable running and send it one request per line",
+			Destination: &apiConfig.ExecWorker,
+		},
+		cli.DurationFlag{
+			Name:        FlagExecTimeout,
+			Usage:       "The time the executable has to reply",
+			Value:       10 * time.Second,
+			Destination: &apiConfig.ExecTimeout,
+		},
+		cli.IntFlag{
+			Name:        FlagExecConcurrency,
+			Usage:       "The maximum number of concurrent requests to the executable",
+			Value:       4,
+			Destination: &apiConfig.ExecConcurrency,
+		},
+		cli.StringFlag{
+			Name:        FlagGRPCAddress,
+			Usage:       "The address of a remote provider served over gRPC, host:port or unix:///path/to/socket (optional)",
+			Destination: &apiConfig.GRPCAddress,
+		},
+		cli.StringFlag{
+			Name:        FlagGRPCCAFile,
+			Usage:       "The CA certificates to verify the remote provider with, enables TLS",
+			Destination: &apiConfig.GRPCCAFile,
+		},
+		cli.StringFlag{
+			Name:        FlagGRPCCertFile,
+			Usage:       "The client certificate presented to the remote provider",
+			Destination: &apiConfig.GRPCCertFile,
+		},
+		cli.StringFlag{
+			Name:        FlagGRPCKeyFile,
+			Usage:       "The key of the client certificate presented to the remote provider",
+			Destination: &apiConfig.GRPCKeyFile,
+		},
+		cli.StringFlag{
+			Name:        FlagGRPCServerName,
+			Usage:       "The name verified against the certificate of the remote provider, defaults to the host of the address",
+			Destination: &apiConfig.GRPCServerName,
+		},
+		cli.DurationFlag{
+			Name:        FlagGRPCTimeout,
+			Usage:       "The timeout of calls to the remote provider",
+			Value:       10 * time.Second,
+			Destination: &apiConfig.GRPCTimeout,
+		},
+		cli.StringFlag{
+			Name:        FlagWebhookURL,
+			Usage:       "The TokenReview webhook to delegate authentication to, e.g. https://authproxy:6660/v1/authenticate (optional)",
+			Destination: &apiConfig.WebhookURL,
+		},
+		cli.StringFlag{
+			Name:        FlagWebhookLogin,
+			Usage:       "The url logins are delegated to with basic auth, e.g. https://authproxy:6660/v1/login (optional)",
+			Destination: &apiConfig.WebhookLogin,
+		},
+		cli.StringFlag{
+			Name:        FlagWebhookCert,
+			Usage:       "The client certificate presented to the webhook",
+			Destination: &apiConfig.WebhookCert,
+		},
+		cli.StringFlag{
+			Name:        FlagWebhookKey,
+			Usage:       "The key of the client certificate presented to the webhook",
+			Destination: &apiConfig.WebhookKey,
+		},
+		cli.StringFlag{
+			Name:        FlagWebhookCA,
+			Usage:       "The CA certificates to verify the webhook with, the system roots are used if unset",
+			Destination: &apiConfig.WebhookCA,
+		},
+		cli.DurationFlag{
+			Name:        FlagWebhookTimeout,
+			Usage:       "The timeout of requests to the webhook",
+			Value:       10 * time.Second,
+			Destination: &apiConfig.WebhookTimeout,
+		},
+		cli.StringFlag{
+			Name:        FlagSQLDriver,
+			Usage:       "The driver of the SQL database users are stored in, postgres or mysql",
+			Value:       "postgres",
+			Destination: &apiConfig.SQLDriver,
+		},
+		cli.StringFlag{
+			Name:        FlagSQLDSN,
+			EnvVar:      EnvSQLDSN,
+			Usage:       "The data source name of the SQL database users are stored in (optional)",
+			Destination: &apiConfig.SQLDSN,
+		},
+		cli.BoolFlag{
+			Name:        FlagSQLMigrate,
+			Usage:       "Create or update the reference schema of the SQL database on startup",
+			Destination: &apiConfig.SQLMigrate,
+		},
+		cli.StringFlag{
+			Name:        FlagSQLUserQuery,
+			Usage:       "The query selecting username, uid and password hash of a user, defaults to the reference schema",
+			Destination: &apiConfig.SQLUserQuery,
+		},
+		cli.StringFlag{
+			Name:        FlagSQLGroupsQuery,
+			Usage:       "The query selecting the group names of a user, defaults to the reference schema",
+			Destination: &apiConfig.SQLGroupsQuery,
+		},
+		cli.DurationFlag{
+			Name:        FlagCacheTTL,
+			Usage:       "The duration to cache authenticated decisions of tokens, 0 disables caching them",
+			Destination: &apiConfig.CacheTTL,
+		},
+		cli.DurationFlag{
+			Name:        FlagCacheNegTTL,
+			Usage:       "The duration to cache unauthenticated decisions of tokens, 0 disables caching them",
+			Destination: &apiConfig.CacheNegTTL,
+		},
+		cli.IntFlag{
+			Name:        FlagCacheEntries,
+			Usage:       "The maximum number of cached decisions",
+			Value:       10000,
+			Destination: &apiConfig.CacheEntries,
+		},
+		cli.IntFlag{
+			Name:        FlagCacheBytes,
+			Usage:       "The maximum estimated memory of cached decisions in bytes",
+			Value:       64 << 20,
+			Destination: &apiConfig.CacheBytes,
+		},
+		cli.IntFlag{
+			Name:        FlagLockoutUser,
+			Usage:       "The failed logins of a username within the failure window before its logins are delayed, 0 disables the limit",
+			Value:       5,
+			Destination: &apiConfig.LockoutUser,
+		},
+		cli.IntFlag{
+			Name:        FlagLockoutIP,
+			Usage:       "The failed logins of a client IP within the failure window before its logins are delayed, 0 disables the limit",
+			Value:       20,
+			Destination: &apiConfig.LockoutIP,
+		},
+		cli.DurationFlag{
+			Name:        FlagLockoutWindow,
+			Usage:       "The sliding window failed logins are counted in",
+			Value:       15 * time.Minute,
+			Destination: &apiConfig.LockoutWindow,
+		},
+		cli.DurationFlag{
+			Name:        FlagLockoutDuration,
+			Usage:       "The longest time logins are rejected for after too many failures, delays double from 1s with every failure",
+			Value:       15 * time.Minute,
+			Destination: &apiConfig.LockoutDuration,
+		},
+		cli.StringSliceFlag{
+			Name:  FlagRateLimit,
+			Usage: "A token bucket limit of every client as comma separated options path, rate (per second), burst and key (cert, ip or header:<name>), e.g. path=/v1/login,rate=1,burst=5,key=ip (repeatable). Limits keyed by cert make clients presenting a certificate not signed by --tls-ca-cert fail the TLS handshake",
+			Value: &apiConfig.RateLimits,
+		},
+		cli.IntFlag{
+			Name:        FlagMaxInFlight,
+			Usage:       "The number of requests served at once, further requests are answered with 503. 0 disables the limit",
+			Destination: &apiConfig.MaxInFlight,
+		},
+		cli.DurationFlag{
+			Name:        FlagProviderTimeout,
+			Usage:       "The timeout of every provider call, 0 disables it",
+			Destination: &apiConfig.ProviderTimeout,
+		},
+		cli.IntFlag{
+			Name:        FlagBreakerFailures,
+			Usage:       "The consecutive failed or timed out provider calls opening the circuit breaker, 0 disables it",
+			Destination: &apiConfig.BreakerFailures,
+		},
+		cli.DurationFlag{
+			Name:        FlagBreakerOpen,
+			Usage:       "The time an open circuit breaker rejects calls before probing the provider",
+			Value:       30 * time.Second,
+			Destination: &apiConfig.BreakerOpen,
+		},
 	}
 )
 
@@ -113,20 +661,317 @@ func apiAction(c *cli.Context) error {
 
 	// create the config from command line flags
 	config := authproxy.ProxyConfig{
-		HTTPAddr:        apiConfig.HTTPAddr,
-		HTTPPrivateAddr: apiConfig.HTTPPrivateAddr,
-		TLSKey:          apiConfig.TLSKey,
-		TLSCert:         apiConfig.TLSCert,
-		TLSClientCA:     apiConfig.TLSClientCA,
-		LogJSON:         apiConfig.LogJSON,
-		LogLevel:        apiConfig.LogLevel,
+		HTTPAddr:                apiConfig.HTTPAddr,
+		HTTPPrivateAddr:         apiConfig.HTTPPrivateAddr,
+		TLSKey:                  apiConfig.TLSKey,
+		TLSCert:                 apiConfig.TLSCert,
+		TLSClientCA:             apiConfig.TLSClientCA,
+		LogJSON:                 apiConfig.LogJSON,
+		LogLevel:                apiConfig.LogLevel,
+		Audiences:               apiConfig.Audiences,
+		CacheTTL:                apiConfig.CacheTTL,
+		CacheNegativeTTL:        apiConfig.CacheNegTTL,
+		CacheMaxEntries:         apiConfig.CacheEntries,
+		CacheMaxBytes:           apiConfig.CacheBytes,
+		LockoutUserFailures:     apiConfig.LockoutUser,
+		LockoutClientIPFailures: apiConfig.LockoutIP,
+		LockoutWindow:           apiConfig.LockoutWindow,
+		LockoutDuration:         apiConfig.LockoutDuration,
+		MaxInFlight:             apiConfig.MaxInFlight,
+		ProviderTimeout:         apiConfig.ProviderTimeout,
+		BreakerFailures:         apiConfig.BreakerFailures,
+		BreakerOpenDuration:     apiConfig.BreakerOpen,
+	}
+
+	logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stdout))
+
+	for _, value := range apiConfig.RateLimits {
+		limit, err := authproxy.ParseRateLimit(value)
+		if err != nil {
+			fmt.Printf("failed to parse rate limit: %s", err)
+			os.Exit(1)
+		}
+
+		config.RateLimits = append(config.RateLimits, limit)
+	}
+
+	if apiConfig.IntrospectUsers != "" {
+		clients, err := api.LoadIntrospectionClients(apiConfig.IntrospectUsers)
+		if err != nil {
+			fmt.Printf("failed to load introspection clients: %s", err)
+			os.Exit(1)
+		}
+
+		config.IntrospectionClients = clients
+	}
+
+	// initialize the authentication providers, they are tried in order
+	var members []union.Member
+
+	if apiConfig.TokenFile != "" {
+		tokens, err := tokenfile.NewProvider(tokenfile.Config{
+			Path:           apiConfig.TokenFile,
+			ReloadInterval: apiConfig.ReloadInterval,
+			Logger:         log.With(logger, "component", "tokenfile"),
+		})
+		if err != nil {
+			fmt.Printf("failed to load token file: %s", err)
+			os.Exit(1)
+		}
+		defer tokens.Close()
+
+		members = append(members, union.Member{Name: "tokenfile", Provider: provider.NewContextAdapter(tokens)})
+	}
+
+	if apiConfig.HtpasswdFile != "" {
+		users, err := htpasswd.NewProvider(htpasswd.Config{
+			Path:           apiConfig.HtpasswdFile,
+			GroupsPath:     apiConfig.HtpasswdGroups,
+			ReloadInterval: apiConfig.ReloadInterval,
+			TokenTTL:       apiConfig.TokenTTL,
+			Logger:         log.With(logger, "component", "htpasswd"),
+		})
+		if err != nil {
+			fmt.Printf("failed to load htpasswd file: %s", err)
+			os.Exit(1)
+		}
+		defer users.Close()
+
+		members = append(members, union.Member{Name: "htpasswd", Provider: provider.NewContextAdapter(users)})
+	}
+
+	if apiConfig.LDAPURL != "" {
+		directory, err := ldap.NewProvider(ldap.Config{
+			URL:               apiConfig.LDAPURL,
+			StartTLS:          apiConfig.LDAPStartTLS,
+			CAFile:            apiConfig.LDAPCAFile,
+			BindDN:            apiConfig.LDAPBindDN,
+			BindPassword:      apiConfig.LDAPBindPW,
+			BaseDN:            apiConfig.LDAPBaseDN,
+			UserFilter:        apiConfig.LDAPUserFilter,
+			UsernameAttribute: apiConfig.LDAPUserAttr,
+			UIDAttribute:      apiConfig.LDAPUIDAttr,
+			GroupBaseDN:       apiConfig.LDAPGroupBase,
+			GroupFilter:       apiConfig.LDAPGroupFilter,
+			TokenTTL:          apiConfig.TokenTTL,
+			Logger:            log.With(logger, "component", "ldap"),
+		})
+		if err != nil {
+			fmt.Printf("failed to create ldap provider: %s", err)
+			os.Exit(1)
+		}
+		defer directory.Close()
+
+		members = append(members, union.Member{Name: "ldap", Provider: directory})
+	}
+
+	if apiConfig.SQLDSN != "" {
+		database, err := sql.NewProvider(sql.Config{
+			Driver:  apiConfig.SQLDriver,
+			DSN:     apiConfig.SQLDSN,
+			Migrate: apiConfig.SQLMigrate,
+			Queries: sql.Queries{
+				User:   apiConfig.SQLUserQuery,
+				Groups: apiConfig.SQLGroupsQuery,
+			},
+			TokenTTL: apiConfig.TokenTTL,
+			Logger:   log.With(logger, "component", "sql"),
+		})
+		if err != nil {
+			fmt.Printf("failed to create sql provider: %s", err)
+			os.Exit(1)
+		}
+		defer database.Close()
+
+		members = append(members, union.Member{Name: "sql", Provider: database})
 	}
 
-	// initialize the authentication provider
-	fake := fake.NewFakeProvider()
+	if apiConfig.OIDCIssuerURL != "" {
+		var audiences []string
+		if apiConfig.OIDCClientID != "" {
+			audiences = []string{apiConfig.OIDCClientID}
+		}
+
+		idTokens, err := oidc.NewProvider(oidc.Config{
+			IssuerURL:         apiConfig.OIDCIssuerURL,
+			Audiences:         audiences,
+			JWKSFile:          apiConfig.OIDCJWKSFile,
+			ReloadInterval:    apiConfig.ReloadInterval,
+			SigningAlgorithms: apiConfig.OIDCSigningAlgs,
+			UsernameClaim:     apiConfig.OIDCUserClaim,
+			UsernamePrefix:    apiConfig.OIDCUserPrefix,
+			GroupsClaim:       apiConfig.OIDCGroupsClaim,
+			GroupsPrefix:      apiConfig.OIDCGroupPrefix,
+			Logger:            log.With(logger, "component", "oidc"),
+		})
+		if err != nil {
+			fmt.Printf("failed to create oidc provider: %s", err)
+			os.Exit(1)
+		}
+		defer idTokens.Close()
+
+		members = append(members, union.Member{Name: "oidc", Provider: idTokens})
+	}
+
+	if apiConfig.IntrospectURL != "" {
+		opaqueTokens, err := introspection.NewProvider(introspection.Config{
+			URL:          apiConfig.IntrospectURL,
+			ClientID:     apiConfig.IntrospectID,
+			ClientSecret: apiConfig.IntrospectKey,
+			TokenURL:     apiConfig.IntrospectToken,
+			Logger:       log.With(logger, "component", "introspection"),
+		})
+		if err != nil {
+			fmt.Printf("failed to create introspection provider: %s", err)
+			os.Exit(1)
+		}
+
+		members = append(members, union.Member{Name: "introspection", Provider: opaqueTokens})
+	}
+
+	if apiConfig.ExecCommand != "" {
+		plugin, err := exec.NewProvider(exec.Config{
+			Command:        apiConfig.ExecCommand,
+			Args:           apiConfig.ExecArgs,
+			Worker:         apiConfig.ExecWorker,
+			Timeout:        apiConfig.ExecTimeout,
+			MaxConcurrency: apiConfig.ExecConcurrency,
+			Logger:         log.With(logger, "component", "exec"),
+		})
+		if err != nil {
+			fmt.Printf("failed to create exec provider: %s", err)
+			os.Exit(1)
+		}
+		defer plugin.Close()
+
+		members = append(members, union.Member{Name: "exec", Provider: plugin})
+	}
+
+	if apiConfig.GRPCAddress != "" {
+		remote, err := grpc.NewProvider(grpc.Config{
+			Address:    apiConfig.GRPCAddress,
+			CAFile:     apiConfig.GRPCCAFile,
+			CertFile:   apiConfig.GRPCCertFile,
+			KeyFile:    apiConfig.GRPCKeyFile,
+			ServerName: apiConfig.GRPCServerName,
+			Timeout:    apiConfig.GRPCTimeout,
+		})
+		if err != nil {
+			fmt.Printf("failed to create grpc provider: %s", err)
+			os.Exit(1)
+		}
+		defer remote.Close()
+
+		members = append(members, union.Member{Name: "grpc", Provider: remote})
+	}
+
+	if apiConfig.WebhookURL != "" {
+		hook, err := webhook.NewProvider(webhook.Config{
+			URL:      apiConfig.WebhookURL,
+			LoginURL: apiConfig.WebhookLogin,
+			TLSCert:  apiConfig.WebhookCert,
+			TLSKey:   apiConfig.WebhookKey,
+			TLSCA:    apiConfig.WebhookCA,
+			Timeout:  apiConfig.WebhookTimeout,
+		})
+		if err != nil {
+			fmt.Printf("failed to create webhook provider: %s", err)
+			os.Exit(1)
+		}
+
+		members = append(members, union.Member{Name: "webhook", Provider: hook})
+	}
+
+	var prv provider.ContextProvider
+	switch len(members) {
+	case 0:
+		prv = provider.NewContextAdapter(fake.NewFakeProvider())
+	case 1:
+		prv = members[0].Provider
+	default:
+		mode := union.ContinueOnError
+		if apiConfig.FailOnError {
+			mode = union.FailOnError
+		}
+
+		chain, err := union.NewProvider(union.Config{
+			Providers: members,
+			Mode:      mode,
+			Logger:    log.With(logger, "component", "union"),
+		})
+		if err != nil {
+			fmt.Printf("failed to create provider chain: %s", err)
+			os.Exit(1)
+		}
+
+		prv = chain
+	}
+
+	// issue signed tokens for logins of the provider
+	var keys jwt.KeySet
+
+	if apiConfig.SigningKey != "" {
+		key, err := jwt.LoadKey(apiConfig.SigningKey)
+		if err != nil {
+			fmt.Printf("failed to load token signing key: %s", err)
+			os.Exit(1)
+		}
+
+		keys = jwt.NewStaticKeySet(key)
+	}
+
+	if apiConfig.SigningKeyDir != "" {
+		manager, err := jwt.NewManager(jwt.ManagerConfig{
+			Dir:              apiConfig.SigningKeyDir,
+			RotationInterval: apiConfig.KeyRotation,
+			RetentionPeriod:  apiConfig.TokenTTL + apiConfig.ClockSkew,
+			ReloadInterval:   apiConfig.ReloadInterval,
+			Logger:           log.With(logger, "component", "keys"),
+		})
+		if err != nil {
+			fmt.Printf("failed to load token signing keys: %s", err)
+			os.Exit(1)
+		}
+		defer manager.Close()
+
+		keys = manager
+	}
+
+	if keys != nil {
+		issuer, err := jwt.NewIssuer(jwt.Config{
+			Provider:  prv,
+			Keys:      keys,
+			Issuer:    apiConfig.TokenIssuer,
+			Audiences: apiConfig.Audiences,
+			Lifetime:  apiConfig.TokenTTL,
+			ClockSkew: apiConfig.ClockSkew,
+		})
+		if err != nil {
+			fmt.Printf("failed to create token issuer: %s", err)
+			os.Exit(1)
+		}
+
+		prv = issuer
+	}
 
 	// add the provider and config to the proxy
-	prx := authproxy.NewWithProvider(fake, config)
+	prx := authproxy.NewWithContextProvider(prv, config)
+
+	// initialize the policy authorizer
+	if apiConfig.PolicyFile != "" {
+		authz, err := policy.NewAuthorizer(policy.Config{
+			Path:           apiConfig.PolicyFile,
+			ReloadInterval: apiConfig.ReloadInterval,
+			Logger:         log.With(logger, "component", "policy"),
+		})
+		if err != nil {
+			fmt.Printf("failed to load authorization policy: %s", err)
+			os.Exit(1)
+		}
+		defer authz.Close()
+
+		prx.Authorizer = authz
+	}
 
 	if err := prx.ListenAndServe(); err != nil {
 		fmt.Printf("something went wrong: %s", err)
@@ -26,11 +26,13 @@ import (
 const (
 	FlagPath        = "path"
 	FlagTLSServerCA = "tls-ca-cert"
+	FlagAPIVersion  = "api-version"
 )
 
 type clientConf struct {
-	Path string
-	CA   string
+	Path       string
+	CA         string
+	APIVersion string
 }
 
 var (
@@ -47,6 +49,12 @@ var (
 			Usage:       "The tls server ca file to be used",
 			Destination: &clientConfig.CA,
 		},
+		cli.StringFlag{
+			Name:        FlagAPIVersion,
+			Usage:       "The TokenReview apiVersion to use (authentication.k8s.io/v1 or authentication.k8s.io/v1beta1)",
+			Value:       client.AuthenticationV1beta1,
+			Destination: &clientConfig.APIVersion,
+		},
 	}
 
 	clientActions = []cli.Command{
@@ -84,8 +92,9 @@ func loginAction(c *cli.Context) error {
 	username, password := c.Args()[0], c.Args()[1]
 
 	cfg := client.AuthClientConfig{
-		Path: clientConfig.Path,
-		CA:   clientConfig.CA,
+		Path:       clientConfig.Path,
+		CA:         clientConfig.CA,
+		APIVersion: clientConfig.APIVersion,
 	}
 
 	cl, err := client.NewForConfig(&cfg)
@@ -111,8 +120,9 @@ func authAction(c *cli.Context) error {
 	token := c.Args()[0]
 
 	cfg := client.AuthClientConfig{
-		Path: clientConfig.Path,
-		CA:   clientConfig.CA,
+		Path:       clientConfig.Path,
+		CA:         clientConfig.CA,
+		APIVersion: clientConfig.APIVersion,
 	}
 
 	cl, err := client.NewForConfig(&cfg)
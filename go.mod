@@ -1,6 +1,6 @@
 module github.com/cbrgm/authproxy
 
-go 1.12
+go 1.13
 
 require (
 	github.com/VividCortex/gohistogram v1.0.0 // indirect
@@ -13,10 +13,22 @@ require (
 	github.com/go-openapi/spec v0.19.2
 	github.com/go-openapi/strfmt v0.19.2
 	github.com/go-openapi/swag v0.19.4
+	github.com/go-openapi/validate v0.19.2
+	github.com/go-sql-driver/mysql v1.4.1
+	github.com/golang/protobuf v1.3.2
 	github.com/jessevdk/go-flags v1.4.0
+	github.com/lib/pq v1.2.0
+	github.com/mattn/go-sqlite3 v1.11.0
 	github.com/oklog/run v1.0.0
 	github.com/prometheus/client_golang v0.9.2
 	github.com/urfave/cli v1.20.0
+	golang.org/x/crypto v0.0.0-20191011191535-87dc89f01550
 	golang.org/x/net v0.0.0-20190724013045-ca1201d0de80
 	golang.org/x/oauth2 v0.0.0-20190604053449-0f29369cfe45
+	golang.org/x/time v0.0.0-20190308202827-9d24e82272b4
+	google.golang.org/grpc v1.24.0
+	gopkg.in/asn1-ber.v1 v1.0.0-20181015200546-f715ec2f112d
+	gopkg.in/ldap.v3 v3.1.0
+	gopkg.in/square/go-jose.v2 v2.3.1
+	gopkg.in/yaml.v2 v2.2.2
 )
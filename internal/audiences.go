@@ -0,0 +1,85 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package internal
+
+import (
+	"context"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+)
+
+type audienceService struct {
+	audiences []string
+	service   Service
+}
+
+// NewAudienceService returns a service rejecting tokens whose audiences do not intersect the given audiences.
+// Requests without audiences are authenticated against the given audiences. Like in Kubernetes, authenticated
+// results of audience unaware providers, which return no audiences in the status, are valid for all requested
+// audiences the api accepts.
+func NewAudienceService(audiences []string, s Service) Service {
+	return &audienceService{audiences: audiences, service: s}
+}
+
+func (s *audienceService) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	return s.service.Login(ctx, req)
+}
+
+func (s *audienceService) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	requested := req.Audiences
+	if len(requested) == 0 {
+		requested = s.audiences
+	}
+
+	// only ask the provider for audiences we accept
+	req.Audiences = intersect(requested, s.audiences)
+	if len(req.Audiences) == 0 {
+		return provider.Unauthenticated(), nil
+	}
+
+	trr, err := s.service.Authenticate(ctx, req)
+	if err != nil || trr == nil || trr.Status == nil || !trr.Status.Authenticated {
+		return trr, err
+	}
+
+	if len(trr.Status.Audiences) == 0 {
+		// the provider didn't check the audiences, the token is valid for the audiences of the api
+		trr.Status.Audiences = req.Audiences
+		return trr, nil
+	}
+
+	trr.Status.Audiences = intersect(trr.Status.Audiences, req.Audiences)
+	if len(trr.Status.Audiences) == 0 {
+		return provider.Unauthenticated(), nil
+	}
+
+	return trr, nil
+}
+
+// intersect returns the elements of a which are also contained in b
+func intersect(a, b []string) []string {
+	var res []string
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				res = append(res, x)
+				break
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package internal
+
+import (
+	"context"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"reflect"
+	"testing"
+)
+
+// audienceStub authenticates every token for its audiences
+type audienceStub struct {
+	audiences []string
+	requested []string
+}
+
+func (s *audienceStub) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	return provider.Unauthenticated(), nil
+}
+
+func (s *audienceStub) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	s.requested = req.Audiences
+	return &models.TokenReviewRequest{
+		Status: &models.TokenReviewStatus{
+			Authenticated: true,
+			User:          &models.UserInfo{Username: "foo"},
+			Audiences:     s.audiences,
+		},
+	}, nil
+}
+
+func TestAudienceService(t *testing.T) {
+	tests := []struct {
+		name      string
+		provided  []string
+		requested []string
+		forwarded []string
+		audiences []string
+	}{
+		{name: "default audiences", provided: []string{"api", "other"}, forwarded: []string{"api", "billing"}, audiences: []string{"api"}},
+		{name: "requested audience", provided: []string{"api", "billing"}, requested: []string{"billing"}, forwarded: []string{"billing"}, audiences: []string{"billing"}},
+		{name: "unknown audience", provided: []string{"api"}, requested: []string{"other"}},
+		{name: "token of other audience", provided: []string{"other"}, forwarded: []string{"api", "billing"}},
+		{name: "audience unaware provider", forwarded: []string{"api", "billing"}, audiences: []string{"api", "billing"}},
+		{name: "audience unaware provider with requested audiences", requested: []string{"billing", "other"}, forwarded: []string{"billing"}, audiences: []string{"billing"}},
+	}
+
+	for _, tt := range tests {
+		backend := &audienceStub{audiences: tt.provided}
+		s := NewAudienceService([]string{"api", "billing"}, backend)
+
+		trr, err := s.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "token", Audiences: tt.requested})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(backend.requested, tt.forwarded) {
+			t.Errorf("%s: expected audiences %v to be forwarded, got %v", tt.name, tt.forwarded, backend.requested)
+		}
+		if trr.Status.Authenticated != (tt.audiences != nil) {
+			t.Errorf("%s: expected authenticated %v, got %+v", tt.name, tt.audiences != nil, trr.Status)
+			continue
+		}
+		if tt.audiences != nil && !reflect.DeepEqual(trr.Status.Audiences, tt.audiences) {
+			t.Errorf("%s: expected audiences %v, got %v", tt.name, tt.audiences, trr.Status.Audiences)
+		}
+	}
+}
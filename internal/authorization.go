@@ -0,0 +1,45 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package internal
+
+import (
+	"context"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+)
+
+// AuthorizationService represents the authorization middleware used by authproxy
+type AuthorizationService interface {
+	Authorize(ctx context.Context, req provider.AuthorizeRequest) (*models.SubjectAccessReviewStatus, error)
+}
+
+// authorizationService represents the authorization middleware implementation
+type authorizationService struct {
+	authorizer provider.Authorizer
+}
+
+// NewAuthorizationService returns a new authorization middleware service configured with the given authorizer
+func NewAuthorizationService(authz provider.Authorizer) *authorizationService {
+	return &authorizationService{
+		authorizer: authz,
+	}
+}
+
+// Authorize wraps the authorizer specific implementation
+func (s *authorizationService) Authorize(ctx context.Context, req provider.AuthorizeRequest) (*models.SubjectAccessReviewStatus, error) {
+	return s.authorizer.Authorize(ctx, req)
+}
@@ -0,0 +1,270 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/go-kit/kit/metrics"
+	"sync"
+	"time"
+)
+
+// DefaultBreakerOpenDuration is the time an open circuit rejects calls before probing if none is configured
+const DefaultBreakerOpenDuration = 30 * time.Second
+
+// BreakerState is the state of a circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed passes all calls to the provider
+	BreakerClosed BreakerState = iota
+	// BreakerHalfOpen passes a single probe call to the provider to check whether it recovered
+	BreakerHalfOpen
+	// BreakerOpen rejects all calls without calling the provider
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerConfig represents the configuration of the circuit breaker around provider calls
+type BreakerConfig struct {
+	// Timeout of every provider call, 0 disables it
+	Timeout time.Duration
+	// Failures is the number of consecutive failed or timed out calls opening the circuit, 0 never opens it
+	Failures int
+	// OpenDuration is the time an open circuit rejects calls before a probe call is passed (default: 30s)
+	OpenDuration time.Duration
+}
+
+// Breaker tracks the failures of provider calls and opens the circuit after too many of them
+type Breaker struct {
+	config BreakerConfig
+	state  metrics.Gauge
+	now    func() time.Time
+
+	mu         sync.Mutex
+	current    BreakerState
+	generation uint64
+	failures   int
+	opened     time.Time
+	probing    bool
+}
+
+// outcome is the result of a call for the breaker
+type outcome int
+
+const (
+	succeeded outcome = iota
+	failed
+	// abandoned calls were canceled by the caller and say nothing about the provider
+	abandoned
+)
+
+// NewBreaker returns a new closed circuit breaker reporting its state to the gauge, labeled by state
+func NewBreaker(cfg BreakerConfig, state metrics.Gauge) *Breaker {
+	if cfg.OpenDuration <= 0 {
+		cfg.OpenDuration = DefaultBreakerOpenDuration
+	}
+
+	b := &Breaker{config: cfg, state: state, now: time.Now}
+	b.report()
+	return b
+}
+
+// State returns the current state, an open circuit whose open duration elapsed is reported half-open
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.current == BreakerOpen && b.now().Sub(b.opened) >= b.config.OpenDuration {
+		return BreakerHalfOpen
+	}
+	return b.current
+}
+
+// allow returns whether a call may be passed to the provider and the generation its outcome belongs to
+func (b *Breaker) allow() (uint64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.current {
+	case BreakerOpen:
+		if b.now().Sub(b.opened) < b.config.OpenDuration {
+			return 0, false
+		}
+		b.transition(BreakerHalfOpen)
+		b.probing = true
+		return b.generation, true
+	case BreakerHalfOpen:
+		if b.probing {
+			return 0, false
+		}
+		b.probing = true
+		return b.generation, true
+	default:
+		return b.generation, true
+	}
+}
+
+// done records the outcome of a call, outcomes of calls started before the last transition are ignored
+func (b *Breaker) done(generation uint64, o outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+
+	switch b.current {
+	case BreakerHalfOpen:
+		b.probing = false
+		switch o {
+		case succeeded:
+			b.transition(BreakerClosed)
+		case failed:
+			b.transition(BreakerOpen)
+		}
+	case BreakerClosed:
+		switch o {
+		case succeeded:
+			b.failures = 0
+		case failed:
+			b.failures++
+			if b.config.Failures > 0 && b.failures >= b.config.Failures {
+				b.transition(BreakerOpen)
+			}
+		}
+	}
+}
+
+// transition changes the state and starts a new generation, the caller must hold the lock
+func (b *Breaker) transition(state BreakerState) {
+	b.current = state
+	b.generation++
+	b.failures = 0
+	if state == BreakerOpen {
+		b.opened = b.now()
+	}
+	b.report()
+}
+
+// report sets the gauge of the current state to 1 and the others to 0
+func (b *Breaker) report() {
+	for _, s := range []BreakerState{BreakerClosed, BreakerHalfOpen, BreakerOpen} {
+		value := 0.0
+		if s == b.current {
+			value = 1
+		}
+		b.state.With("state", s.String()).Set(value)
+	}
+}
+
+type breakerService struct {
+	breaker *Breaker
+	service Service
+}
+
+// NewBreakerService returns a service enforcing the timeout of the breaker on every call and
+// failing fast with service unavailable while the circuit is open.
+// Calls exceeding the timeout return immediately and their context is cancelled, providers
+// adapted with provider.NewContextAdapter ignore it and are left to finish in the background.
+func NewBreakerService(breaker *Breaker, service Service) Service {
+	return &breakerService{breaker: breaker, service: service}
+}
+
+func (s *breakerService) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	return s.call(ctx, func(ctx context.Context) (*models.TokenReviewRequest, error) {
+		return s.service.Login(ctx, req)
+	})
+}
+
+func (s *breakerService) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	return s.call(ctx, func(ctx context.Context) (*models.TokenReviewRequest, error) {
+		return s.service.Authenticate(ctx, req)
+	})
+}
+
+type callResult struct {
+	trr *models.TokenReviewRequest
+	err error
+}
+
+// call runs fn if the circuit allows it and records its outcome
+func (s *breakerService) call(ctx context.Context, fn func(context.Context) (*models.TokenReviewRequest, error)) (*models.TokenReviewRequest, error) {
+	generation, ok := s.breaker.allow()
+	if !ok {
+		return nil, errors.NewServiceUnavailable(fmt.Errorf("provider circuit breaker is open"))
+	}
+
+	callCtx := ctx
+	if s.breaker.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, s.breaker.config.Timeout)
+		defer cancel()
+	}
+
+	// providers ignoring the context must not block the caller beyond the timeout
+	results := make(chan callResult, 1)
+	go func() {
+		trr, err := fn(callCtx)
+		results <- callResult{trr: trr, err: err}
+	}()
+
+	select {
+	case res := <-results:
+		switch {
+		case ctx.Err() != nil:
+			s.breaker.done(generation, abandoned)
+		case isProviderFailure(res.err):
+			s.breaker.done(generation, failed)
+		default:
+			s.breaker.done(generation, succeeded)
+		}
+		return res.trr, res.err
+	case <-callCtx.Done():
+		if ctx.Err() != nil {
+			s.breaker.done(generation, abandoned)
+			return nil, ctx.Err()
+		}
+		s.breaker.done(generation, failed)
+		return nil, errors.NewServiceUnavailable(fmt.Errorf("provider call timed out after %s", s.breaker.config.Timeout))
+	}
+}
+
+// isProviderFailure returns true for errors indicating a broken provider, client errors like 401 are answers
+func isProviderFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	reason := errors.ReasonForError(err)
+	return reason == 0 || reason >= 500
+}
@@ -0,0 +1,220 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/go-kit/kit/metrics"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testGauge records the values of a gauge by label values
+type testGauge struct {
+	mu     *sync.Mutex
+	values map[string]float64
+	lvs    []string
+}
+
+func newTestGauge() *testGauge {
+	return &testGauge{mu: &sync.Mutex{}, values: map[string]float64{}}
+}
+
+func (g *testGauge) With(labelValues ...string) metrics.Gauge {
+	return &testGauge{mu: g.mu, values: g.values, lvs: append(append([]string(nil), g.lvs...), labelValues...)}
+}
+
+func (g *testGauge) Set(value float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[strings.Join(g.lvs, "=")] = value
+}
+
+func (g *testGauge) Add(delta float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[strings.Join(g.lvs, "=")] += delta
+}
+
+func (g *testGauge) value(labelValues ...string) float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.values[strings.Join(labelValues, "=")]
+}
+
+// flakyService fails Authenticate with err, hangs until its context is done if hang is set and counts the calls
+type flakyService struct {
+	mu    sync.Mutex
+	calls int
+	err   error
+	hang  bool
+}
+
+func (s *flakyService) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	return provider.Unauthenticated(), nil
+}
+
+func (s *flakyService) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	s.mu.Lock()
+	s.calls++
+	err, hang := s.err, s.hang
+	s.mu.Unlock()
+
+	if hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return provider.Unauthenticated(), nil
+}
+
+func (s *flakyService) set(err error, hang bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err, s.hang = err, hang
+}
+
+func (s *flakyService) called() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func TestBreakerService(t *testing.T) {
+	backend := &flakyService{}
+	state := newTestGauge()
+	breaker := NewBreaker(BreakerConfig{Timeout: 20 * time.Millisecond, Failures: 2, OpenDuration: time.Minute}, state)
+	now := time.Unix(1500000000, 0)
+	breaker.now = func() time.Time { return now }
+	s := NewBreakerService(breaker, backend)
+	ctx := context.Background()
+
+	// client errors and unauthenticated tokens don't count as failures
+	backend.set(errors.NewUnauthorized("invalid token"), false)
+	for i := 0; i < 3; i++ {
+		if _, err := s.Authenticate(ctx, provider.AuthenticateRequest{Token: "foo"}); !errors.IsUnauthorized(err) {
+			t.Fatalf("expected unauthorized error, got %v", err)
+		}
+	}
+	if breaker.State() != BreakerClosed {
+		t.Fatalf("expected circuit to stay closed, got %s", breaker.State())
+	}
+
+	// a failure and a timeout open the circuit
+	backend.set(fmt.Errorf("connection refused"), false)
+	if _, err := s.Authenticate(ctx, provider.AuthenticateRequest{Token: "foo"}); err == nil {
+		t.Fatal("expected provider error")
+	}
+	backend.set(nil, true)
+	if _, err := s.Authenticate(ctx, provider.AuthenticateRequest{Token: "foo"}); !errors.IsServiceUnavailable(err) {
+		t.Fatalf("expected timeout to be service unavailable, got %v", err)
+	}
+	if breaker.State() != BreakerOpen || state.value("state", "open") != 1 || state.value("state", "closed") != 0 {
+		t.Fatalf("expected circuit to be open, got %s", breaker.State())
+	}
+
+	calls := backend.called()
+	if _, err := s.Authenticate(ctx, provider.AuthenticateRequest{Token: "foo"}); !errors.IsServiceUnavailable(err) {
+		t.Fatalf("expected open circuit to fail fast, got %v", err)
+	}
+	if backend.called() != calls {
+		t.Error("expected open circuit not to call the provider")
+	}
+
+	// a failed probe opens the circuit again
+	now = now.Add(time.Minute)
+	if breaker.State() != BreakerHalfOpen {
+		t.Fatalf("expected circuit to be half-open after the open duration, got %s", breaker.State())
+	}
+	if _, err := s.Authenticate(ctx, provider.AuthenticateRequest{Token: "foo"}); !errors.IsServiceUnavailable(err) {
+		t.Fatalf("expected probe to time out, got %v", err)
+	}
+	if breaker.State() != BreakerOpen {
+		t.Fatalf("expected failed probe to open the circuit, got %s", breaker.State())
+	}
+
+	// a successful probe closes it
+	now = now.Add(time.Minute)
+	backend.set(nil, false)
+	if _, err := s.Authenticate(ctx, provider.AuthenticateRequest{Token: "foo"}); err != nil {
+		t.Fatalf("expected probe to succeed, got %v", err)
+	}
+	if breaker.State() != BreakerClosed || state.value("state", "closed") != 1 || state.value("state", "open") != 0 {
+		t.Fatalf("expected successful probe to close the circuit, got %s", breaker.State())
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	breaker := NewBreaker(BreakerConfig{Failures: 1, OpenDuration: time.Second}, newTestGauge())
+	now := time.Unix(1500000000, 0)
+	breaker.now = func() time.Time { return now }
+
+	generation, _ := breaker.allow()
+	breaker.done(generation, failed)
+	if breaker.State() != BreakerOpen {
+		t.Fatalf("expected circuit to be open, got %s", breaker.State())
+	}
+
+	now = now.Add(time.Second)
+	probe, ok := breaker.allow()
+	if !ok {
+		t.Fatal("expected probe to be allowed")
+	}
+	if _, ok := breaker.allow(); ok {
+		t.Error("expected only one probe at a time")
+	}
+
+	// probes abandoned by their caller allow another probe
+	breaker.done(probe, abandoned)
+	if probe, ok = breaker.allow(); !ok {
+		t.Fatal("expected another probe to be allowed")
+	}
+
+	// outcomes of calls started before the circuit opened are ignored
+	breaker.done(generation, succeeded)
+	if breaker.State() != BreakerHalfOpen {
+		t.Errorf("expected stale outcome to be ignored, got %s", breaker.State())
+	}
+	breaker.done(probe, succeeded)
+	if breaker.State() != BreakerClosed {
+		t.Errorf("expected circuit to be closed, got %s", breaker.State())
+	}
+}
+
+func TestBreakerServiceCanceled(t *testing.T) {
+	backend := &flakyService{hang: true}
+	breaker := NewBreaker(BreakerConfig{Timeout: time.Minute, Failures: 1}, newTestGauge())
+	s := NewBreakerService(breaker, backend)
+
+	// callers giving up don't count as provider failures
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := s.Authenticate(ctx, provider.AuthenticateRequest{Token: "foo"}); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline of the caller to be exceeded, got %v", err)
+	}
+	if breaker.State() != BreakerClosed {
+		t.Errorf("expected circuit to stay closed, got %s", breaker.State())
+	}
+}
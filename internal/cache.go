@@ -0,0 +1,256 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package internal
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/go-kit/kit/metrics"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCacheMaxEntries is the number of cached decisions if no limit is configured
+	DefaultCacheMaxEntries = 10000
+	// DefaultCacheMaxBytes is the estimated memory of cached decisions if no limit is configured
+	DefaultCacheMaxBytes = 64 << 20
+
+	// cacheEntryOverhead estimates the memory of an entry besides its key and TokenReview
+	cacheEntryOverhead = 256
+)
+
+// CacheConfig represents the configuration of the authentication decision cache
+type CacheConfig struct {
+	// TTL of authenticated decisions, they are never cached beyond the expiration of the token. 0 disables caching them
+	TTL time.Duration
+	// NegativeTTL of unauthenticated decisions, 0 disables caching them. Errors are never cached
+	NegativeTTL time.Duration
+	// MaxEntries limits the number of cached decisions (default: 10000)
+	MaxEntries int
+	// MaxBytes limits the estimated memory of cached decisions (default: 64MiB)
+	MaxBytes int
+}
+
+type cacheService struct {
+	config    CacheConfig
+	lookups   metrics.Counter
+	evictions metrics.Counter
+	service   Service
+	now       func() time.Time
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*list.Element
+	lru     *list.List
+	bytes   int
+}
+
+type cacheEntry struct {
+	key     [sha256.Size]byte
+	trr     *models.TokenReviewRequest
+	size    int
+	expires time.Time
+}
+
+// NewCacheService returns a service caching the authentication decisions of tokens.
+// Entries are keyed by a hash of the token and the requested audiences, the least recently used
+// entries are evicted once the entry or memory limit is reached. Logins are never cached.
+func NewCacheService(cfg CacheConfig, lookups, evictions metrics.Counter, service Service) Service {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = DefaultCacheMaxEntries
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultCacheMaxBytes
+	}
+
+	// Initialize counters with 0
+	lookups.With("result", "hit").Add(0)
+	lookups.With("result", "miss").Add(0)
+	evictions.With("reason", "expired").Add(0)
+	evictions.With("reason", "capacity").Add(0)
+
+	return &cacheService{
+		config:    cfg,
+		lookups:   lookups,
+		evictions: evictions,
+		service:   service,
+		now:       time.Now,
+		entries:   map[[sha256.Size]byte]*list.Element{},
+		lru:       list.New(),
+	}
+}
+
+func (s *cacheService) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	return s.service.Login(ctx, req)
+}
+
+func (s *cacheService) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	key := cacheKey(req)
+
+	if trr, ok := s.get(key); ok {
+		s.lookups.With("result", "hit").Add(1)
+		return trr, nil
+	}
+	s.lookups.With("result", "miss").Add(1)
+
+	trr, err := s.service.Authenticate(ctx, req)
+	if err != nil || trr == nil || trr.Status == nil {
+		return trr, err
+	}
+
+	ttl := s.config.NegativeTTL
+	if trr.Status.Authenticated {
+		ttl = s.config.TTL
+	}
+	if ttl <= 0 {
+		return trr, nil
+	}
+
+	expires := s.now().Add(ttl)
+	if exp := trr.Status.Expiration; exp != 0 && time.Unix(exp, 0).Before(expires) {
+		expires = time.Unix(exp, 0)
+	}
+	s.add(key, copyTokenReview(trr), expires)
+
+	return trr, nil
+}
+
+// get returns a copy of the cached decision of a key
+func (s *cacheService) get(key [sha256.Size]byte) (*models.TokenReviewRequest, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if !s.now().Before(entry.expires) {
+		s.remove(elem, "expired")
+		return nil, false
+	}
+
+	s.lru.MoveToFront(elem)
+	return copyTokenReview(entry.trr), true
+}
+
+// add caches the decision of a key until it expires, evicting the least recently used entries if needed
+func (s *cacheService) add(key [sha256.Size]byte, trr *models.TokenReviewRequest, expires time.Time) {
+	size := cacheEntryOverhead + len(key)
+	if b, err := json.Marshal(trr); err == nil {
+		size += len(b)
+	}
+	if size > s.config.MaxBytes {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.entries[key]; ok {
+		s.remove(elem, "")
+	}
+
+	now := s.now()
+	for s.lru.Len() >= s.config.MaxEntries || s.bytes+size > s.config.MaxBytes {
+		oldest := s.lru.Back()
+		if !now.Before(oldest.Value.(*cacheEntry).expires) {
+			s.remove(oldest, "expired")
+		} else {
+			s.remove(oldest, "capacity")
+		}
+	}
+
+	s.entries[key] = s.lru.PushFront(&cacheEntry{key: key, trr: trr, size: size, expires: expires})
+	s.bytes += size
+}
+
+// remove deletes an entry and counts its eviction for the reason, the caller must hold the lock
+func (s *cacheService) remove(elem *list.Element, reason string) {
+	entry := s.lru.Remove(elem).(*cacheEntry)
+	delete(s.entries, entry.key)
+	s.bytes -= entry.size
+
+	if reason != "" {
+		s.evictions.With("reason", reason).Add(1)
+	}
+}
+
+// cacheKey returns the hash of the token and the requested audiences, so raw tokens are never kept in memory
+func cacheKey(req provider.AuthenticateRequest) [sha256.Size]byte {
+	audiences := append([]string(nil), req.Audiences...)
+	sort.Strings(audiences)
+
+	return sha256.Sum256([]byte(req.Token + "\x00" + strings.Join(audiences, "\x00")))
+}
+
+// copyTokenReview returns a copy of a TokenReview which can be modified without changing the original
+func copyTokenReview(trr *models.TokenReviewRequest) *models.TokenReviewRequest {
+	c := *trr
+	if trr.Spec != nil {
+		spec := *trr.Spec
+		spec.Audiences = copyStrings(trr.Spec.Audiences)
+		c.Spec = &spec
+	}
+
+	status := *trr.Status
+	status.Audiences = copyStrings(trr.Status.Audiences)
+	if trr.Status.User != nil {
+		user := *trr.Status.User
+		user.Groups = copyStrings(trr.Status.User.Groups)
+		user.Extra = copyExtra(trr.Status.User.Extra)
+		status.User = &user
+	}
+	c.Status = &status
+
+	return &c
+}
+
+// copyExtra returns a copy of the extra maps providers return, other values are shared
+func copyExtra(extra interface{}) interface{} {
+	switch e := extra.(type) {
+	case map[string][]string:
+		c := make(map[string][]string, len(e))
+		for k, v := range e {
+			c[k] = copyStrings(v)
+		}
+		return c
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(e))
+		for k, v := range e {
+			c[k] = v
+		}
+		return c
+	default:
+		return extra
+	}
+}
+
+// copyStrings returns a copy of a slice, keeping nil and empty slices apart as they are encoded differently
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string{}, s...)
+}
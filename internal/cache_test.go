@@ -0,0 +1,218 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package internal
+
+import (
+	"context"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/go-kit/kit/metrics"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingService authenticates the token foo as user foo and counts the calls
+type countingService struct {
+	calls int
+	exp   int64
+}
+
+func (s *countingService) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	return provider.Unauthenticated(), nil
+}
+
+func (s *countingService) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	s.calls++
+	if req.Token != "foo" {
+		return provider.Unauthenticated(), nil
+	}
+	return &models.TokenReviewRequest{
+		Status: &models.TokenReviewStatus{
+			Authenticated: true,
+			User:          &models.UserInfo{Username: "foo", Groups: []string{"developers"}},
+			Audiences:     req.Audiences,
+			Expiration:    s.exp,
+		},
+	}, nil
+}
+
+// testCounter records the values of a counter by label values
+type testCounter struct {
+	mu     *sync.Mutex
+	values map[string]float64
+	lvs    []string
+}
+
+func newTestCounter() *testCounter {
+	return &testCounter{mu: &sync.Mutex{}, values: map[string]float64{}}
+}
+
+func (c *testCounter) With(labelValues ...string) metrics.Counter {
+	return &testCounter{mu: c.mu, values: c.values, lvs: append(append([]string(nil), c.lvs...), labelValues...)}
+}
+
+func (c *testCounter) Add(delta float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[strings.Join(c.lvs, "=")] += delta
+}
+
+func (c *testCounter) value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[strings.Join(labelValues, "=")]
+}
+
+func newTestCache(cfg CacheConfig, backend Service) (*cacheService, *testCounter, *testCounter, *time.Time) {
+	lookups, evictions := newTestCounter(), newTestCounter()
+	s := NewCacheService(cfg, lookups, evictions, backend).(*cacheService)
+
+	now := time.Unix(1500000000, 0)
+	s.now = func() time.Time { return now }
+
+	return s, lookups, evictions, &now
+}
+
+func TestCacheService(t *testing.T) {
+	backend := &countingService{}
+	s, lookups, evictions, now := newTestCache(CacheConfig{TTL: time.Minute, NegativeTTL: 10 * time.Second}, backend)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		trr, err := s.Authenticate(ctx, provider.AuthenticateRequest{Token: "foo"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !trr.Status.Authenticated || trr.Status.User.Username != "foo" {
+			t.Fatalf("expected token to authenticate foo, got %+v", trr.Status)
+		}
+		// callers modifying results must not change the cache
+		trr.Status.User.Groups[0] = "admins"
+	}
+	if backend.calls != 1 {
+		t.Errorf("expected 1 call to the service, got %d", backend.calls)
+	}
+	if hits, misses := lookups.value("result", "hit"), lookups.value("result", "miss"); hits != 1 || misses != 1 {
+		t.Errorf("expected 1 hit and 1 miss, got %v and %v", hits, misses)
+	}
+
+	trr, err := s.Authenticate(ctx, provider.AuthenticateRequest{Token: "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groups := trr.Status.User.Groups; len(groups) != 1 || groups[0] != "developers" {
+		t.Errorf("expected cached groups to be unchanged, got %v", groups)
+	}
+
+	// audiences are part of the key
+	if _, err := s.Authenticate(ctx, provider.AuthenticateRequest{Token: "foo", Audiences: []string{"api"}}); err != nil {
+		t.Fatal(err)
+	}
+	if backend.calls != 2 {
+		t.Errorf("expected requests for other audiences to miss the cache, got %d calls", backend.calls)
+	}
+
+	// unauthenticated decisions are cached for the negative TTL
+	for i := 0; i < 2; i++ {
+		if _, err := s.Authenticate(ctx, provider.AuthenticateRequest{Token: "bar"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if backend.calls != 3 {
+		t.Errorf("expected unauthenticated decision to be cached, got %d calls", backend.calls)
+	}
+
+	*now = now.Add(10 * time.Second)
+	if _, err := s.Authenticate(ctx, provider.AuthenticateRequest{Token: "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Authenticate(ctx, provider.AuthenticateRequest{Token: "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	if backend.calls != 4 {
+		t.Errorf("expected only the unauthenticated decision to expire, got %d calls", backend.calls)
+	}
+	if expired := evictions.value("reason", "expired"); expired != 1 {
+		t.Errorf("expected 1 expired eviction, got %v", expired)
+	}
+
+	for key := range s.entries {
+		if strings.Contains(string(key[:]), "foo") {
+			t.Error("expected cache keys not to contain tokens")
+		}
+	}
+}
+
+func TestCacheServiceExpiration(t *testing.T) {
+	backend := &countingService{}
+	s, _, _, now := newTestCache(CacheConfig{TTL: time.Hour}, backend)
+	backend.exp = now.Add(time.Minute).Unix()
+
+	for _, elapsed := range []time.Duration{0, 30 * time.Second, 30 * time.Second} {
+		*now = now.Add(elapsed)
+		if _, err := s.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "foo"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if backend.calls != 2 {
+		t.Errorf("expected decision not to be cached beyond the token expiration, got %d calls", backend.calls)
+	}
+
+	// without a negative TTL unauthenticated decisions are not cached
+	for i := 0; i < 2; i++ {
+		if _, err := s.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "bar"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if backend.calls != 4 {
+		t.Errorf("expected unauthenticated decisions not to be cached, got %d calls", backend.calls)
+	}
+}
+
+func TestCacheServiceLimits(t *testing.T) {
+	backend := &countingService{}
+	s, _, evictions, _ := newTestCache(CacheConfig{NegativeTTL: time.Minute, MaxEntries: 2}, backend)
+	ctx := context.Background()
+
+	for _, token := range []string{"a", "b", "a", "c", "a", "b"} {
+		if _, err := s.Authenticate(ctx, provider.AuthenticateRequest{Token: token}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// c evicts b as a was used more recently, then b evicts c
+	if backend.calls != 4 {
+		t.Errorf("expected the least recently used entries to be evicted, got %d calls", backend.calls)
+	}
+	if capacity := evictions.value("reason", "capacity"); capacity != 2 {
+		t.Errorf("expected 2 evictions, got %v", capacity)
+	}
+	if len(s.entries) != 2 || s.lru.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", len(s.entries))
+	}
+
+	s, _, _, _ = newTestCache(CacheConfig{NegativeTTL: time.Minute, MaxBytes: 2 * cacheEntryOverhead}, backend)
+	for _, token := range []string{"a", "b", "c"} {
+		if _, err := s.Authenticate(ctx, provider.AuthenticateRequest{Token: token}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.bytes > 2*cacheEntryOverhead || len(s.entries) != 1 {
+		t.Errorf("expected the memory limit to hold 1 entry, got %d entries of %d bytes", len(s.entries), s.bytes)
+	}
+}
@@ -0,0 +1,299 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultLockoutWindow is the sliding window failed logins are counted in if none is configured
+	DefaultLockoutWindow = 15 * time.Minute
+	// DefaultLockoutBaseDelay is the first backoff delay if none is configured
+	DefaultLockoutBaseDelay = time.Second
+	// DefaultLockoutDuration is the longest backoff delay if none is configured
+	DefaultLockoutDuration = 15 * time.Minute
+
+	// lockoutPurgeInterval limits how often idle keys are removed from the memory store
+	lockoutPurgeInterval = time.Minute
+)
+
+// LockoutConfig represents the configuration of the login brute-force protection
+type LockoutConfig struct {
+	// UserFailures is the number of failed logins of a username within the window before further
+	// logins of it are delayed, 0 disables tracking usernames
+	UserFailures int
+	// ClientIPFailures is the number of failed logins of a client IP within the window before further
+	// logins from it are delayed, 0 disables tracking client IPs
+	ClientIPFailures int
+	// Window is the sliding window failed logins are counted in (default: 15m)
+	Window time.Duration
+	// BaseDelay is the delay after the first failure beyond the limit, it doubles with every further failure (default: 1s)
+	BaseDelay time.Duration
+	// Duration is the longest delay, reaching it locks the username or client IP out temporarily (default: 15m)
+	Duration time.Duration
+	// Store holds the failure counters (default: in-memory store)
+	Store LockoutStore
+}
+
+// LockoutStore holds failed logins and lockouts by key.
+// Implementations backed by shared storage apply lockouts across several authproxy replicas.
+type LockoutStore interface {
+	// AddFailure records a failed login of the key at now and returns the failures within the window ending at now
+	AddFailure(key string, now time.Time, window time.Duration) (int, error)
+	// Lock locks the key until the given time
+	Lock(key string, until time.Time) error
+	// LockedUntil returns the time the key is locked until, the zero time if it isn't locked
+	LockedUntil(key string) (time.Time, error)
+	// Reset removes the failures and the lock of the key
+	Reset(key string) error
+}
+
+type lockoutService struct {
+	config  LockoutConfig
+	service Service
+	now     func() time.Time
+}
+
+// NewLockoutService returns a service protecting logins against brute-force attacks.
+// Failed logins are counted per username and per client IP in a sliding window, once a limit is exceeded
+// every further failure doubles the time logins are rejected with 429 Too Many Requests, up to the lockout duration.
+// Unauthenticated results and 401 or 403 errors count as failures, errors of unavailable providers don't.
+func NewLockoutService(cfg LockoutConfig, service Service) Service {
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultLockoutWindow
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = DefaultLockoutBaseDelay
+	}
+	if cfg.Duration <= 0 {
+		cfg.Duration = DefaultLockoutDuration
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryLockoutStore()
+	}
+
+	return &lockoutService{config: cfg, service: service, now: time.Now}
+}
+
+func (s *lockoutService) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	keys := s.keys(req)
+
+	now := s.now()
+	var until time.Time
+	for _, k := range keys {
+		locked, err := s.config.Store.LockedUntil(k.key)
+		if err != nil {
+			return nil, errors.NewServiceUnavailable(fmt.Errorf("failed to read login lockout: %v", err))
+		}
+		if locked.After(until) {
+			until = locked
+		}
+	}
+	if until.After(now) {
+		return nil, tooManyLogins(until.Sub(now))
+	}
+
+	trr, err := s.service.Login(ctx, req)
+	if err != nil && !rejected(err) || err == nil && (trr == nil || trr.Status == nil) {
+		// failing providers say nothing about the password
+		return trr, err
+	}
+
+	if err == nil && trr.Status.Authenticated {
+		// only the username is reset, a valid account must not unlock the client IP for guessing others
+		for _, k := range keys {
+			if k.user {
+				if err := s.config.Store.Reset(k.key); err != nil {
+					return nil, errors.NewServiceUnavailable(fmt.Errorf("failed to reset login failures: %v", err))
+				}
+			}
+		}
+		return trr, nil
+	}
+
+	for _, k := range keys {
+		failures, err := s.config.Store.AddFailure(k.key, now, s.config.Window)
+		if err != nil {
+			return nil, errors.NewServiceUnavailable(fmt.Errorf("failed to record login failure: %v", err))
+		}
+		if delay := s.delay(failures, k.limit); delay > 0 {
+			if err := s.config.Store.Lock(k.key, now.Add(delay)); err != nil {
+				return nil, errors.NewServiceUnavailable(fmt.Errorf("failed to lock logins: %v", err))
+			}
+		}
+	}
+
+	return trr, err
+}
+
+// rejected returns true for errors of providers rejecting the credentials, e.g. of disabled accounts,
+// they count as failed logins like unauthenticated results
+func rejected(err error) bool {
+	return errors.IsUnauthorized(err) || errors.IsForbidden(err)
+}
+
+func (s *lockoutService) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	return s.service.Authenticate(ctx, req)
+}
+
+// lockoutKey is a username or client IP failures are counted for
+type lockoutKey struct {
+	key   string
+	limit int
+	user  bool
+}
+
+// keys returns the keys of a login request whose failures are counted
+func (s *lockoutService) keys(req provider.LoginRequest) []lockoutKey {
+	var keys []lockoutKey
+	if s.config.UserFailures > 0 && req.Username != "" {
+		keys = append(keys, lockoutKey{key: "user:" + req.Username, limit: s.config.UserFailures, user: true})
+	}
+	if s.config.ClientIPFailures > 0 && req.Metadata.ClientIP != "" {
+		keys = append(keys, lockoutKey{key: "ip:" + req.Metadata.ClientIP, limit: s.config.ClientIPFailures})
+	}
+	return keys
+}
+
+// delay returns how long logins are rejected after the given number of failures
+func (s *lockoutService) delay(failures, limit int) time.Duration {
+	if failures <= limit {
+		return 0
+	}
+
+	// the exponent is capped as the delay reaches the lockout duration long before overflowing
+	exp := failures - limit - 1
+	if exp > 62 {
+		exp = 62
+	}
+	delay := float64(s.config.BaseDelay) * math.Pow(2, float64(exp))
+	if delay >= float64(s.config.Duration) {
+		return s.config.Duration
+	}
+	return time.Duration(delay)
+}
+
+// tooManyLogins returns a 429 error asking the client to retry after the delay
+func tooManyLogins(delay time.Duration) error {
+	seconds := int(math.Ceil(delay.Seconds()))
+	return errors.NewTooManyRequests("too many failed logins", seconds)
+}
+
+// MemoryLockoutStore implements LockoutStore in memory, counters are lost on restarts
+type MemoryLockoutStore struct {
+	mu     sync.Mutex
+	keys   map[string]*lockoutEntry
+	purged time.Time
+}
+
+type lockoutEntry struct {
+	failures []time.Time
+	window   time.Duration
+	locked   time.Time
+}
+
+// NewMemoryLockoutStore returns a new in-memory lockout store
+func NewMemoryLockoutStore() *MemoryLockoutStore {
+	return &MemoryLockoutStore{keys: map[string]*lockoutEntry{}}
+}
+
+// AddFailure records a failed login of the key at now and returns the failures within the window ending at now
+func (s *MemoryLockoutStore) AddFailure(key string, now time.Time, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.purge(now)
+
+	entry, ok := s.keys[key]
+	if !ok {
+		entry = &lockoutEntry{}
+		s.keys[key] = entry
+	}
+	entry.window = window
+	entry.failures = append(entry.expire(now), now)
+
+	return len(entry.failures), nil
+}
+
+// Lock locks the key until the given time
+func (s *MemoryLockoutStore) Lock(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.keys[key]
+	if !ok {
+		entry = &lockoutEntry{}
+		s.keys[key] = entry
+	}
+	entry.locked = until
+
+	return nil
+}
+
+// LockedUntil returns the time the key is locked until, the zero time if it isn't locked
+func (s *MemoryLockoutStore) LockedUntil(key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.keys[key]
+	if !ok {
+		return time.Time{}, nil
+	}
+	return entry.locked, nil
+}
+
+// Reset removes the failures and the lock of the key
+func (s *MemoryLockoutStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, key)
+	return nil
+}
+
+// purge removes keys without failures in their window or lock, the caller must hold the lock
+func (s *MemoryLockoutStore) purge(now time.Time) {
+	if now.Sub(s.purged) < lockoutPurgeInterval {
+		return
+	}
+	for key, entry := range s.keys {
+		entry.failures = entry.expire(now)
+		if len(entry.failures) == 0 && !entry.locked.After(now) {
+			delete(s.keys, key)
+		}
+	}
+	s.purged = now
+}
+
+// expire returns the failures within the window ending at now
+func (e *lockoutEntry) expire(now time.Time) []time.Time {
+	start := now.Add(-e.window)
+	for i, t := range e.failures {
+		if t.After(start) {
+			return e.failures[i:]
+		}
+	}
+	return e.failures[:0]
+}
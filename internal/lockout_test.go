@@ -0,0 +1,196 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"testing"
+	"time"
+)
+
+// passwordService accepts the password bar for every user and counts the logins,
+// other passwords are rejected with err if set
+type passwordService struct {
+	logins int
+	err    error
+}
+
+func (s *passwordService) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	s.logins++
+	if req.Password != "bar" {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return provider.Unauthenticated(), nil
+	}
+	return &models.TokenReviewRequest{
+		Status: &models.TokenReviewStatus{
+			Authenticated: true,
+			User:          &models.UserInfo{Username: req.Username},
+		},
+	}, nil
+}
+
+func (s *passwordService) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	return provider.Unauthenticated(), nil
+}
+
+func login(s Service, username, password, ip string) (*models.TokenReviewRequest, error) {
+	return s.Login(context.Background(), provider.LoginRequest{
+		Username: username,
+		Password: password,
+		Metadata: provider.RequestMetadata{ClientIP: ip},
+	})
+}
+
+func TestLockoutService(t *testing.T) {
+	backend := &passwordService{}
+	s := NewLockoutService(LockoutConfig{UserFailures: 2, ClientIPFailures: 10, BaseDelay: time.Second, Duration: 4 * time.Second}, backend).(*lockoutService)
+	now := time.Unix(1500000000, 0)
+	s.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := login(s, "foo", "baz", "10.0.0.1"); err != nil {
+			t.Fatalf("expected failures within the limit to be answered, got %v", err)
+		}
+	}
+
+	// every failure beyond the limit doubles the delay up to the lockout duration
+	for _, expected := range []int{1, 2, 4, 4} {
+		if _, err := login(s, "foo", "baz", "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+		_, err := login(s, "foo", "bar", "10.0.0.2")
+		if !errors.IsTooManyRequests(err) || errors.RetryAfterSeconds(err) != expected {
+			t.Fatalf("expected logins to be locked for %ds, got %v", expected, err)
+		}
+		now = now.Add(time.Duration(expected) * time.Second)
+	}
+	if backend.logins != 6 {
+		t.Errorf("expected locked logins not to reach the service, got %d logins", backend.logins)
+	}
+
+	// other users are not affected, successful logins reset the username
+	if trr, err := login(s, "bar", "bar", "10.0.0.1"); err != nil || !trr.Status.Authenticated {
+		t.Fatalf("expected login of other user to succeed, got %v", err)
+	}
+	if trr, err := login(s, "foo", "bar", "10.0.0.2"); err != nil || !trr.Status.Authenticated {
+		t.Fatalf("expected login after the lockout to succeed, got %v", err)
+	}
+	if _, err := login(s, "foo", "baz", "10.0.0.2"); err != nil {
+		t.Fatalf("expected failures to be reset after a successful login, got %v", err)
+	}
+}
+
+func TestLockoutServiceErrors(t *testing.T) {
+	tests := []struct {
+		err     error
+		counted bool
+	}{
+		{err: errors.NewUnauthorized("account expired"), counted: true},
+		{err: errors.NewForbidden("account disabled"), counted: true},
+		{err: errors.NewServiceUnavailable(fmt.Errorf("ldap down"))},
+		{err: errors.NewInternalError(fmt.Errorf("query failed"))},
+	}
+
+	for _, tt := range tests {
+		backend := &passwordService{err: tt.err}
+		s := NewLockoutService(LockoutConfig{UserFailures: 1, BaseDelay: time.Second}, backend)
+
+		for i := 0; i < 2; i++ {
+			if _, err := login(s, "foo", "baz", ""); errors.ReasonForError(err) != errors.ReasonForError(tt.err) {
+				t.Fatalf("%v: expected the error of the provider, got %v", tt.err, err)
+			}
+		}
+
+		_, err := login(s, "foo", "baz", "")
+		if locked := errors.IsTooManyRequests(err); locked != tt.counted {
+			t.Errorf("%v: expected logins to be locked %v, got %v", tt.err, tt.counted, err)
+		}
+	}
+}
+
+func TestLockoutServiceClientIP(t *testing.T) {
+	backend := &passwordService{}
+	s := NewLockoutService(LockoutConfig{ClientIPFailures: 3, Window: time.Minute}, backend).(*lockoutService)
+	now := time.Unix(1500000000, 0)
+	s.now = func() time.Time { return now }
+
+	// guessing passwords of different users from one client IP
+	for _, username := range []string{"a", "b", "c", "d"} {
+		now = now.Add(10 * time.Second)
+		if _, err := login(s, username, "baz", "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := login(s, "e", "bar", "10.0.0.1"); !errors.IsTooManyRequests(err) {
+		t.Errorf("expected client IP to be locked, got %v", err)
+	}
+	if _, err := login(s, "e", "bar", "10.0.0.2"); err != nil {
+		t.Errorf("expected other client IPs not to be locked, got %v", err)
+	}
+
+	// failures leave the sliding window
+	now = now.Add(time.Minute)
+	if _, err := login(s, "e", "baz", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := login(s, "e", "bar", "10.0.0.1"); err != nil {
+		t.Errorf("expected old failures to leave the window, got %v", err)
+	}
+}
+
+func TestMemoryLockoutStore(t *testing.T) {
+	s := NewMemoryLockoutStore()
+	now := time.Unix(1500000000, 0)
+
+	for i, expected := range []int{1, 2, 2} {
+		failures, err := s.AddFailure("foo", now.Add(time.Duration(i)*time.Second), 2*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if failures != expected {
+			t.Errorf("expected %d failures in the window, got %d", expected, failures)
+		}
+	}
+
+	if err := s.Lock("foo", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if until, _ := s.LockedUntil("foo"); !until.Equal(now.Add(time.Hour)) {
+		t.Errorf("expected foo to be locked, got %v", until)
+	}
+
+	// idle keys are purged
+	if _, err := s.AddFailure("bar", now.Add(2*time.Hour), time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.keys["foo"]; ok {
+		t.Error("expected idle key to be purged")
+	}
+
+	if err := s.Reset("bar"); err != nil {
+		t.Fatal(err)
+	}
+	if until, _ := s.LockedUntil("bar"); !until.IsZero() {
+		t.Errorf("expected bar not to be locked, got %v", until)
+	}
+}
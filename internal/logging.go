@@ -17,7 +17,9 @@
 package internal
 
 import (
+	"context"
 	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"time"
@@ -32,13 +34,15 @@ func NewLoggingService(logger log.Logger, s Service) Service {
 	return &loggingService{logger: logger, service: s}
 }
 
-func (s *loggingService) Login(username, password string) (*models.TokenReviewRequest, error) {
+func (s *loggingService) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
 	start := time.Now()
 
-	tkn, err := s.service.Login(username, password)
+	tkn, err := s.service.Login(ctx, req)
 
 	logger := log.With(s.logger,
 		"method", "Login",
+		"request_id", req.Metadata.RequestID,
+		"client_ip", req.Metadata.ClientIP,
 		"duration", time.Since(start),
 	)
 
@@ -51,13 +55,15 @@ func (s *loggingService) Login(username, password string) (*models.TokenReviewRe
 	return tkn, err
 }
 
-func (s *loggingService) Authenticate(bearerToken string) (*models.TokenReviewRequest, error) {
+func (s *loggingService) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
 	start := time.Now()
 
-	trr, err := s.service.Authenticate(bearerToken)
+	trr, err := s.service.Authenticate(ctx, req)
 
 	logger := log.With(s.logger,
 		"method", "Authenticate",
+		"request_id", req.Metadata.RequestID,
+		"client_ip", req.Metadata.ClientIP,
 		"duration", time.Since(start),
 	)
 
@@ -69,3 +75,33 @@ func (s *loggingService) Authenticate(bearerToken string) (*models.TokenReviewRe
 
 	return trr, err
 }
+
+type loggingAuthorizationService struct {
+	logger  log.Logger
+	service AuthorizationService
+}
+
+func NewLoggingAuthorizationService(logger log.Logger, s AuthorizationService) AuthorizationService {
+	return &loggingAuthorizationService{logger: logger, service: s}
+}
+
+func (s *loggingAuthorizationService) Authorize(ctx context.Context, req provider.AuthorizeRequest) (*models.SubjectAccessReviewStatus, error) {
+	start := time.Now()
+
+	status, err := s.service.Authorize(ctx, req)
+
+	logger := log.With(s.logger,
+		"method", "Authorize",
+		"request_id", req.Metadata.RequestID,
+		"client_ip", req.Metadata.ClientIP,
+		"duration", time.Since(start),
+	)
+
+	if err != nil {
+		level.Warn(logger).Log("msg", "failed to authorize user", "err", err)
+	} else if status != nil {
+		level.Debug(logger).Log("allowed", status.Allowed, "denied", status.Denied)
+	}
+
+	return status, err
+}
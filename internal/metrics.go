@@ -17,7 +17,10 @@
 package internal
 
 import (
+	"context"
+	"github.com/cbrgm/authproxy/api/errors"
 	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
 	"github.com/go-kit/kit/metrics"
 )
 
@@ -30,23 +33,59 @@ func NewMetricsService(loginAttempts metrics.Counter, service Service) Service {
 	// Initialize counters with 0
 	loginAttempts.With("status", "failure").Add(0)
 	loginAttempts.With("status", "success").Add(0)
+	loginAttempts.With("status", "locked").Add(0)
 
 	return &metricsService{loginAttempts: loginAttempts, service: service}
 }
 
-func (s *metricsService) Login(username, password string) (*models.TokenReviewRequest, error) {
-	trr, err := s.service.Login(username, password)
+func (s *metricsService) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	trr, err := s.service.Login(ctx, req)
 
-	if err != nil || !trr.Status.Authenticated {
+	switch {
+	case errors.IsTooManyRequests(err):
+		s.loginAttempts.With("status", "locked").Add(1)
+	case err != nil || !trr.Status.Authenticated:
 		s.loginAttempts.With("status", "failure").Add(1)
-	} else {
+	default:
 		s.loginAttempts.With("status", "success").Add(1)
 	}
 
 	return trr, err
 }
 
-func (s *metricsService) Authenticate(bearerToken string) (*models.TokenReviewRequest, error) {
+func (s *metricsService) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
 	// Don't do anything here
-	return s.service.Authenticate(bearerToken)
+	return s.service.Authenticate(ctx, req)
+}
+
+type metricsAuthorizationService struct {
+	decisions metrics.Counter
+	service   AuthorizationService
+}
+
+func NewMetricsAuthorizationService(decisions metrics.Counter, service AuthorizationService) AuthorizationService {
+	// Initialize counters with 0
+	decisions.With("decision", "allowed").Add(0)
+	decisions.With("decision", "denied").Add(0)
+	decisions.With("decision", "no_opinion").Add(0)
+	decisions.With("decision", "error").Add(0)
+
+	return &metricsAuthorizationService{decisions: decisions, service: service}
+}
+
+func (s *metricsAuthorizationService) Authorize(ctx context.Context, req provider.AuthorizeRequest) (*models.SubjectAccessReviewStatus, error) {
+	status, err := s.service.Authorize(ctx, req)
+
+	switch {
+	case err != nil || status == nil:
+		s.decisions.With("decision", "error").Add(1)
+	case status.Allowed:
+		s.decisions.With("decision", "allowed").Add(1)
+	case status.Denied:
+		s.decisions.With("decision", "denied").Add(1)
+	default:
+		s.decisions.With("decision", "no_opinion").Add(1)
+	}
+
+	return status, err
 }
@@ -17,34 +17,35 @@
 package internal
 
 import (
+	"context"
 	"github.com/cbrgm/authproxy/api/v1/models"
 	"github.com/cbrgm/authproxy/provider"
 )
 
 // Service represents the middleware used by authproxy
 type Service interface {
-	Login(username, password string) (*models.TokenReviewRequest, error)
-	Authenticate(bearerToken string) (*models.TokenReviewRequest, error)
+	Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error)
+	Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error)
 }
 
 // service represents the middleware implementation
 type service struct {
-	provider provider.Provider
+	provider provider.ContextProvider
 }
 
 // NewService returns a new middleware service configured with the given provider
-func NewService(prv *provider.Provider) *service {
+func NewService(prv provider.ContextProvider) *service {
 	return &service{
-		provider: *prv,
+		provider: prv,
 	}
 }
 
 // Login wraps the provider specific login implementation
-func (s *service) Login(username, password string) (*models.TokenReviewRequest, error) {
-	return s.provider.Login(username, password)
+func (s *service) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	return s.provider.Login(ctx, req)
 }
 
 // Authenticate wraps the provider specific authentication implementation
-func (s *service) Authenticate(bearerToken string) (*models.TokenReviewRequest, error) {
-	return s.provider.Authenticate(bearerToken)
+func (s *service) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	return s.provider.Authenticate(ctx, req)
 }
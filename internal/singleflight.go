@@ -0,0 +1,155 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"sync"
+	"time"
+)
+
+type singleflightService struct {
+	service Service
+
+	mu    sync.Mutex
+	calls map[[sha256.Size]byte]*flight
+}
+
+// flight is an Authenticate call shared by all concurrent callers with the same token, audiences and client
+type flight struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+
+	trr *models.TokenReviewRequest
+	err error
+}
+
+// NewSingleflightService returns a service collapsing concurrent Authenticate calls for the same token,
+// audiences and client into one call of the wrapped service. Calls are only collapsed if their client ip,
+// user agent and peer certificates match, the shared call carries the request id of the caller starting it.
+// Every caller waits only until its own context is done; the shared call is canceled once all callers gave up.
+// Logins are never collapsed.
+func NewSingleflightService(service Service) Service {
+	return &singleflightService{
+		service: service,
+		calls:   map[[sha256.Size]byte]*flight{},
+	}
+}
+
+func (s *singleflightService) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	return s.service.Login(ctx, req)
+}
+
+func (s *singleflightService) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	key := flightKey(req)
+
+	s.mu.Lock()
+	f, ok := s.calls[key]
+	if !ok {
+		// the shared call must not be canceled with the context of the caller starting it
+		callCtx, cancel := context.WithCancel(detach(ctx))
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		s.calls[key] = f
+		go s.do(callCtx, key, f, req)
+	}
+	f.waiters++
+	s.mu.Unlock()
+
+	select {
+	case <-f.done:
+		if f.err != nil || f.trr == nil || f.trr.Status == nil {
+			return f.trr, f.err
+		}
+		// every caller gets its own copy as later services may modify it
+		return copyTokenReview(f.trr), nil
+	case <-ctx.Done():
+		s.leave(key, f)
+		return nil, ctx.Err()
+	}
+}
+
+// do runs the shared call and publishes its result to the waiting callers
+func (s *singleflightService) do(ctx context.Context, key [sha256.Size]byte, f *flight, req provider.AuthenticateRequest) {
+	f.trr, f.err = s.service.Authenticate(ctx, req)
+
+	s.mu.Lock()
+	if s.calls[key] == f {
+		delete(s.calls, key)
+	}
+	s.mu.Unlock()
+
+	close(f.done)
+	f.cancel()
+}
+
+// leave removes a caller giving up on a call, the call is canceled and forgotten if it was the last one
+func (s *singleflightService) leave(key [sha256.Size]byte, f *flight) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f.waiters--
+	if f.waiters > 0 {
+		return
+	}
+	if s.calls[key] == f {
+		delete(s.calls, key)
+	}
+	f.cancel()
+}
+
+// flightKey returns the hash of the cache key of req and the metadata of its client the provider may depend on
+func flightKey(req provider.AuthenticateRequest) [sha256.Size]byte {
+	base := cacheKey(req)
+	h := sha256.New()
+	h.Write(base[:])
+
+	fields := []string{req.Metadata.ClientIP, req.Metadata.UserAgent}
+	for _, cert := range req.Metadata.PeerCertificates {
+		fields = append(fields, string(cert.Raw))
+	}
+	// every field is prefixed with its length so different metadata never hashes the same
+	for _, field := range fields {
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(len(field)))
+		h.Write(size[:])
+		h.Write([]byte(field))
+	}
+
+	var key [sha256.Size]byte
+	copy(key[:], h.Sum(nil))
+	return key
+}
+
+// detachedContext carries the values of its parent but is never canceled with it
+type detachedContext struct {
+	parent context.Context
+}
+
+// detach returns a context with the values of ctx which is not canceled when ctx is
+func detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+func (c detachedContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (c detachedContext) Done() <-chan struct{}             { return nil }
+func (c detachedContext) Err() error                        { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
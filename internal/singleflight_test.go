@@ -0,0 +1,226 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package internal
+
+import (
+	"context"
+	"crypto/x509"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingService authenticates every token after release is closed or its context is done
+type blockingService struct {
+	calls    int32
+	release  chan struct{}
+	canceled chan struct{}
+}
+
+func (s *blockingService) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	return provider.Unauthenticated(), nil
+}
+
+func (s *blockingService) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	atomic.AddInt32(&s.calls, 1)
+	select {
+	case <-s.release:
+		return &models.TokenReviewRequest{
+			Status: &models.TokenReviewStatus{
+				Authenticated: true,
+				User:          &models.UserInfo{Username: req.Token, Groups: []string{"developers"}},
+			},
+		}, nil
+	case <-ctx.Done():
+		close(s.canceled)
+		return nil, ctx.Err()
+	}
+}
+
+func TestSingleflightService(t *testing.T) {
+	backend := &blockingService{release: make(chan struct{}), canceled: make(chan struct{})}
+	s := NewSingleflightService(backend).(*singleflightService)
+
+	var wg sync.WaitGroup
+	results := make(chan *models.TokenReviewRequest, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			trr, err := s.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "foo"})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			results <- trr
+		}()
+	}
+
+	// callers give up after their own deadline while the shared call is still running
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	waitForWaiters(t, s, 10)
+	if _, err := s.Authenticate(ctx, provider.AuthenticateRequest{Token: "foo"}); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline to be exceeded, got %v", err)
+	}
+
+	close(backend.release)
+	wg.Wait()
+	close(results)
+
+	if calls := atomic.LoadInt32(&backend.calls); calls != 1 {
+		t.Errorf("expected 1 call to the service, got %d", calls)
+	}
+
+	var previous *models.TokenReviewRequest
+	for trr := range results {
+		if !trr.Status.Authenticated || trr.Status.User.Username != "foo" {
+			t.Errorf("expected token to authenticate foo, got %+v", trr.Status)
+		}
+		if trr == previous {
+			t.Error("expected every caller to get its own copy")
+		}
+		previous = trr
+	}
+
+	// later calls aren't collapsed with finished ones
+	if _, err := s.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	if calls := atomic.LoadInt32(&backend.calls); calls != 2 {
+		t.Errorf("expected 2 calls to the service, got %d", calls)
+	}
+}
+
+func TestSingleflightServiceMetadata(t *testing.T) {
+	backend := &blockingService{release: make(chan struct{}), canceled: make(chan struct{})}
+	s := NewSingleflightService(backend).(*singleflightService)
+
+	clients := []provider.RequestMetadata{
+		{RequestID: "1", ClientIP: "10.0.0.1"},
+		{RequestID: "2", ClientIP: "10.0.0.1"},
+		{RequestID: "3", ClientIP: "10.0.0.2"},
+		{RequestID: "4", ClientIP: "10.0.0.1", UserAgent: "kubectl"},
+		{RequestID: "5", ClientIP: "10.0.0.1", PeerCertificates: []*x509.Certificate{{Raw: []byte("cert")}}},
+	}
+
+	var wg sync.WaitGroup
+	for _, md := range clients {
+		wg.Add(1)
+		go func(md provider.RequestMetadata) {
+			defer wg.Done()
+			if _, err := s.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "foo", Metadata: md}); err != nil {
+				t.Error(err)
+			}
+		}(md)
+	}
+
+	// only the calls of the same client with different request ids are collapsed
+	waitForWaiters(t, s, len(clients))
+	waitForCalls(t, backend, 4)
+	s.mu.Lock()
+	flights := len(s.calls)
+	s.mu.Unlock()
+	if flights != 4 {
+		t.Errorf("expected 4 shared calls, got %d", flights)
+	}
+
+	close(backend.release)
+	wg.Wait()
+
+	if calls := atomic.LoadInt32(&backend.calls); calls != 4 {
+		t.Errorf("expected 4 calls to the service, got %d", calls)
+	}
+}
+
+func TestSingleflightServiceCancel(t *testing.T) {
+	backend := &blockingService{release: make(chan struct{}), canceled: make(chan struct{})}
+	s := NewSingleflightService(backend).(*singleflightService)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := s.Authenticate(ctx, provider.AuthenticateRequest{Token: "foo"})
+		first <- err
+	}()
+	waitForCalls(t, backend, 1)
+
+	second := make(chan error)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		_, err := s.Authenticate(ctx, provider.AuthenticateRequest{Token: "foo"})
+		second <- err
+	}()
+	waitForWaiters(t, s, 2)
+
+	// the caller starting the shared call leaves without canceling it for the others
+	cancel()
+	if err := <-first; err != context.Canceled {
+		t.Errorf("expected first caller to be canceled, got %v", err)
+	}
+	select {
+	case <-backend.canceled:
+		t.Fatal("expected shared call to keep running for the remaining caller")
+	default:
+	}
+
+	// once every caller gave up the shared call is canceled
+	if err := <-second; err != context.DeadlineExceeded {
+		t.Errorf("expected deadline of second caller to be exceeded, got %v", err)
+	}
+	select {
+	case <-backend.canceled:
+	case <-time.After(time.Second):
+		t.Fatal("expected shared call to be canceled")
+	}
+}
+
+// waitForCalls waits until the service was called n times
+func waitForCalls(t *testing.T, s *blockingService, n int32) {
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&s.calls) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d calls to the service", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitForWaiters waits until n callers wait for the shared call
+func waitForWaiters(t *testing.T, s *singleflightService, n int) {
+	deadline := time.Now().Add(time.Second)
+	for {
+		s.mu.Lock()
+		waiters := 0
+		for _, f := range s.calls {
+			waiters += f.waiters
+		}
+		s.mu.Unlock()
+
+		if waiters >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d callers to wait", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
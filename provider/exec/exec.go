@@ -0,0 +1,363 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package exec implements a provider delegating logins and authentication to an external executable.
+// The executable receives a versioned JSON request on stdin and replies with a TokenReviewRequest in JSON on stdout.
+package exec
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const (
+	// APIVersion is the version of the protocol spoken with executables
+	APIVersion = "exec.authproxy.cbrgm.net/v1"
+	// RequestKind is the kind of requests sent to executables
+	RequestKind = "ExecRequest"
+
+	// OperationLogin asks the executable to check the credentials and issue a token
+	OperationLogin = "login"
+	// OperationAuthenticate asks the executable to authenticate a bearer token
+	OperationAuthenticate = "authenticate"
+
+	// DefaultTimeout is the time an executable has to reply if none is configured
+	DefaultTimeout = 10 * time.Second
+	// DefaultMaxConcurrency is the number of concurrent requests if none is configured
+	DefaultMaxConcurrency = 4
+
+	// maxResponseSize limits the size of replies read from executables
+	maxResponseSize = 1 << 20
+)
+
+// Request is sent to the executable on stdin
+type Request struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	// Operation is either login or authenticate
+	Operation string `json:"operation"`
+	// Username and Password are set for logins
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Token is set for authentication requests
+	Token string `json:"token,omitempty"`
+	// Audiences the token is presented to, executables should only authenticate
+	// the token for these audiences and return the matching ones in the status
+	Audiences []string `json:"audiences,omitempty"`
+	// ClientIP is the address of the client sending the request
+	ClientIP string `json:"clientIP,omitempty"`
+}
+
+// Config represents the exec provider configuration
+type Config struct {
+	// Command is the path of the executable
+	Command string
+	// Args are passed to the executable
+	Args []string
+	// Env is added to the environment of authproxy for the executable, in the form key=value
+	Env []string
+	// Worker keeps executables running and sends them one request per line instead of
+	// starting the executable for every request. Replies have to be written as a single line
+	Worker bool
+	// Timeout is the time the executable has to reply, including waiting for a free slot (default: 10s)
+	Timeout time.Duration
+	// MaxConcurrency limits the number of concurrent requests and the number of workers (default: 4)
+	MaxConcurrency int
+	// Logger receives the stderr output of executables (optional)
+	Logger log.Logger
+}
+
+// Provider implements provider.ContextProvider by running an executable
+type Provider struct {
+	config Config
+	slots  chan struct{}
+
+	mu      sync.Mutex
+	workers []*worker
+	closed  bool
+}
+
+// NewProvider returns a new provider running the executable of the config.
+// Workers are started on demand and stopped by Close.
+func NewProvider(cfg Config) (*Provider, error) {
+	if cfg.Command == "" {
+		return nil, fmt.Errorf("no command configured")
+	}
+	path, err := exec.LookPath(cfg.Command)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Command = path
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.MaxConcurrency <= 0 {
+		cfg.MaxConcurrency = DefaultMaxConcurrency
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.NewNopLogger()
+	}
+
+	return &Provider{
+		config: cfg,
+		slots:  make(chan struct{}, cfg.MaxConcurrency),
+	}, nil
+}
+
+// Login sends the credentials to the executable
+func (p *Provider) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	return p.call(ctx, Request{
+		Operation: OperationLogin,
+		Username:  req.Username,
+		Password:  req.Password,
+		ClientIP:  req.Metadata.ClientIP,
+	})
+}
+
+// Authenticate sends the bearer token to the executable
+func (p *Provider) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	return p.call(ctx, Request{
+		Operation: OperationAuthenticate,
+		Token:     req.Token,
+		Audiences: req.Audiences,
+		ClientIP:  req.Metadata.ClientIP,
+	})
+}
+
+// Close stops the running workers
+func (p *Provider) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, w := range p.workers {
+		w.stop()
+	}
+	p.workers = nil
+	p.closed = true
+}
+
+// call sends the request to the executable once a slot is free and decodes the reply.
+// The executable is killed if it doesn't reply before the timeout or the context is done
+func (p *Provider) call(ctx context.Context, req Request) (*models.TokenReviewRequest, error) {
+	req.APIVersion = APIVersion
+	req.Kind = RequestKind
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	select {
+	case p.slots <- struct{}{}:
+		defer func() { <-p.slots }()
+	case <-ctx.Done():
+		return nil, errors.NewServiceUnavailable(fmt.Errorf("no free exec slot within %s", p.config.Timeout))
+	}
+
+	var reply []byte
+	if p.config.Worker {
+		reply, err = p.callWorker(ctx, body)
+	} else {
+		reply, err = p.run(ctx, body)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var trr models.TokenReviewRequest
+	if err := json.Unmarshal(reply, &trr); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("invalid reply of %s: %v", p.config.Command, err))
+	}
+	if trr.Status == nil {
+		return nil, errors.NewInternalError(fmt.Errorf("reply of %s has no status", p.config.Command))
+	}
+	if trr.APIVersion == "" {
+		trr.APIVersion = "authentication.k8s.io/v1beta1"
+	}
+	if trr.Kind == "" {
+		trr.Kind = "TokenReview"
+	}
+
+	return &trr, nil
+}
+
+// run starts the executable for a single request
+func (p *Provider) run(ctx context.Context, body []byte) ([]byte, error) {
+	cmd := p.command()
+	cmd.Stdin = bytes.NewReader(body)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("failed to start %s: %v", p.config.Command, err))
+	}
+
+	type result struct {
+		reply []byte
+		err   error
+	}
+	results := make(chan result, 1)
+
+	// processes started by the executable may hold on to its stdout and stderr after it exited,
+	// so the pipes are read in the background until the context is done
+	go func() {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.logStderr(stderr, cmd.Process.Pid)
+		}()
+
+		reply, err := ioutil.ReadAll(io.LimitReader(stdout, maxResponseSize))
+		// drain the rest so the executable doesn't block on a full pipe
+		_, _ = io.Copy(ioutil.Discard, stdout)
+		wg.Wait()
+		results <- result{reply: reply, err: err}
+	}()
+
+	var r result
+	select {
+	case r = <-results:
+	case <-ctx.Done():
+		// killing the process group closes the pipes held by processes the executable started
+		kill(cmd)
+		_ = cmd.Wait()
+		return nil, errors.NewServiceUnavailable(fmt.Errorf("%s didn't reply within %s", p.config.Command, p.config.Timeout))
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("%s failed: %v", p.config.Command, err))
+	}
+	if r.err != nil {
+		return nil, errors.NewInternalError(r.err)
+	}
+
+	return r.reply, nil
+}
+
+// callWorker sends the request to an idle worker, starting one if none is idle.
+// Workers failing or timing out are stopped and replaced by the next request
+func (p *Provider) callWorker(ctx context.Context, body []byte) ([]byte, error) {
+	w, err := p.idleWorker()
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := w.call(ctx, body)
+	if err != nil {
+		p.removeWorker(w)
+		w.stop()
+
+		if ctx.Err() != nil {
+			return nil, errors.NewServiceUnavailable(fmt.Errorf("%s didn't reply within %s", p.config.Command, p.config.Timeout))
+		}
+		return nil, errors.NewInternalError(fmt.Errorf("%s worker failed: %v", p.config.Command, err))
+	}
+
+	p.mu.Lock()
+	w.busy = false
+	p.mu.Unlock()
+
+	return reply, nil
+}
+
+// idleWorker returns a worker not serving a request and marks it busy.
+// The number of workers is bounded by the slots, so there is always an idle worker or room for a new one
+func (p *Provider) idleWorker() (*worker, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, errors.NewServiceUnavailable(fmt.Errorf("exec provider is closed"))
+	}
+
+	for _, w := range p.workers {
+		if !w.busy && !w.exited() {
+			w.busy = true
+			return w, nil
+		}
+	}
+
+	// drop workers which exited on their own
+	workers := p.workers[:0]
+	for _, w := range p.workers {
+		if !w.exited() {
+			workers = append(workers, w)
+		}
+	}
+	p.workers = workers
+
+	w, err := p.startWorker()
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("failed to start %s: %v", p.config.Command, err))
+	}
+	w.busy = true
+	p.workers = append(p.workers, w)
+
+	return w, nil
+}
+
+func (p *Provider) removeWorker(w *worker) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := range p.workers {
+		if p.workers[i] == w {
+			p.workers = append(p.workers[:i], p.workers[i+1:]...)
+			return
+		}
+	}
+}
+
+// command returns the command running the executable in its own process group
+func (p *Provider) command() *exec.Cmd {
+	cmd := exec.Command(p.config.Command, p.config.Args...)
+	cmd.Env = append(os.Environ(), p.config.Env...)
+	setProcessGroup(cmd)
+	return cmd
+}
+
+// logStderr logs every line the executable writes to stderr
+func (p *Provider) logStderr(stderr io.Reader, pid int) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		level.Warn(p.config.Logger).Log("msg", "exec plugin stderr", "command", p.config.Command, "pid", pid, "stderr", scanner.Text())
+	}
+}
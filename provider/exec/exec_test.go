@@ -0,0 +1,277 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package exec
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/go-kit/kit/log"
+	"net/http"
+	"os"
+	"os/exec"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pluginEnv makes the test binary act as exec plugin, see TestMain
+const pluginEnv = "AUTHPROXY_TEST_EXEC_PLUGIN"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(pluginEnv) {
+	case "":
+		os.Exit(m.Run())
+	case "garbage":
+		fmt.Println("not json")
+	case "exit":
+		fmt.Fprintln(os.Stderr, "backend unreachable")
+		os.Exit(3)
+	case "orphan":
+		// the child inherits stdout and keeps it open after the plugin exited
+		cmd := exec.Command(os.Args[0])
+		cmd.Env = append(os.Environ(), pluginEnv+"=sleep")
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		_ = cmd.Start()
+	case "sleep":
+		time.Sleep(time.Minute)
+	default:
+		plugin()
+	}
+	os.Exit(0)
+}
+
+// plugin replies to every request line, the token slow delays the reply and the credentials foo/bar and token valid are accepted
+func plugin() {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil || req.APIVersion != APIVersion || req.Kind != RequestKind {
+			fmt.Fprintln(os.Stderr, "invalid request")
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "%s request\n", req.Operation)
+
+		if req.Token == "slow" {
+			time.Sleep(3 * time.Second)
+		}
+
+		status := &models.TokenReviewStatus{Audiences: req.Audiences}
+		if (req.Operation == OperationLogin && req.Username == "foo" && req.Password == "bar") || req.Token == "valid" || req.Token == "slow" {
+			status.Authenticated = true
+			status.User = &models.UserInfo{
+				Username: "foo",
+				Extra:    map[string][]string{"pid": {strconv.Itoa(os.Getpid())}},
+			}
+		}
+
+		json.NewEncoder(os.Stdout).Encode(models.TokenReviewRequest{Status: status})
+	}
+}
+
+func newTestProvider(t *testing.T, mode string, cfg Config) *Provider {
+	cfg.Command = os.Args[0]
+	cfg.Env = []string{pluginEnv + "=" + mode}
+
+	p, err := NewProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func pid(trr *models.TokenReviewRequest) string {
+	extra := map[string][]string{}
+	b, _ := json.Marshal(trr.Status.User.Extra)
+	_ = json.Unmarshal(b, &extra)
+	return extra["pid"][0]
+}
+
+func TestProvider(t *testing.T) {
+	for _, worker := range []bool{false, true} {
+		var stderr bytes.Buffer
+		p := newTestProvider(t, "plugin", Config{
+			Worker: worker,
+			Logger: log.NewLogfmtLogger(log.NewSyncWriter(&stderr)),
+		})
+
+		login, err := p.Login(context.Background(), provider.LoginRequest{Username: "foo", Password: "bar"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !login.Status.Authenticated || login.Status.User.Username != "foo" {
+			t.Errorf("worker %v: expected login to succeed, got %+v", worker, login.Status)
+		}
+		if login.APIVersion != "authentication.k8s.io/v1beta1" || login.Kind != "TokenReview" {
+			t.Errorf("worker %v: expected TokenReview defaults, got %s %s", worker, login.APIVersion, login.Kind)
+		}
+
+		denied, err := p.Login(context.Background(), provider.LoginRequest{Username: "foo", Password: "wrong"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if denied.Status.Authenticated {
+			t.Errorf("worker %v: expected wrong password to be rejected", worker)
+		}
+
+		auth, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "valid"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !auth.Status.Authenticated {
+			t.Errorf("worker %v: expected token to be authenticated", worker)
+		}
+
+		// workers serve all requests in the same process
+		if samePid := pid(login) == pid(auth); samePid != worker {
+			t.Errorf("worker %v: expected same process to be %v", worker, worker)
+		}
+
+		p.Close()
+
+		if !worker && !strings.Contains(stderr.String(), "stderr=\"login request\"") {
+			t.Errorf("expected stderr to be logged, got %s", stderr.String())
+		}
+	}
+}
+
+func TestProviderErrors(t *testing.T) {
+	tests := []struct {
+		mode    string
+		worker  bool
+		token   string
+		timeout time.Duration
+		status  int
+	}{
+		{mode: "garbage", status: http.StatusInternalServerError},
+		{mode: "garbage", worker: true, status: http.StatusInternalServerError},
+		{mode: "exit", status: http.StatusInternalServerError},
+		{mode: "exit", worker: true, status: http.StatusInternalServerError},
+		{mode: "plugin", token: "slow", timeout: time.Second, status: http.StatusServiceUnavailable},
+		{mode: "plugin", worker: true, token: "slow", timeout: time.Second, status: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		p := newTestProvider(t, tt.mode, Config{Worker: tt.worker, Timeout: tt.timeout})
+
+		_, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: tt.token})
+		if status := errors.ReasonForError(err); status != tt.status {
+			t.Errorf("%s (worker %v): expected status %d, got %d (%v)", tt.mode, tt.worker, tt.status, status, err)
+		}
+
+		p.Close()
+	}
+}
+
+func TestOrphanTimeout(t *testing.T) {
+	p := newTestProvider(t, "orphan", Config{Timeout: time.Second})
+	defer p.Close()
+
+	start := time.Now()
+	_, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "valid"})
+	if status := errors.ReasonForError(err); status != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d (%v)", http.StatusServiceUnavailable, status, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected timeout after 1s, took %s", elapsed)
+	}
+}
+
+func TestContext(t *testing.T) {
+	for _, worker := range []bool{false, true} {
+		p := newTestProvider(t, "plugin", Config{Worker: worker})
+
+		auth, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "valid", Audiences: []string{"api"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(auth.Status.Audiences, []string{"api"}) {
+			t.Errorf("worker %v: expected audiences to be sent to the executable, got %v", worker, auth.Status.Audiences)
+		}
+
+		// the executable is killed once the context is done, long before the timeout
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		start := time.Now()
+		if _, err := p.Authenticate(ctx, provider.AuthenticateRequest{Token: "slow"}); err == nil {
+			t.Errorf("worker %v: expected cancelled request to fail", worker)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("worker %v: expected request to be cancelled after 500ms, took %s", worker, elapsed)
+		}
+		cancel()
+
+		p.Close()
+	}
+}
+
+func TestWorkerRestart(t *testing.T) {
+	p := newTestProvider(t, "plugin", Config{Worker: true, Timeout: time.Second})
+	defer p.Close()
+
+	first, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "valid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a worker timing out is replaced by a new one
+	if _, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "slow"}); err == nil {
+		t.Fatal("expected slow request to time out")
+	}
+
+	second, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "valid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid(first) == pid(second) {
+		t.Error("expected timed out worker to be replaced")
+	}
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	p := newTestProvider(t, "plugin", Config{MaxConcurrency: 1, Timeout: 100 * time.Millisecond})
+	defer p.Close()
+
+	// occupy the only slot as a running request would
+	p.slots <- struct{}{}
+
+	if _, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "valid"}); errors.ReasonForError(err) != http.StatusServiceUnavailable {
+		t.Errorf("expected request to fail waiting for a free slot, got %v", err)
+	}
+
+	<-p.slots
+	p.config.Timeout = 5 * time.Second
+
+	if _, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "valid"}); err != nil {
+		t.Errorf("expected request to succeed with a free slot, got %v", err)
+	}
+}
+
+func TestNewProviderInvalidConfig(t *testing.T) {
+	for _, cfg := range []Config{{}, {Command: "/does/not/exist"}} {
+		if _, err := NewProvider(cfg); err == nil {
+			t.Errorf("expected config %+v to be invalid", cfg)
+		}
+	}
+}
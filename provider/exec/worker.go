@@ -0,0 +1,124 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package exec
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os/exec"
+)
+
+// worker is a long-running executable reading one request and writing one reply per line
+type worker struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Scanner
+	done   chan struct{}
+
+	// busy is guarded by the mutex of the provider
+	busy bool
+}
+
+// startWorker starts a new worker, its stderr is logged until it exits
+func (p *Provider) startWorker() (*worker, error) {
+	cmd := p.command()
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 4096), maxResponseSize)
+
+	w := &worker{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: scanner,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		p.logStderr(stderr, cmd.Process.Pid)
+		_ = cmd.Wait()
+		close(w.done)
+	}()
+
+	return w, nil
+}
+
+// call writes the request and waits for the reply line until the context is done
+func (w *worker) call(ctx context.Context, body []byte) ([]byte, error) {
+	type result struct {
+		reply []byte
+		err   error
+	}
+	replies := make(chan result, 1)
+
+	go func() {
+		if _, err := w.stdin.Write(append(body, '\n')); err != nil {
+			replies <- result{err: err}
+			return
+		}
+		if !w.stdout.Scan() {
+			err := w.stdout.Err()
+			if err == nil {
+				err = io.ErrUnexpectedEOF
+			}
+			replies <- result{err: err}
+			return
+		}
+		replies <- result{reply: append([]byte(nil), w.stdout.Bytes()...)}
+	}()
+
+	select {
+	case r := <-replies:
+		return r.reply, r.err
+	case <-ctx.Done():
+		// killing the worker unblocks the goroutine waiting for the reply
+		w.stop()
+		return nil, ctx.Err()
+	}
+}
+
+// exited returns true if the worker process exited
+func (w *worker) exited() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop kills the process group of the worker
+func (w *worker) stop() {
+	_ = w.stdin.Close()
+	kill(w.cmd)
+}
@@ -17,7 +17,9 @@
 package fake
 
 import (
+	"context"
 	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
 )
 
 // FakeProvider represents a fake identity provider
@@ -82,3 +84,18 @@ func (provider *FakeProvider) Authenticate(bearerToken string) (*models.TokenRev
 		},
 	}, nil
 }
+
+// Authorize implements authorization functionalities allowing every action for user foo
+func (provider *FakeProvider) Authorize(ctx context.Context, req provider.AuthorizeRequest) (*models.SubjectAccessReviewStatus, error) {
+	if req.Spec.User == "foo" {
+		return &models.SubjectAccessReviewStatus{
+			Allowed: true,
+			Reason:  "user foo is allowed to do everything",
+		}, nil
+	}
+
+	// Neither allowed nor denied: let other authorizers decide
+	return &models.SubjectAccessReviewStatus{
+		Allowed: false,
+	}, nil
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider/grpc/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strconv"
+)
+
+// retryAfterKey is the trailer carrying the seconds clients should wait after ResourceExhausted errors
+const retryAfterKey = "retry-after"
+
+// toProto converts a TokenReviewRequest to its protobuf representation
+func toProto(trr *models.TokenReviewRequest) *pb.TokenReview {
+	review := &pb.TokenReview{}
+	if trr == nil {
+		return review
+	}
+	if trr.Spec != nil {
+		review.Token = trr.Spec.Token
+	}
+
+	if s := trr.Status; s != nil {
+		review.Status = &pb.TokenReviewStatus{
+			Authenticated: s.Authenticated,
+			Error:         s.Error,
+			Audiences:     s.Audiences,
+			Expiration:    s.Expiration,
+		}
+
+		if u := s.User; u != nil {
+			review.Status.User = &pb.UserInfo{
+				Username: u.Username,
+				Uid:      u.UID,
+				Groups:   u.Groups,
+				Extra:    extraToProto(u.Extra),
+			}
+		}
+	}
+
+	return review
+}
+
+// fromProto converts a protobuf TokenReview to a TokenReviewRequest
+func fromProto(review *pb.TokenReview) *models.TokenReviewRequest {
+	trr := &models.TokenReviewRequest{
+		APIVersion: "authentication.k8s.io/v1beta1",
+		Kind:       "TokenReview",
+		Status:     &models.TokenReviewStatus{},
+	}
+	if review.GetToken() != "" {
+		trr.Spec = &models.TokenReviewSpec{Token: review.GetToken()}
+	}
+
+	if s := review.GetStatus(); s != nil {
+		trr.Status.Authenticated = s.GetAuthenticated()
+		trr.Status.Error = s.GetError()
+		trr.Status.Audiences = s.GetAudiences()
+		trr.Status.Expiration = s.GetExpiration()
+
+		if u := s.GetUser(); u != nil {
+			trr.Status.User = &models.UserInfo{
+				Username: u.GetUsername(),
+				UID:      u.GetUid(),
+				Groups:   u.GetGroups(),
+			}
+			if len(u.GetExtra()) > 0 {
+				extra := make(map[string][]string, len(u.GetExtra()))
+				for key, value := range u.GetExtra() {
+					extra[key] = value.GetValues()
+				}
+				trr.Status.User.Extra = extra
+			}
+		}
+	}
+
+	return trr
+}
+
+// extraToProto converts the extra of a user, the string values of map[string][]string
+// and map[string]interface{} extras are supported
+func extraToProto(extra interface{}) map[string]*pb.ExtraValue {
+	out := map[string]*pb.ExtraValue{}
+
+	switch e := extra.(type) {
+	case map[string][]string:
+		for key, values := range e {
+			out[key] = &pb.ExtraValue{Values: values}
+		}
+	case map[string]interface{}:
+		for key, value := range e {
+			switch v := value.(type) {
+			case []string:
+				out[key] = &pb.ExtraValue{Values: v}
+			case []interface{}:
+				values := make([]string, 0, len(v))
+				for _, item := range v {
+					values = append(values, fmt.Sprint(item))
+				}
+				out[key] = &pb.ExtraValue{Values: values}
+			default:
+				out[key] = &pb.ExtraValue{Values: []string{fmt.Sprint(v)}}
+			}
+		}
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// toStatus converts errors of providers to gRPC status errors
+func toStatus(ctx context.Context, err error) error {
+	message := errors.SafeMessage(err)
+
+	switch errors.ReasonForError(err) {
+	case http.StatusBadRequest:
+		return status.Error(codes.InvalidArgument, message)
+	case http.StatusUnauthorized:
+		return status.Error(codes.Unauthenticated, message)
+	case http.StatusForbidden:
+		return status.Error(codes.PermissionDenied, message)
+	case http.StatusTooManyRequests:
+		if seconds := errors.RetryAfterSeconds(err); seconds > 0 {
+			_ = grpc.SetTrailer(ctx, metadata.Pairs(retryAfterKey, strconv.Itoa(seconds)))
+		}
+		return status.Error(codes.ResourceExhausted, message)
+	case http.StatusServiceUnavailable:
+		return status.Error(codes.Unavailable, message)
+	}
+
+	switch err {
+	case context.DeadlineExceeded:
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case context.Canceled:
+		return status.Error(codes.Canceled, err.Error())
+	}
+
+	return status.Error(codes.Internal, message)
+}
+
+// fromStatus converts gRPC errors returned by servers to the errors of the api
+func fromStatus(err error, trailer metadata.MD) error {
+	s, ok := status.FromError(err)
+	if !ok {
+		return errors.NewInternalError(err)
+	}
+
+	switch s.Code() {
+	case codes.InvalidArgument:
+		return errors.NewBadRequest(s.Message())
+	case codes.Unauthenticated:
+		return errors.NewUnauthorized(s.Message())
+	case codes.PermissionDenied:
+		return errors.NewForbidden(s.Message())
+	case codes.ResourceExhausted:
+		seconds := 0
+		if values := trailer.Get(retryAfterKey); len(values) > 0 {
+			seconds, _ = strconv.Atoi(values[0])
+		}
+		return errors.NewTooManyRequests(s.Message(), seconds)
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return errors.NewServiceUnavailable(err)
+	}
+
+	return errors.NewInternalError(err)
+}
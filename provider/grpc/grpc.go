@@ -0,0 +1,211 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package grpc implements a provider calling a remote identity provider over gRPC,
+// so providers can run in a separate process or sidecar. The protocol is defined in pb/provider.proto,
+// existing providers can be exposed with NewServer.
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/grpc/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the timeout of calls if none is configured
+	DefaultTimeout = 10 * time.Second
+
+	// unixPrefix marks addresses of unix sockets
+	unixPrefix = "unix://"
+	// requestIDKey is the metadata key carrying the request id of the client request
+	requestIDKey = "x-request-id"
+)
+
+// Config represents the gRPC provider configuration
+type Config struct {
+	// Address of the server, host:port or unix:///path/to/socket
+	Address string
+	// CAFile holds the certificates the server certificate is verified with.
+	// TLS is disabled if neither CAFile nor CertFile are set
+	CAFile string
+	// CertFile and KeyFile hold the client certificate for mTLS (optional)
+	CertFile string
+	KeyFile  string
+	// ServerName is verified against the server certificate, defaults to the host of the address
+	ServerName string
+	// Timeout of calls (default: 10s)
+	Timeout time.Duration
+	// DialOptions are added to the options the connection is created with (optional)
+	DialOptions []grpc.DialOption
+}
+
+// Provider implements provider.ContextProvider by calling a remote provider
+type Provider struct {
+	config Config
+	conn   *grpc.ClientConn
+	client pb.ProviderClient
+}
+
+// NewProvider returns a new provider for the server of the config.
+// The connection is established in the background, Close closes it.
+func NewProvider(cfg Config) (*Provider, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("no address configured")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	target := cfg.Address
+	opts := []grpc.DialOption{}
+
+	if strings.HasPrefix(cfg.Address, unixPrefix) {
+		path := strings.TrimPrefix(cfg.Address, unixPrefix)
+		target = "passthrough:///" + path
+		opts = append(opts, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		}))
+	}
+
+	if cfg.CAFile != "" || cfg.CertFile != "" {
+		tlsConfig, err := clientTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+
+	conn, err := grpc.Dial(target, append(opts, cfg.DialOptions...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provider{
+		config: cfg,
+		conn:   conn,
+		client: pb.NewProviderClient(conn),
+	}, nil
+}
+
+// Login sends the credentials to the remote provider
+func (p *Provider) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	ctx, cancel := p.callContext(ctx, req.Metadata)
+	defer cancel()
+
+	var trailer metadata.MD
+	review, err := p.client.Login(ctx, &pb.LoginRequest{Username: req.Username, Password: req.Password}, grpc.Trailer(&trailer))
+	if err != nil {
+		return nil, fromStatus(err, trailer)
+	}
+
+	return fromProto(review), nil
+}
+
+// Authenticate sends the bearer token and audiences to the remote provider
+func (p *Provider) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	ctx, cancel := p.callContext(ctx, req.Metadata)
+	defer cancel()
+
+	var trailer metadata.MD
+	review, err := p.client.Authenticate(ctx, &pb.AuthenticateRequest{Token: req.Token, Audiences: req.Audiences}, grpc.Trailer(&trailer))
+	if err != nil {
+		return nil, fromStatus(err, trailer)
+	}
+
+	return fromProto(review), nil
+}
+
+// callContext returns the context of a call limited by the timeout,
+// the request id of the client request is sent along to correlate the logs of both services
+func (p *Provider) callContext(ctx context.Context, md provider.RequestMetadata) (context.Context, context.CancelFunc) {
+	if md.RequestID != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, requestIDKey, md.RequestID)
+	}
+	return context.WithTimeout(ctx, p.config.Timeout)
+}
+
+// Health returns an error if the remote provider can't serve requests.
+// Providers not implementing the health call are considered healthy
+func (p *Provider) Health(ctx context.Context) error {
+	res, err := p.client.Health(ctx, &pb.HealthRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		return nil
+	}
+	if err != nil {
+		return errors.NewServiceUnavailable(err)
+	}
+	if res.GetStatus() != pb.HealthResponse_SERVING {
+		return errors.NewServiceUnavailable(fmt.Errorf("remote provider is %s", res.GetStatus()))
+	}
+	return nil
+}
+
+// Close closes the connection to the server
+func (p *Provider) Close() error {
+	return p.conn.Close()
+}
+
+// clientTLSConfig returns the tls config verifying the server and presenting the client certificate, if any
+func clientTLSConfig(cfg Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{ServerName: cfg.ServerName}
+
+	if tlsConfig.ServerName == "" && !strings.HasPrefix(cfg.Address, unixPrefix) {
+		host, _, err := net.SplitHostPort(cfg.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address: %v", err)
+		}
+		tlsConfig.ServerName = host
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = roots
+	}
+
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
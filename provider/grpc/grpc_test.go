@@ -0,0 +1,342 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package grpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/fake"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// stub returns a fixed user or error, reports its health and records the last request
+type stub struct {
+	err       error
+	unhealthy bool
+
+	request  provider.AuthenticateRequest
+	deadline bool
+}
+
+func (s *stub) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	return s.Authenticate(ctx, provider.AuthenticateRequest{Token: req.Password, Metadata: req.Metadata})
+}
+
+func (s *stub) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	s.request = req
+	_, s.deadline = ctx.Deadline()
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &models.TokenReviewRequest{
+		Spec: &models.TokenReviewSpec{Token: req.Token},
+		Status: &models.TokenReviewStatus{
+			Authenticated: true,
+			User: &models.UserInfo{
+				Username: "foo",
+				UID:      "1",
+				Groups:   []string{"developers"},
+				Extra:    map[string]interface{}{"scopes": []string{"read", "write"}, "tenant": "acme"},
+			},
+			Audiences:  []string{"api"},
+			Expiration: 1565000000,
+		},
+	}, nil
+}
+
+func (s *stub) Health(ctx context.Context) error {
+	if s.unhealthy {
+		return fmt.Errorf("backend down")
+	}
+	return nil
+}
+
+// serve exposes the provider on a unix socket and returns a client for it
+func serve(t *testing.T, prv provider.ContextProvider, cfg ServerConfig, clientCfg Config) (*Provider, func()) {
+	dir, err := ioutil.TempDir("", "grpc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Address == "" {
+		cfg.Address = "unix://" + filepath.Join(dir, "provider.sock")
+	}
+
+	server, err := NewServer(prv, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve()
+
+	clientCfg.Address = cfg.Address
+	if _, ok := server.Addr().(*net.TCPAddr); ok {
+		clientCfg.Address = server.Addr().String()
+	}
+	clientCfg.Timeout = 5 * time.Second
+
+	client, err := NewProvider(clientCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, func() {
+		client.Close()
+		server.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestProvider(t *testing.T) {
+	client, stop := serve(t, provider.NewContextAdapter(fake.NewFakeProvider()), ServerConfig{}, Config{})
+	defer stop()
+
+	login, err := client.Login(context.Background(), provider.LoginRequest{Username: "foo", Password: "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !login.Status.Authenticated || login.Spec == nil || login.Spec.Token == "" {
+		t.Fatalf("expected login to issue a token, got %+v", login)
+	}
+
+	auth, err := client.Authenticate(context.Background(), provider.AuthenticateRequest{Token: login.Spec.Token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !auth.Status.Authenticated {
+		t.Error("expected token to be authenticated")
+	}
+
+	denied, err := client.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "invalid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if denied.Status.Authenticated {
+		t.Error("expected invalid token to be rejected")
+	}
+
+	if err := client.Health(context.Background()); err != nil {
+		t.Errorf("expected provider without health check to be healthy, got %v", err)
+	}
+}
+
+func TestUserConversion(t *testing.T) {
+	client, stop := serve(t, &stub{}, ServerConfig{}, Config{})
+	defer stop()
+
+	trr, err := client.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &models.TokenReviewStatus{
+		Authenticated: true,
+		User: &models.UserInfo{
+			Username: "foo",
+			UID:      "1",
+			Groups:   []string{"developers"},
+			Extra:    map[string][]string{"scopes": {"read", "write"}, "tenant": {"acme"}},
+		},
+		Audiences:  []string{"api"},
+		Expiration: 1565000000,
+	}
+	if !reflect.DeepEqual(trr.Status, want) {
+		t.Errorf("expected status %+v, got %+v", want, trr.Status)
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	backend := &stub{}
+	client, stop := serve(t, backend, ServerConfig{}, Config{})
+	defer stop()
+
+	_, err := client.Authenticate(context.Background(), provider.AuthenticateRequest{
+		Token:     "token",
+		Audiences: []string{"api"},
+		Metadata:  provider.RequestMetadata{RequestID: "abc"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backend.request.Token != "token" || !reflect.DeepEqual(backend.request.Audiences, []string{"api"}) {
+		t.Errorf("expected token and audiences to be passed to the provider, got %+v", backend.request)
+	}
+	if backend.request.Metadata.RequestID != "abc" {
+		t.Errorf("expected request id to be passed to the provider, got %q", backend.request.Metadata.RequestID)
+	}
+	if !backend.deadline {
+		t.Error("expected the deadline of the client to be passed to the provider")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		err        error
+		status     int
+		retryAfter int
+	}{
+		{err: errors.NewBadRequest("malformed token"), status: http.StatusBadRequest},
+		{err: errors.NewUnauthorized("token expired"), status: http.StatusUnauthorized},
+		{err: errors.NewForbidden("user disabled"), status: http.StatusForbidden},
+		{err: errors.NewTooManyRequests("slow down", 7), status: http.StatusTooManyRequests, retryAfter: 7},
+		{err: errors.NewServiceUnavailable(fmt.Errorf("ldap down")), status: http.StatusServiceUnavailable},
+		{err: fmt.Errorf("secret connection string"), status: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		client, stop := serve(t, &stub{err: tt.err}, ServerConfig{}, Config{})
+
+		_, err := client.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "token"})
+		if status := errors.ReasonForError(err); status != tt.status {
+			t.Errorf("%v: expected status %d, got %d (%v)", tt.err, tt.status, status, err)
+		}
+		if seconds := errors.RetryAfterSeconds(err); seconds != tt.retryAfter {
+			t.Errorf("%v: expected retry after %d, got %d", tt.err, tt.retryAfter, seconds)
+		}
+
+		stop()
+	}
+}
+
+func TestHealth(t *testing.T) {
+	client, stop := serve(t, &stub{unhealthy: true}, ServerConfig{}, Config{})
+	defer stop()
+
+	if err := client.Health(context.Background()); errors.ReasonForError(err) != http.StatusServiceUnavailable {
+		t.Errorf("expected unhealthy provider to be reported, got %v", err)
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grpc-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca, caKey := writeCertificate(t, dir, "ca", nil, nil)
+	writeCertificate(t, dir, "server", ca, caKey)
+	writeCertificate(t, dir, "client", ca, caKey)
+
+	server := ServerConfig{
+		Address:      "127.0.0.1:0",
+		CertFile:     filepath.Join(dir, "server.crt"),
+		KeyFile:      filepath.Join(dir, "server.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+	}
+
+	tests := []struct {
+		name   string
+		client Config
+		err    bool
+	}{
+		{
+			name: "client certificate",
+			client: Config{
+				CAFile:   filepath.Join(dir, "ca.crt"),
+				CertFile: filepath.Join(dir, "client.crt"),
+				KeyFile:  filepath.Join(dir, "client.key"),
+			},
+		},
+		{
+			name:   "no client certificate",
+			client: Config{CAFile: filepath.Join(dir, "ca.crt")},
+			err:    true,
+		},
+		{
+			name:   "plaintext",
+			client: Config{},
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		client, stop := serve(t, &stub{}, server, tt.client)
+		client.config.Timeout = time.Second
+
+		_, err := client.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "token"})
+		if (err != nil) != tt.err {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.err, err)
+		}
+
+		stop()
+	}
+}
+
+// writeCertificate writes name.crt and name.key signed by the parent, a CA is written if parent is nil
+func writeCertificate(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".crt"), certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".key"), keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
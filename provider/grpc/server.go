@@ -0,0 +1,190 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/grpc/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+)
+
+// HealthChecker is implemented by providers able to report their health, see Provider.Health
+type HealthChecker interface {
+	Health(ctx context.Context) error
+}
+
+// ServerConfig represents the configuration of servers exposing a provider
+type ServerConfig struct {
+	// Address to listen on, host:port or unix:///path/to/socket
+	Address string
+	// CertFile and KeyFile hold the server certificate, TLS is disabled if unset
+	CertFile string
+	KeyFile  string
+	// ClientCAFile holds the certificates client certificates are verified with, clients have to present one if set
+	ClientCAFile string
+	// ServerOptions are added to the options the server is created with (optional)
+	ServerOptions []grpc.ServerOption
+}
+
+// Server exposes a provider.ContextProvider over gRPC
+type Server struct {
+	listener net.Listener
+	server   *grpc.Server
+	socket   string
+}
+
+// NewServer listens on the address of the config and returns a server exposing the provider.
+// Calls get the context of the gRPC request, carrying the deadline and the request id of the client.
+// Providers implementing HealthChecker report their health, all others are reported serving
+func NewServer(prv provider.ContextProvider, cfg ServerConfig) (*Server, error) {
+	opts := append([]grpc.ServerOption{}, cfg.ServerOptions...)
+
+	if cfg.CertFile != "" {
+		tlsConfig, err := serverTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	} else if cfg.ClientCAFile != "" {
+		return nil, fmt.Errorf("client certificates require a server certificate")
+	}
+
+	s := &Server{server: grpc.NewServer(opts...)}
+	pb.RegisterProviderServer(s.server, &service{provider: prv})
+
+	var err error
+	if strings.HasPrefix(cfg.Address, unixPrefix) {
+		s.socket = strings.TrimPrefix(cfg.Address, unixPrefix)
+		// remove the socket of a previous server which wasn't stopped
+		if err := os.Remove(s.socket); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		s.listener, err = net.Listen("unix", s.socket)
+	} else {
+		s.listener, err = net.Listen("tcp", cfg.Address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Serve serves requests until Stop is called
+func (s *Server) Serve() error {
+	return s.server.Serve(s.listener)
+}
+
+// Stop stops the server after the running requests finished
+func (s *Server) Stop() {
+	s.server.GracefulStop()
+	if s.socket != "" {
+		_ = os.Remove(s.socket)
+	}
+}
+
+// service implements pb.ProviderServer by calling a provider
+type service struct {
+	provider provider.ContextProvider
+}
+
+func (s *service) Login(ctx context.Context, req *pb.LoginRequest) (*pb.TokenReview, error) {
+	trr, err := s.provider.Login(ctx, provider.LoginRequest{
+		Username: req.GetUsername(),
+		Password: req.GetPassword(),
+		Metadata: requestMetadata(ctx),
+	})
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return toProto(trr), nil
+}
+
+func (s *service) Authenticate(ctx context.Context, req *pb.AuthenticateRequest) (*pb.TokenReview, error) {
+	trr, err := s.provider.Authenticate(ctx, provider.AuthenticateRequest{
+		Token:     req.GetToken(),
+		Audiences: req.GetAudiences(),
+		Metadata:  requestMetadata(ctx),
+	})
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return toProto(trr), nil
+}
+
+func (s *service) Health(ctx context.Context, req *pb.HealthRequest) (*pb.HealthResponse, error) {
+	if checker, ok := s.provider.(HealthChecker); ok {
+		if err := checker.Health(ctx); err != nil {
+			return &pb.HealthResponse{Status: pb.HealthResponse_NOT_SERVING}, nil
+		}
+	}
+	return &pb.HealthResponse{Status: pb.HealthResponse_SERVING}, nil
+}
+
+// requestMetadata returns the metadata of the client request sent along with a gRPC request
+func requestMetadata(ctx context.Context) provider.RequestMetadata {
+	var md provider.RequestMetadata
+	if incoming, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := incoming.Get(requestIDKey); len(values) > 0 {
+			md.RequestID = values[0]
+		}
+	}
+	return md
+}
+
+// serverTLSConfig returns the tls config presenting the server certificate and verifying client certificates, if configured
+func serverTLSConfig(cfg ServerConfig) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %v", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = roots
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package htpasswd
+
+import (
+	"bufio"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// User represents a user with a password hash
+type User struct {
+	Username string `json:"username" yaml:"username"`
+	// UID defaults to the username
+	UID      string `json:"uid" yaml:"uid"`
+	Password string `json:"password" yaml:"password"`
+}
+
+// UserFile represents a YAML user file
+type UserFile struct {
+	Users []User `json:"users" yaml:"users"`
+}
+
+// LoadUsers reads a user file and returns the users by username.
+// Files ending in .yaml, .yml or .json are read as YAML user files, all others as htpasswd files.
+func LoadUsers(path string) (map[string]User, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read user file: %v", err)
+		}
+		return ParseUsers(b)
+	default:
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read htpasswd file: %v", err)
+		}
+		defer f.Close()
+		return ParseHtpasswd(f)
+	}
+}
+
+// ParseHtpasswd parses an Apache htpasswd file with lines in the format user:hash
+func ParseHtpasswd(r io.Reader) (map[string]User, error) {
+	users := map[string]User{}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("htpasswd file line %d: expected user:hash", n)
+		}
+
+		user := User{Username: parts[0], UID: parts[0], Password: parts[1]}
+		if err := add(users, user); err != nil {
+			return nil, fmt.Errorf("htpasswd file line %d: %v", n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse htpasswd file: %v", err)
+	}
+
+	return users, nil
+}
+
+// ParseUsers parses a YAML user file
+func ParseUsers(b []byte) (map[string]User, error) {
+	var file UserFile
+	if err := yaml.UnmarshalStrict(b, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse user file: %v", err)
+	}
+
+	users := map[string]User{}
+	for i, user := range file.Users {
+		if user.Username == "" {
+			return nil, fmt.Errorf("user file user %d: username is empty", i)
+		}
+		if user.UID == "" {
+			user.UID = user.Username
+		}
+		if err := add(users, user); err != nil {
+			return nil, fmt.Errorf("user file user %d: %v", i, err)
+		}
+	}
+
+	return users, nil
+}
+
+func add(users map[string]User, user User) error {
+	if _, exists := users[user.Username]; exists {
+		return fmt.Errorf("duplicate user %q", user.Username)
+	}
+	if err := supported(user.Password); err != nil {
+		return fmt.Errorf("user %q: %v", user.Username, err)
+	}
+	users[user.Username] = user
+	return nil
+}
+
+// LoadGroups reads a group file and returns the sorted groups by username
+func LoadGroups(path string) (map[string][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read group file: %v", err)
+	}
+	defer f.Close()
+
+	return ParseGroups(f)
+}
+
+// ParseGroups parses an Apache htgroup file with lines in the format group: user1 user2
+// and returns the sorted groups by username
+func ParseGroups(r io.Reader) (map[string][]string, error) {
+	groups := map[string][]string{}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		group := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || group == "" {
+			return nil, fmt.Errorf("group file line %d: expected group: user1 user2", n)
+		}
+
+		for _, username := range strings.Fields(parts[1]) {
+			if !contains(groups[username], group) {
+				groups[username] = append(groups[username], group)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse group file: %v", err)
+	}
+
+	for _, g := range groups {
+		sort.Strings(g)
+	}
+
+	return groups, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
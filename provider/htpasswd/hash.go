@@ -0,0 +1,275 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package htpasswd
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"sync"
+)
+
+const (
+	prefixBcryptA  = "$2a$"
+	prefixBcryptB  = "$2b$"
+	prefixBcryptY  = "$2y$"
+	prefixSHA      = "{SHA}"
+	prefixAPR1     = "$apr1$"
+	prefixArgon2id = "$argon2id$"
+	apr1Alphabet   = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// Argon2id parameters used by HashArgon2id
+const (
+	Argon2idTime    = 3
+	Argon2idMemory  = 64 * 1024
+	Argon2idThreads = 4
+	argon2idKeyLen  = 32
+	argon2idSaltLen = 16
+)
+
+// Limits of the argon2id parameters of hashes, hashes exceeding them are rejected
+// so a single stored hash can't stall or exhaust the memory of the process
+const (
+	MaxArgon2idTime    = 16
+	MaxArgon2idMemory  = 256 * 1024
+	MaxArgon2idThreads = 16
+
+	minArgon2idKeyLen  = 16
+	maxArgon2idKeyLen  = 64
+	minArgon2idSaltLen = 8
+	maxArgon2idSaltLen = 64
+)
+
+// supported returns an error if the hash algorithm is not supported or the hash is invalid
+func supported(hash string) error {
+	if strings.HasPrefix(hash, prefixArgon2id) {
+		_, err := parseArgon2id(hash)
+		return err
+	}
+	for _, prefix := range []string{prefixBcryptA, prefixBcryptB, prefixBcryptY, prefixSHA, prefixAPR1, prefixArgon2id} {
+		if strings.HasPrefix(hash, prefix) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported password hash, expected bcrypt, SHA, APR1 or argon2id")
+}
+
+// verify returns true if the password matches the hash
+func verify(hash, password string) bool {
+	switch {
+	case strings.HasPrefix(hash, prefixBcryptA), strings.HasPrefix(hash, prefixBcryptB), strings.HasPrefix(hash, prefixBcryptY):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	case strings.HasPrefix(hash, prefixSHA):
+		sum := sha1.Sum([]byte(password))
+		return equal(hash[len(prefixSHA):], base64.StdEncoding.EncodeToString(sum[:]))
+	case strings.HasPrefix(hash, prefixAPR1):
+		salt := strings.SplitN(hash[len(prefixAPR1):], "$", 2)[0]
+		return equal(hash, apr1(password, salt))
+	case strings.HasPrefix(hash, prefixArgon2id):
+		return verifyArgon2id(hash, password)
+	default:
+		return false
+	}
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+var (
+	dummyOnce sync.Once
+	dummyHash []byte
+)
+
+// verifyDummy spends about as much time as verifying a real bcrypt hash.
+// It is used for unknown users so response times don't reveal which usernames exist.
+func verifyDummy(password string) {
+	dummyOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("authproxy"), bcrypt.DefaultCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+// apr1 returns the Apache MD5 crypt hash of the password, see https://httpd.apache.org/docs/2.4/misc/password_encryptions.html
+func apr1(password, salt string) string {
+	if len(salt) > 8 {
+		salt = salt[:8]
+	}
+	pw := []byte(password)
+
+	alt := md5.New()
+	alt.Write(pw)
+	alt.Write([]byte(salt))
+	alt.Write(pw)
+	altSum := alt.Sum(nil)
+
+	ctx := md5.New()
+	ctx.Write(pw)
+	ctx.Write([]byte(prefixAPR1))
+	ctx.Write([]byte(salt))
+	for i := len(pw); i > 0; i -= 16 {
+		if i > 16 {
+			ctx.Write(altSum)
+		} else {
+			ctx.Write(altSum[:i])
+		}
+	}
+	for i := len(pw); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			ctx.Write([]byte{0})
+		} else {
+			ctx.Write(pw[:1])
+		}
+	}
+	sum := ctx.Sum(nil)
+
+	for i := 0; i < 1000; i++ {
+		round := md5.New()
+		if i&1 != 0 {
+			round.Write(pw)
+		} else {
+			round.Write(sum)
+		}
+		if i%3 != 0 {
+			round.Write([]byte(salt))
+		}
+		if i%7 != 0 {
+			round.Write(pw)
+		}
+		if i&1 != 0 {
+			round.Write(sum)
+		} else {
+			round.Write(pw)
+		}
+		sum = round.Sum(nil)
+	}
+
+	var b strings.Builder
+	b.WriteString(prefixAPR1)
+	b.WriteString(salt)
+	b.WriteByte('$')
+
+	encode := func(v uint, n int) {
+		for ; n > 0; n-- {
+			b.WriteByte(apr1Alphabet[v&0x3f])
+			v >>= 6
+		}
+	}
+	for _, i := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		encode(uint(sum[i[0]])<<16|uint(sum[i[1]])<<8|uint(sum[i[2]]), 4)
+	}
+	encode(uint(sum[11]), 2)
+
+	return b.String()
+}
+
+// argon2idParams is a parsed argon2id hash
+type argon2idParams struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// parseArgon2id parses a hash in the PHC string format: $argon2id$v=19$m=65536,t=3,p=4$salt$key
+// and returns an error if its parameters are out of bounds
+func parseArgon2id(hash string) (*argon2idParams, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return nil, fmt.Errorf("invalid argon2id hash: expected $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<key>")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, fmt.Errorf("invalid argon2id hash: unsupported version %q", parts[2])
+	}
+
+	h := &argon2idParams{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.time, &h.threads); err != nil {
+		return nil, fmt.Errorf("invalid argon2id hash parameters %q: %v", parts[3], err)
+	}
+	switch {
+	case h.time < 1 || h.time > MaxArgon2idTime:
+		return nil, fmt.Errorf("invalid argon2id hash: time must be between 1 and %d", MaxArgon2idTime)
+	case h.threads < 1 || h.threads > MaxArgon2idThreads:
+		return nil, fmt.Errorf("invalid argon2id hash: threads must be between 1 and %d", MaxArgon2idThreads)
+	case h.memory < 8*uint32(h.threads) || h.memory > MaxArgon2idMemory:
+		return nil, fmt.Errorf("invalid argon2id hash: memory must be between 8 KiB per thread and %d KiB", MaxArgon2idMemory)
+	}
+
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil || len(h.salt) < minArgon2idSaltLen || len(h.salt) > maxArgon2idSaltLen {
+		return nil, fmt.Errorf("invalid argon2id hash: salt must be %d to %d bytes of unpadded base64", minArgon2idSaltLen, maxArgon2idSaltLen)
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.key) < minArgon2idKeyLen || len(h.key) > maxArgon2idKeyLen {
+		return nil, fmt.Errorf("invalid argon2id hash: key must be %d to %d bytes of unpadded base64", minArgon2idKeyLen, maxArgon2idKeyLen)
+	}
+
+	return h, nil
+}
+
+// verifyArgon2id verifies an argon2id hash, invalid hashes never match
+func verifyArgon2id(hash, password string) bool {
+	h, err := parseArgon2id(hash)
+	if err != nil {
+		return false
+	}
+
+	derived := argon2.IDKey([]byte(password), h.salt, h.time, h.memory, h.threads, uint32(len(h.key)))
+	return subtle.ConstantTimeCompare(h.key, derived) == 1
+}
+
+// HashArgon2id returns an argon2id hash of the password to be used in YAML user files
+func HashArgon2id(password string) (string, error) {
+	salt := make([]byte, argon2idSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %v", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, Argon2idTime, Argon2idMemory, Argon2idThreads, argon2idKeyLen)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		prefixArgon2id, argon2.Version, Argon2idMemory, Argon2idTime, Argon2idThreads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// CheckHash returns an error if the hash is not a valid bcrypt, SHA, APR1 or argon2id hash.
+// Hashes read from storage other applications write to should be checked before verifying passwords.
+func CheckHash(hash string) error {
+	return supported(hash)
+}
+
+// Verify returns true if the password matches a bcrypt, SHA, APR1 or argon2id hash, invalid hashes never match.
+// It allows other providers to store passwords in the formats supported by htpasswd files.
+func Verify(hash, password string) bool {
+	return verify(hash, password)
+}
+
+// VerifyDummy spends about as much time as Verify for a bcrypt hash, it should be called for unknown users
+func VerifyDummy(password string) {
+	verifyDummy(password)
+}
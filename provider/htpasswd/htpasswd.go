@@ -0,0 +1,193 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package htpasswd implements a provider checking login credentials against an Apache htpasswd file
+// or a YAML user file. Tokens are issued through a pluggable token store after successful logins.
+package htpasswd
+
+import (
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/internal/watch"
+	"github.com/cbrgm/authproxy/provider/tokenstore"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"sync/atomic"
+	"time"
+)
+
+// Config represents the htpasswd provider configuration
+type Config struct {
+	// Path of the htpasswd file, files ending in .yaml, .yml or .json are read as YAML user files
+	Path string
+	// GroupsPath of the group file in htgroup format (optional)
+	GroupsPath string
+	// ReloadInterval is the interval the files are checked for changes, 0 disables reloading
+	ReloadInterval time.Duration
+	// TokenStore issues tokens after successful logins (default: in-memory store)
+	TokenStore tokenstore.Store
+	// TokenTTL is the lifetime of tokens issued by the default in-memory store
+	TokenTTL time.Duration
+	// Logger is used to report failed reloads (optional)
+	Logger log.Logger
+}
+
+// Provider implements provider.Provider using an htpasswd or YAML user file
+type Provider struct {
+	config Config
+	stop   []func()
+
+	// users holds a map[string]User and groups a map[string][]string, both are replaced atomically on reloads
+	users  atomic.Value
+	groups atomic.Value
+}
+
+// NewProvider returns a new provider for the user and group files of the config.
+// The files are reloaded on changes until Close is called.
+func NewProvider(cfg Config) (*Provider, error) {
+	users, err := LoadUsers(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[string][]string{}
+	if cfg.GroupsPath != "" {
+		if groups, err = LoadGroups(cfg.GroupsPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Logger == nil {
+		cfg.Logger = log.NewNopLogger()
+	}
+	if cfg.TokenStore == nil {
+		cfg.TokenStore = tokenstore.NewMemoryStore(cfg.TokenTTL)
+	}
+
+	p := &Provider{config: cfg}
+	p.users.Store(users)
+	p.groups.Store(groups)
+
+	if cfg.ReloadInterval > 0 {
+		p.stop = append(p.stop, watch.Poll(cfg.Path, cfg.ReloadInterval, p.reloadUsers))
+		if cfg.GroupsPath != "" {
+			p.stop = append(p.stop, watch.Poll(cfg.GroupsPath, cfg.ReloadInterval, p.reloadGroups))
+		}
+	}
+
+	return p, nil
+}
+
+// Login checks the credentials against the user file and issues a token on success
+func (p *Provider) Login(username, password string) (*models.TokenReviewRequest, error) {
+	users := p.users.Load().(map[string]User)
+
+	user, ok := users[username]
+	if !ok {
+		verifyDummy(password)
+		return provider.Unauthenticated(), nil
+	}
+	if !verify(user.Password, password) {
+		return provider.Unauthenticated(), nil
+	}
+
+	info := &models.UserInfo{
+		Username: user.Username,
+		UID:      user.UID,
+		Groups:   p.groupsOf(user.Username),
+	}
+
+	token, err := p.config.TokenStore.Issue(info)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenReviewRequest{
+		APIVersion: "authentication.k8s.io/v1beta1",
+		Kind:       "TokenReview",
+		Spec: &models.TokenReviewSpec{
+			Token: token,
+		},
+		Status: &models.TokenReviewStatus{
+			Authenticated: true,
+			User:          info,
+		},
+	}, nil
+}
+
+// Authenticate looks up the bearer token in the token store
+func (p *Provider) Authenticate(bearerToken string) (*models.TokenReviewRequest, error) {
+	info, err := p.config.TokenStore.Lookup(bearerToken)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return provider.Unauthenticated(), nil
+	}
+
+	// users removed from the user file lose access before their tokens expire
+	if _, ok := p.users.Load().(map[string]User)[info.Username]; !ok {
+		return provider.Unauthenticated(), nil
+	}
+
+	// groups are resolved on every request so changes of the group file apply to issued tokens
+	info.Groups = p.groupsOf(info.Username)
+
+	return &models.TokenReviewRequest{
+		APIVersion: "authentication.k8s.io/v1beta1",
+		Kind:       "TokenReview",
+		Status: &models.TokenReviewStatus{
+			Authenticated: true,
+			User:          info,
+		},
+	}, nil
+}
+
+// Close stops reloading the user and group files
+func (p *Provider) Close() {
+	for _, stop := range p.stop {
+		stop()
+	}
+}
+
+func (p *Provider) groupsOf(username string) []string {
+	groups := p.groups.Load().(map[string][]string)
+	return append([]string{}, groups[username]...)
+}
+
+// reloadUsers replaces the users by the current user file, the old users are kept on errors
+func (p *Provider) reloadUsers() {
+	users, err := LoadUsers(p.config.Path)
+	if err != nil {
+		level.Error(p.config.Logger).Log("msg", "failed to reload user file, keeping previous users", "path", p.config.Path, "err", err)
+		return
+	}
+
+	p.users.Store(users)
+	level.Info(p.config.Logger).Log("msg", "reloaded user file", "path", p.config.Path, "users", len(users))
+}
+
+// reloadGroups replaces the groups by the current group file, the old groups are kept on errors
+func (p *Provider) reloadGroups() {
+	groups, err := LoadGroups(p.config.GroupsPath)
+	if err != nil {
+		level.Error(p.config.Logger).Log("msg", "failed to reload group file, keeping previous groups", "path", p.config.GroupsPath, "err", err)
+		return
+	}
+
+	p.groups.Store(groups)
+	level.Info(p.config.Logger).Log("msg", "reloaded group file", "path", p.config.GroupsPath)
+}
@@ -0,0 +1,150 @@
+package htpasswd
+
+import (
+	"golang.org/x/crypto/bcrypt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("bar"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	argon2idHash, err := HashArgon2id("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashes := []string{
+		string(bcryptHash),
+		"$2y$" + string(bcryptHash[4:]),
+		"{SHA}Ys23Ag/5IOWqZCw9QGaVDdHwH00=",
+		"$apr1$abcdefgh$bGLO42WqKpmLoPH9uGWTg1",
+		argon2idHash,
+	}
+	for _, hash := range hashes {
+		if err := supported(hash); err != nil {
+			t.Errorf("expected hash %q to be supported: %v", hash, err)
+		}
+		if !verify(hash, "bar") {
+			t.Errorf("expected password to match hash %q", hash)
+		}
+		if verify(hash, "baz") {
+			t.Errorf("expected wrong password not to match hash %q", hash)
+		}
+	}
+
+	if err := supported("plaintext"); err == nil {
+		t.Error("expected plain text passwords to be unsupported")
+	}
+}
+
+func TestArgon2idBounds(t *testing.T) {
+	const (
+		salt = "c2FsdHNhbHRzYWx0c2FsdA"
+		key  = "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+	)
+	hashes := map[string]string{
+		"zero time":        "$argon2id$v=19$m=65536,t=0,p=4$" + salt + "$" + key,
+		"zero threads":     "$argon2id$v=19$m=65536,t=3,p=0$" + salt + "$" + key,
+		"oversized memory": "$argon2id$v=19$m=4294967295,t=3,p=4$" + salt + "$" + key,
+		"oversized time":   "$argon2id$v=19$m=65536,t=4294967295,p=4$" + salt + "$" + key,
+		"short key":        "$argon2id$v=19$m=65536,t=3,p=4$" + salt + "$a2V5",
+		"empty salt":       "$argon2id$v=19$m=65536,t=3,p=4$$" + key,
+	}
+	for name, hash := range hashes {
+		if err := supported(hash); err == nil {
+			t.Errorf("%s: expected hash %q to be rejected", name, hash)
+		}
+		if verify(hash, "bar") {
+			t.Errorf("%s: expected hash %q not to match", name, hash)
+		}
+	}
+
+	if err := add(map[string]User{}, User{Username: "foo", Password: hashes["zero time"]}); err == nil {
+		t.Error("expected users with invalid hashes to be rejected when loading")
+	}
+}
+
+func TestParseGroups(t *testing.T) {
+	groups, err := ParseGroups(strings.NewReader("# groups\nadmins: foo\ndevelopers: foo  bar\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"foo": {"admins", "developers"},
+		"bar": {"developers"},
+	}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("expected groups %v, got %v", expected, groups)
+	}
+
+	if _, err := ParseGroups(strings.NewReader("developers foo\n")); err == nil {
+		t.Error("expected group file to be invalid")
+	}
+}
+
+func TestProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "htpasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	argon2idHash, err := HashArgon2id("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := filepath.Join(dir, "users.yaml")
+	groups := filepath.Join(dir, "groups")
+	if err := ioutil.WriteFile(users, []byte("users:\n- username: foo\n  uid: \"1\"\n  password: "+argon2idHash+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(groups, []byte("developers: foo\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewProvider(Config{Path: users, GroupsPath: groups})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	for _, credentials := range [][2]string{{"foo", "baz"}, {"unknown", "bar"}} {
+		trr, err := p.Login(credentials[0], credentials[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trr.Status.Authenticated {
+			t.Errorf("expected login of %s to fail", credentials[0])
+		}
+	}
+
+	login, err := p.Login("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !login.Status.Authenticated || login.Spec == nil || login.Spec.Token == "" {
+		t.Fatalf("expected login to issue a token, got %+v", login)
+	}
+
+	trr, err := p.Authenticate(login.Spec.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := trr.Status.User
+	if !trr.Status.Authenticated || user.Username != "foo" || user.UID != "1" || !reflect.DeepEqual(user.Groups, []string{"developers"}) {
+		t.Errorf("unexpected authentication result %+v", trr.Status)
+	}
+
+	if trr, _ := p.Authenticate("invalid"); trr.Status.Authenticated {
+		t.Error("expected unknown token not to be authenticated")
+	}
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package watch notifies providers about changes of files on disk.
+package watch
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// Poll calls onChange whenever the modification time or size of the file at path changes.
+// The file is checked every interval until the returned stop function is called.
+// Symlinks are followed, so atomic replacements of ConfigMap mounted files are detected as well.
+func Poll(path string, interval time.Duration, onChange func()) (stop func()) {
+	done := make(chan struct{})
+	last := stat(path)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				current := stat(path)
+				if current != last {
+					last = current
+					onChange()
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// fileState represents the state of a file used to detect changes
+type fileState struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+func stat(path string) fileState {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{modTime: fi.ModTime(), size: fi.Size(), exists: true}
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package introspection
+
+import (
+	"container/list"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"sync"
+	"time"
+)
+
+// cache holds the users of active tokens by token hash until they expire.
+// The least recently used entries are evicted once maxEntries is reached
+type cache struct {
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type cacheEntry struct {
+	key       string
+	user      models.UserInfo
+	audiences []string
+	exp       int64
+	expires   time.Time
+}
+
+func newCache(maxEntries int, now func() time.Time) *cache {
+	return &cache{
+		maxEntries: maxEntries,
+		now:        now,
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+	}
+}
+
+// get returns the entry of a token hash if it did not expire yet.
+// Entries are never modified, callers have to copy the user before handing it out
+func (c *cache) get(key string) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.remove(elem)
+		return cacheEntry{}, false
+	}
+	c.lru.MoveToFront(elem)
+	return *entry, true
+}
+
+// add caches the entry, evicting the least recently used entries beyond maxEntries
+func (c *cache) add(entry cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[entry.key]; ok {
+		c.remove(elem)
+	}
+	c.entries[entry.key] = c.lru.PushFront(&entry)
+
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove deletes an entry, the caller must hold the lock
+func (c *cache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).key)
+}
@@ -0,0 +1,334 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package introspection implements a provider authenticating opaque OAuth2 tokens
+// through a token introspection endpoint (RFC 7662).
+package introspection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/tokenstore"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultUsernameClaim is the claim used as username if none is configured, sub is used if it is missing
+	DefaultUsernameClaim = "username"
+	// DefaultScopesExtraKey is the extra key the scopes of tokens are mapped to if none is configured
+	DefaultScopesExtraKey = "scopes"
+	// DefaultMaxCacheTTL is the longest time results are cached if none is configured
+	DefaultMaxCacheTTL = 5 * time.Minute
+	// DefaultMaxCacheEntries is the number of cached tokens if no limit is configured
+	DefaultMaxCacheEntries = 10000
+)
+
+// Config represents the introspection provider configuration
+type Config struct {
+	// URL of the introspection endpoint
+	URL string
+	// ClientID and ClientSecret authenticate authproxy at the introspection endpoint
+	ClientID     string
+	ClientSecret string
+	// TokenURL enables the client credentials grant: an access token is requested from the token endpoint
+	// and sent as bearer token. Without it the client credentials are sent with HTTP basic authentication.
+	TokenURL string
+	// Scopes requested with the client credentials grant (optional)
+	Scopes []string
+	// HTTPClient is used for requests (default: http.Client with a 10s timeout)
+	HTTPClient *http.Client
+
+	// Audiences of which at least one must be contained in the aud of tokens (optional)
+	Audiences []string
+	// UsernameClaim is the claim used as username, sub is used if it is missing (default: username)
+	UsernameClaim string
+	// GroupsClaim is the claim holding a string or a list of groups (optional)
+	GroupsClaim string
+	// ScopesExtraKey is the extra key the space separated scope claim is mapped to (default: scopes)
+	ScopesExtraKey string
+	// ExtraClaims maps extra keys to the claims providing their values (optional)
+	ExtraClaims map[string]string
+
+	// MaxCacheTTL limits the time active tokens are cached, they are never cached beyond their exp (default: 5m)
+	MaxCacheTTL time.Duration
+	// MaxCacheEntries limits the number of cached tokens, the least recently used are evicted (default: 10000)
+	MaxCacheEntries int
+	// DisableCache introspects every token on every request
+	DisableCache bool
+
+	// Logger is used to report failed introspection requests (optional)
+	Logger log.Logger
+}
+
+// Response represents the parts of an introspection response used by the provider
+type Response struct {
+	Active   bool   `json:"active"`
+	Subject  string `json:"sub"`
+	Username string `json:"username"`
+	Scope    string `json:"scope"`
+	Expiry   int64  `json:"exp"`
+	// Claims holds all claims of the response
+	Claims map[string]interface{} `json:"-"`
+}
+
+// Provider implements provider.ContextProvider using an OAuth2 token introspection endpoint.
+// Active tokens are cached until their exp, but at most for MaxCacheTTL.
+type Provider struct {
+	config Config
+	client *http.Client
+	cache  *cache
+	now    func() time.Time
+}
+
+// NewProvider returns a new introspection provider for the config
+func NewProvider(cfg Config) (*Provider, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("introspection provider requires an introspection URL")
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.NewNopLogger()
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = DefaultUsernameClaim
+	}
+	if cfg.ScopesExtraKey == "" {
+		cfg.ScopesExtraKey = DefaultScopesExtraKey
+	}
+	if cfg.MaxCacheTTL <= 0 {
+		cfg.MaxCacheTTL = DefaultMaxCacheTTL
+	}
+	if cfg.MaxCacheEntries <= 0 {
+		cfg.MaxCacheEntries = DefaultMaxCacheEntries
+	}
+
+	client := cfg.HTTPClient
+	if cfg.TokenURL != "" {
+		cc := clientcredentials.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			TokenURL:     cfg.TokenURL,
+			Scopes:       cfg.Scopes,
+		}
+		client = cc.Client(context.WithValue(context.Background(), oauth2.HTTPClient, cfg.HTTPClient))
+	}
+
+	p := &Provider{
+		config: cfg,
+		client: client,
+		now:    time.Now,
+	}
+	p.cache = newCache(cfg.MaxCacheEntries, func() time.Time { return p.now() })
+
+	return p, nil
+}
+
+// Login is not supported, tokens are obtained from the authorization server
+func (p *Provider) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	return provider.Unauthenticated(), nil
+}
+
+// Authenticate introspects the token and maps the response to the user
+func (p *Provider) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	bearerToken := req.Token
+	if bearerToken == "" {
+		return provider.Unauthenticated(), nil
+	}
+
+	key := tokenstore.Hash(bearerToken)
+	if !p.config.DisableCache {
+		if entry, ok := p.cache.get(key); ok {
+			return authenticated(entry.user, entry.audiences, entry.exp), nil
+		}
+	}
+
+	resp, err := p.Introspect(ctx, bearerToken)
+	if err != nil {
+		level.Warn(p.config.Logger).Log("msg", "token introspection failed", "err", err)
+		return nil, err
+	}
+
+	now := p.now()
+	if !resp.Active || resp.Expiry != 0 && !now.Before(time.Unix(resp.Expiry, 0)) {
+		return provider.Unauthenticated(), nil
+	}
+	audiences := listClaim(resp.Claims, "aud")
+	if len(p.config.Audiences) > 0 && !containsAny(audiences, p.config.Audiences) {
+		return provider.Unauthenticated(), nil
+	}
+
+	user := p.user(resp)
+	if user.Username == "" {
+		return provider.Unauthenticated(), nil
+	}
+
+	if !p.config.DisableCache {
+		expires := now.Add(p.config.MaxCacheTTL)
+		if resp.Expiry != 0 && time.Unix(resp.Expiry, 0).Before(expires) {
+			expires = time.Unix(resp.Expiry, 0)
+		}
+		p.cache.add(cacheEntry{key: key, user: user, audiences: audiences, exp: resp.Expiry, expires: expires})
+	}
+
+	return authenticated(user, audiences, resp.Expiry), nil
+}
+
+// Introspect sends the token to the introspection endpoint and returns the response
+func (p *Provider) Introspect(ctx context.Context, token string) (*Response, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+
+	req, err := http.NewRequest(http.MethodPost, p.config.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.TokenURL == "" && p.config.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, errors.NewServiceUnavailable(fmt.Errorf("introspection request failed: %v", err))
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode >= 500:
+		return nil, errors.NewServiceUnavailable(fmt.Errorf("introspection endpoint returned %s", res.Status))
+	case res.StatusCode != http.StatusOK:
+		return nil, errors.NewInternalError(fmt.Errorf("introspection endpoint returned %s", res.Status))
+	}
+
+	var claims map[string]interface{}
+	dec := json.NewDecoder(res.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&claims); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("failed to decode introspection response: %v", err))
+	}
+
+	resp := &Response{Claims: claims}
+	resp.Active, _ = claims["active"].(bool)
+	resp.Subject, _ = claims["sub"].(string)
+	resp.Username, _ = claims["username"].(string)
+	resp.Scope, _ = claims["scope"].(string)
+	if exp, ok := claims["exp"].(json.Number); ok {
+		resp.Expiry, _ = exp.Int64()
+	}
+
+	return resp, nil
+}
+
+// user maps an introspection response to the user
+func (p *Provider) user(resp *Response) models.UserInfo {
+	username, _ := resp.Claims[p.config.UsernameClaim].(string)
+	if username == "" {
+		username = resp.Subject
+	}
+
+	user := models.UserInfo{
+		Username: username,
+		UID:      resp.Subject,
+		Groups:   []string{},
+	}
+
+	if p.config.GroupsClaim != "" {
+		user.Groups = append(user.Groups, listClaim(resp.Claims, p.config.GroupsClaim)...)
+	}
+
+	extra := map[string][]string{}
+	if scopes := strings.Fields(resp.Scope); len(scopes) > 0 {
+		extra[p.config.ScopesExtraKey] = scopes
+	}
+	for key, claim := range p.config.ExtraClaims {
+		if values := listClaim(resp.Claims, claim); len(values) > 0 {
+			extra[key] = values
+		}
+	}
+	if len(extra) > 0 {
+		user.Extra = extra
+	}
+
+	return user
+}
+
+// listClaim returns a claim holding a string or a list of values as list of strings
+func listClaim(claims map[string]interface{}, name string) []string {
+	switch v := claims[name].(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, value := range v {
+			values = append(values, fmt.Sprint(value))
+		}
+		return values
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+// containsAny returns true if a contains at least one element of b
+func containsAny(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// authenticated returns an authenticated TokenReview for a copy of the user valid for the audiences until exp,
+// cached users are shared by all requests for the token
+func authenticated(user models.UserInfo, audiences []string, exp int64) *models.TokenReviewRequest {
+	user.Groups = append([]string{}, user.Groups...)
+	if extra, ok := user.Extra.(map[string][]string); ok {
+		copied := make(map[string][]string, len(extra))
+		for key, values := range extra {
+			copied[key] = append([]string{}, values...)
+		}
+		user.Extra = copied
+	}
+
+	return &models.TokenReviewRequest{
+		APIVersion: "authentication.k8s.io/v1beta1",
+		Kind:       "TokenReview",
+		Status: &models.TokenReviewStatus{
+			Authenticated: true,
+			User:          &user,
+			Audiences:     append([]string(nil), audiences...),
+			Expiration:    exp,
+		},
+	}
+}
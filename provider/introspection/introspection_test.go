@@ -0,0 +1,205 @@
+package introspection
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/tokenstore"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newIntrospectionServer(t *testing.T, authorized func(r *http.Request) bool, calls *int32) *httptest.Server {
+	exp := time.Now().Add(time.Hour).Unix()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != "authproxy" || secret != "secret" || r.FormValue("grant_type") != "client_credentials" {
+			http.Error(w, "invalid client", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "access", "token_type": "bearer", "expires_in": 3600})
+	})
+	mux.HandleFunc("/introspect", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+
+		if !authorized(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var resp map[string]interface{}
+		switch r.PostFormValue("token") {
+		case "active":
+			resp = map[string]interface{}{
+				"active":   true,
+				"sub":      "1234",
+				"username": "jane",
+				"scope":    "read write",
+				"exp":      exp,
+				"aud":      []string{"api"},
+				"groups":   []string{"developers"},
+				"tenant":   "acme",
+			}
+		case "slow":
+			<-r.Context().Done()
+			return
+		case "expired":
+			resp = map[string]interface{}{"active": true, "sub": "1234", "exp": time.Now().Add(-time.Minute).Unix()}
+		default:
+			resp = map[string]interface{}{"active": false}
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestAuthenticate(t *testing.T) {
+	var calls int32
+	server := newIntrospectionServer(t, func(r *http.Request) bool {
+		id, secret, ok := r.BasicAuth()
+		return ok && id == "authproxy" && secret == "secret"
+	}, &calls)
+	defer server.Close()
+
+	p, err := NewProvider(Config{
+		URL:          server.URL + "/introspect",
+		ClientID:     "authproxy",
+		ClientSecret: "secret",
+		Audiences:    []string{"api"},
+		GroupsClaim:  "groups",
+		ExtraClaims:  map[string]string{"example.com/tenant": "tenant"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		trr, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "active"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		user := trr.Status.User
+		if !trr.Status.Authenticated || user.Username != "jane" || user.UID != "1234" || !reflect.DeepEqual(user.Groups, []string{"developers"}) {
+			t.Fatalf("unexpected authentication result %+v", trr.Status)
+		}
+		expected := map[string][]string{"scopes": {"read", "write"}, "example.com/tenant": {"acme"}}
+		if !reflect.DeepEqual(user.Extra, expected) {
+			t.Errorf("expected extra %v, got %v", expected, user.Extra)
+		}
+		if !reflect.DeepEqual(trr.Status.Audiences, []string{"api"}) {
+			t.Errorf("expected audiences of the token, got %v", trr.Status.Audiences)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected active token to be cached, introspected %d times", calls)
+	}
+
+	for _, token := range []string{"inactive", "expired"} {
+		if trr, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: token}); err != nil || trr.Status.Authenticated {
+			t.Errorf("expected %s token not to be authenticated, got %v", token, err)
+		}
+	}
+
+	// cached results expire with the token
+	p.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if trr, _ := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "active"}); trr.Status.Authenticated {
+		t.Error("expected token not to be authenticated after its expiry")
+	}
+}
+
+func TestAuthenticateCache(t *testing.T) {
+	var calls int32
+	server := newIntrospectionServer(t, func(r *http.Request) bool { return true }, &calls)
+	defer server.Close()
+
+	p, err := NewProvider(Config{URL: server.URL + "/introspect", GroupsClaim: "groups", MaxCacheEntries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// callers get their own copy of the cached user
+	trr, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "active"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trr.Status.User.Groups[0] = "admins"
+	trr.Status.User.Extra.(map[string][]string)["scopes"][0] = "admin"
+
+	trr, err = p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "active"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trr.Status.User.Groups[0] != "developers" || trr.Status.User.Extra.(map[string][]string)["scopes"][0] != "read" {
+		t.Errorf("expected cached user not to be modified, got %+v", trr.Status.User)
+	}
+
+	// the least recently used token is evicted
+	p.cache.add(cacheEntry{key: "other", expires: time.Now().Add(time.Hour)})
+	if _, ok := p.cache.get(tokenstore.Hash("active")); ok {
+		t.Error("expected least recently used token to be evicted")
+	}
+	if len(p.cache.entries) != 1 {
+		t.Errorf("expected cache to be bounded, got %d entries", len(p.cache.entries))
+	}
+}
+
+func TestAuthenticateClientCredentials(t *testing.T) {
+	var calls int32
+	server := newIntrospectionServer(t, func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer access"
+	}, &calls)
+	defer server.Close()
+
+	p, err := NewProvider(Config{
+		URL:          server.URL + "/introspect",
+		ClientID:     "authproxy",
+		ClientSecret: "secret",
+		TokenURL:     server.URL + "/token",
+		DisableCache: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trr, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "active"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !trr.Status.Authenticated || trr.Status.User.Username != "jane" {
+		t.Errorf("unexpected authentication result %+v", trr.Status)
+	}
+
+	// rejected client credentials are internal errors
+	p, err = NewProvider(Config{URL: server.URL + "/introspect", ClientID: "authproxy", ClientSecret: "wrong"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "active"}); err == nil {
+		t.Error("expected introspection with wrong client credentials to fail")
+	}
+}
+
+func TestAuthenticateContext(t *testing.T) {
+	var calls int32
+	server := newIntrospectionServer(t, func(r *http.Request) bool { return true }, &calls)
+	defer server.Close()
+
+	p, err := NewProvider(Config{URL: server.URL + "/introspect"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := p.Authenticate(ctx, provider.AuthenticateRequest{Token: "slow"}); !errors.IsServiceUnavailable(err) {
+		t.Errorf("expected cancelled introspection to fail with service unavailable, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package jwt
+
+import (
+	"encoding/json"
+	"gopkg.in/square/go-jose.v2"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// JWKSPath is the path the JWKS of an issuer is served on
+const JWKSPath = "/v1/jwks"
+
+// Discovery represents the OpenID provider metadata served on /.well-known/openid-configuration
+type Discovery struct {
+	Issuer            string   `json:"issuer"`
+	JWKSURI           string   `json:"jwks_uri"`
+	ResponseTypes     []string `json:"response_types_supported"`
+	SubjectTypes      []string `json:"subject_types_supported"`
+	SigningAlgorithms []string `json:"id_token_signing_alg_values_supported"`
+	ClaimsSupported   []string `json:"claims_supported"`
+}
+
+// Discoverable returns true if the issuer is an absolute https URL, as OpenID discovery requires.
+// Other issuers have no URL the JWKS URI could be derived from, so they publish no discovery document
+func (i *Issuer) Discoverable() bool {
+	u, err := url.Parse(i.config.Issuer)
+	return err == nil && u.Scheme == "https" && u.Host != ""
+}
+
+// Discovery returns the OpenID provider metadata of the issuer, the JWKS URI is relative to the issuer URL.
+// It must only be published if the issuer is Discoverable
+func (i *Issuer) Discovery() Discovery {
+	algorithms := []string{}
+	for _, key := range i.config.Keys.VerificationKeys() {
+		if !contains(algorithms, key.Algorithm) {
+			algorithms = append(algorithms, key.Algorithm)
+		}
+	}
+
+	return Discovery{
+		Issuer:            i.config.Issuer,
+		JWKSURI:           strings.TrimSuffix(i.config.Issuer, "/") + JWKSPath,
+		ResponseTypes:     []string{"id_token"},
+		SubjectTypes:      []string{"public"},
+		SigningAlgorithms: algorithms,
+		ClaimsSupported:   []string{"iss", "sub", "aud", "exp", "iat", "nbf", "jti", "username", "uid", "groups", "extra"},
+	}
+}
+
+// JWKS returns the public keys tokens of the issuer are verified with
+func (i *Issuer) JWKS() jose.JSONWebKeySet {
+	set := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	for _, key := range i.config.Keys.VerificationKeys() {
+		set.Keys = append(set.Keys, jose.JSONWebKey{
+			Key:       key.Public(),
+			KeyID:     key.ID,
+			Algorithm: key.Algorithm,
+			Use:       "sig",
+		})
+	}
+	return set
+}
+
+// DiscoveryHandler returns a handler serving the OpenID provider metadata of the issuer.
+// The metadata never depends on the request, issuers which are not Discoverable answer 404
+func (i *Issuer) DiscoveryHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !i.Discoverable() {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, i.Discovery())
+	})
+}
+
+// JWKSHandler returns a handler serving the JWKS of the issuer
+func (i *Issuer) JWKSHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, i.JWKS())
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// clients may cache the keys for a short time
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	w.Write(b)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/fake"
+	"gopkg.in/square/go-jose.v2"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestDiscoveryHandlers(t *testing.T) {
+	key := generateKeys(t)[2]
+
+	issuer, err := NewIssuer(Config{Provider: provider.NewContextAdapter(fake.NewFakeProvider()), Keys: NewStaticKeySet(key), Issuer: "https://authproxy.example.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	issuer.DiscoveryHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/.well-known/openid-configuration", nil))
+
+	var discovery Discovery
+	if err := json.Unmarshal(rec.Body.Bytes(), &discovery); err != nil {
+		t.Fatal(err)
+	}
+	if discovery.Issuer != "https://authproxy.example.com/" || discovery.JWKSURI != "https://authproxy.example.com/v1/jwks" {
+		t.Errorf("unexpected discovery %+v", discovery)
+	}
+	if !reflect.DeepEqual(discovery.SigningAlgorithms, []string{EdDSA}) {
+		t.Errorf("expected signing algorithms [EdDSA], got %v", discovery.SigningAlgorithms)
+	}
+
+	rec = httptest.NewRecorder()
+	issuer.JWKSHandler().ServeHTTP(rec, httptest.NewRequest("GET", JWKSPath, nil))
+
+	var jwks jose.JSONWebKeySet
+	if err := json.Unmarshal(rec.Body.Bytes(), &jwks); err != nil {
+		t.Fatal(err)
+	}
+	keys := jwks.Key(key.ID)
+	if len(keys) != 1 || !keys[0].IsPublic() || keys[0].Algorithm != EdDSA {
+		t.Fatalf("expected public key %s in JWKS, got %+v", key.ID, jwks)
+	}
+
+	// tokens of the issuer can be verified with the published key
+	login, err := issuer.Login(context.Background(), provider.LoginRequest{Username: "foo", Password: "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, err := jose.ParseSigned(login.Spec.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tok.Verify(keys[0]); err != nil {
+		t.Errorf("failed to verify token with published key: %v", err)
+	}
+}
+
+func TestDiscoveryRequiresIssuerURL(t *testing.T) {
+	key := generateKeys(t)[2]
+
+	for _, iss := range []string{"authproxy", "http://authproxy.example.com", "https://"} {
+		issuer, err := NewIssuer(Config{Provider: provider.NewContextAdapter(fake.NewFakeProvider()), Keys: NewStaticKeySet(key), Issuer: iss})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if issuer.Discoverable() {
+			t.Errorf("%s: expected issuer not to be discoverable", iss)
+		}
+
+		// the Host header of requests must never end up in the discovery document
+		req := httptest.NewRequest("GET", "/.well-known/openid-configuration", nil)
+		req.Host = "attacker.example.com"
+		rec := httptest.NewRecorder()
+		issuer.DiscoveryHandler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected no discovery document, got %d: %s", iss, rec.Code, rec.Body)
+		}
+	}
+}
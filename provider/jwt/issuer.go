@@ -0,0 +1,251 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package jwt implements a provider issuing signed JWTs after successful logins of a backing provider.
+// Issued tokens are validated locally without asking the backing provider again.
+package jwt
+
+import (
+	"context"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/tokenstore"
+	"gopkg.in/square/go-jose.v2"
+	josejwt "gopkg.in/square/go-jose.v2/jwt"
+	"time"
+)
+
+const (
+	// DefaultLifetime is the lifetime of issued tokens if none is configured
+	DefaultLifetime = time.Hour
+	// DefaultClockSkew is the clock skew tolerated when validating tokens if none is configured
+	DefaultClockSkew = time.Minute
+)
+
+// Config represents the token issuer configuration
+type Config struct {
+	// Provider checks the credentials of logins, providers implementing only
+	// provider.Provider can be used with provider.NewContextAdapter
+	Provider provider.ContextProvider
+	// Keys sign and verify the tokens
+	Keys KeySet
+	// Issuer is the iss claim of issued tokens, tokens of other issuers are rejected
+	Issuer string
+	// Audiences is the aud claim of issued tokens (optional)
+	Audiences []string
+	// Lifetime of issued tokens (default: 1h)
+	Lifetime time.Duration
+	// ClockSkew tolerated when validating the expiry of tokens (default: 1m)
+	ClockSkew time.Duration
+}
+
+// Claims represents the claims of issued tokens
+type Claims struct {
+	josejwt.Claims
+	Username string      `json:"username"`
+	UID      string      `json:"uid,omitempty"`
+	Groups   []string    `json:"groups,omitempty"`
+	Extra    interface{} `json:"extra,omitempty"`
+}
+
+// Issuer implements provider.ContextProvider issuing signed JWTs for users authenticated by the backing provider
+type Issuer struct {
+	config Config
+	now    func() time.Time
+}
+
+// NewIssuer returns a new token issuer for the config
+func NewIssuer(cfg Config) (*Issuer, error) {
+	if cfg.Provider == nil {
+		return nil, fmt.Errorf("token issuer requires a provider")
+	}
+	if cfg.Keys == nil {
+		return nil, fmt.Errorf("token issuer requires signing keys")
+	}
+	if cfg.Lifetime <= 0 {
+		cfg.Lifetime = DefaultLifetime
+	}
+	if cfg.ClockSkew <= 0 {
+		cfg.ClockSkew = DefaultClockSkew
+	}
+
+	return &Issuer{
+		config: cfg,
+		now:    time.Now,
+	}, nil
+}
+
+// Login checks the credentials with the backing provider and issues a signed token on success
+func (i *Issuer) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	trr, err := i.config.Provider.Login(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if trr == nil || trr.Status == nil || !trr.Status.Authenticated {
+		return provider.Unauthenticated(), nil
+	}
+
+	user := trr.Status.User
+	if user == nil {
+		user = &models.UserInfo{Username: req.Username}
+	}
+
+	token, err := i.sign(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenReviewRequest{
+		APIVersion: "authentication.k8s.io/v1beta1",
+		Kind:       "TokenReview",
+		Spec: &models.TokenReviewSpec{
+			Token: token,
+		},
+		Status: &models.TokenReviewStatus{
+			Authenticated: true,
+			User:          user,
+			Audiences:     i.config.Audiences,
+		},
+	}, nil
+}
+
+// Authenticate validates the signature and claims of a token issued by Login.
+// Tokens not signed by the issuer, e.g. static tokens or tokens of other issuers, are authenticated by the backing provider
+func (i *Issuer) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	claims, signed := i.verify(req.Token)
+	if !signed {
+		return i.config.Provider.Authenticate(ctx, req)
+	}
+	if claims == nil {
+		return provider.Unauthenticated(), nil
+	}
+
+	return &models.TokenReviewRequest{
+		APIVersion: "authentication.k8s.io/v1beta1",
+		Kind:       "TokenReview",
+		Status: &models.TokenReviewStatus{
+			Authenticated: true,
+			User: &models.UserInfo{
+				Username: claims.Username,
+				UID:      claims.UID,
+				Groups:   claims.Groups,
+				Extra:    claims.Extra,
+			},
+			Audiences:  claims.Audience,
+			Expiration: claims.Expiry.Time().Unix(),
+		},
+	}, nil
+}
+
+// KeySet returns the keys tokens are signed and verified with
+func (i *Issuer) KeySet() KeySet {
+	return i.config.Keys
+}
+
+// sign returns a signed token for the user
+func (i *Issuer) sign(user *models.UserInfo) (string, error) {
+	key, err := i.config.Keys.SigningKey()
+	if err != nil {
+		return "", fmt.Errorf("failed to get signing key: %v", err)
+	}
+
+	signer, err := jose.NewSigner(
+		jose.SigningKey{
+			Algorithm: jose.SignatureAlgorithm(key.Algorithm),
+			Key:       jose.JSONWebKey{Key: key.Signer, KeyID: key.ID},
+		},
+		(&jose.SignerOptions{}).WithType("JWT"),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create signer: %v", err)
+	}
+
+	// the token id makes every token unique, even if issued within the same second
+	id, err := tokenstore.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := i.now()
+	claims := Claims{
+		Claims: josejwt.Claims{
+			ID:        id,
+			Issuer:    i.config.Issuer,
+			Subject:   user.Username,
+			Audience:  josejwt.Audience(i.config.Audiences),
+			IssuedAt:  josejwt.NewNumericDate(now),
+			NotBefore: josejwt.NewNumericDate(now),
+			Expiry:    josejwt.NewNumericDate(now.Add(i.config.Lifetime)),
+		},
+		Username: user.Username,
+		UID:      user.UID,
+		Groups:   user.Groups,
+		Extra:    user.Extra,
+	}
+
+	token, err := josejwt.Signed(signer).Claims(claims).CompactSerialize()
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %v", err)
+	}
+	return token, nil
+}
+
+// verify returns whether the token is signed by a known key and its claims if they are valid at the current time
+func (i *Issuer) verify(token string) (*Claims, bool) {
+	tok, err := josejwt.ParseSigned(token)
+	if err != nil || len(tok.Headers) != 1 {
+		return nil, false
+	}
+
+	header := tok.Headers[0]
+	key := i.config.Keys.VerificationKey(header.KeyID)
+	if key == nil || header.Algorithm != key.Algorithm {
+		return nil, false
+	}
+
+	var claims Claims
+	if err := tok.Claims(key.Public(), &claims); err != nil {
+		return nil, false
+	}
+
+	if claims.Expiry == nil || claims.Username == "" {
+		return nil, true
+	}
+
+	expected := josejwt.Expected{Issuer: i.config.Issuer, Time: i.now()}
+	if err := claims.ValidateWithLeeway(expected, i.config.ClockSkew); err != nil {
+		return nil, true
+	}
+
+	if len(i.config.Audiences) > 0 && !containsAny(claims.Audience, i.config.Audiences) {
+		return nil, true
+	}
+
+	return &claims, true
+}
+
+// containsAny returns true if a contains at least one element of b
+func containsAny(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
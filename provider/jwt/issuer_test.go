@@ -0,0 +1,179 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/fake"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func generateKeys(t *testing.T) []*Key {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []*Key
+	for _, priv := range []interface{}{rsaKey, ecKey, edKey} {
+		der, err := x509.MarshalPKCS8PrivateKey(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := ParseKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestIssuer(t *testing.T) {
+	for _, key := range generateKeys(t) {
+		t.Run(key.Algorithm, func(t *testing.T) {
+			issuer, err := NewIssuer(Config{
+				Provider:  provider.NewContextAdapter(fake.NewFakeProvider()),
+				Keys:      NewStaticKeySet(key),
+				Issuer:    "https://authproxy.example.com",
+				Audiences: []string{"kubernetes"},
+				Lifetime:  time.Hour,
+				ClockSkew: time.Minute,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if trr, err := issuer.Login(context.Background(), provider.LoginRequest{Username: "foo", Password: "wrong"}); err != nil || trr.Status.Authenticated || trr.Spec != nil {
+				t.Fatalf("expected login with wrong password to fail, got %+v, %v", trr, err)
+			}
+
+			login, err := issuer.Login(context.Background(), provider.LoginRequest{Username: "foo", Password: "bar"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			token := login.Spec.Token
+
+			trr, err := issuer.Authenticate(context.Background(), provider.AuthenticateRequest{Token: token})
+			if err != nil {
+				t.Fatal(err)
+			}
+			user := trr.Status.User
+			if !trr.Status.Authenticated || user.Username != "foo" || user.UID != "1" || !reflect.DeepEqual(user.Groups, []string{"developers"}) {
+				t.Errorf("unexpected authentication result %+v", trr.Status)
+			}
+			if !reflect.DeepEqual(trr.Status.Audiences, []string{"kubernetes"}) {
+				t.Errorf("expected audiences [kubernetes], got %v", trr.Status.Audiences)
+			}
+
+			// tampered signature
+			if trr, _ := issuer.Authenticate(context.Background(), provider.AuthenticateRequest{Token: token[:len(token)-4] + "AAAA"}); trr.Status.Authenticated {
+				t.Error("expected tampered token not to be authenticated")
+			}
+
+			// within the clock skew
+			issuer.now = func() time.Time { return time.Now().Add(time.Hour + 30*time.Second) }
+			if trr, _ := issuer.Authenticate(context.Background(), provider.AuthenticateRequest{Token: token}); !trr.Status.Authenticated {
+				t.Error("expected token to be valid within the clock skew")
+			}
+
+			// expired
+			issuer.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+			if trr, _ := issuer.Authenticate(context.Background(), provider.AuthenticateRequest{Token: token}); trr.Status.Authenticated {
+				t.Error("expected expired token not to be authenticated")
+			}
+		})
+	}
+}
+
+func TestIssuerRejectsForeignTokens(t *testing.T) {
+	keys := generateKeys(t)
+
+	issue := func(key *Key, iss string, audiences []string) string {
+		issuer, err := NewIssuer(Config{Provider: provider.NewContextAdapter(fake.NewFakeProvider()), Keys: NewStaticKeySet(key), Issuer: iss, Audiences: audiences})
+		if err != nil {
+			t.Fatal(err)
+		}
+		trr, err := issuer.Login(context.Background(), provider.LoginRequest{Username: "foo", Password: "bar"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return trr.Spec.Token
+	}
+
+	issuer, err := NewIssuer(Config{Provider: provider.NewContextAdapter(fake.NewFakeProvider()), Keys: NewStaticKeySet(keys[0]), Issuer: "a", Audiences: []string{"kubernetes"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := map[string]string{
+		"unknown key":    issue(keys[1], "a", []string{"kubernetes"}),
+		"other issuer":   issue(keys[0], "b", []string{"kubernetes"}),
+		"other audience": issue(keys[0], "a", []string{"other"}),
+	}
+	for name, token := range tokens {
+		if trr, _ := issuer.Authenticate(context.Background(), provider.AuthenticateRequest{Token: token}); trr.Status.Authenticated {
+			t.Errorf("expected token with %s not to be authenticated", name)
+		}
+	}
+}
+
+func TestIssuerDelegatesUnsignedTokens(t *testing.T) {
+	keys := generateKeys(t)
+
+	issuer, err := NewIssuer(Config{Provider: provider.NewContextAdapter(fake.NewFakeProvider()), Keys: NewStaticKeySet(keys[0]), Issuer: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the static token of the fake provider is no JWT and authenticated by the backing provider
+	trr, err := issuer.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "AbCdEf123456"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !trr.Status.Authenticated {
+		t.Errorf("expected token of the backing provider to be authenticated, got %+v", trr.Status)
+	}
+
+	if trr, _ := issuer.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "invalid"}); trr.Status.Authenticated {
+		t.Error("expected token unknown to the backing provider not to be authenticated")
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseKey(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(small)})); err == nil {
+		t.Error("expected RSA keys with less than 2048 bits to be rejected")
+	}
+
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(p384)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseKey(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})); err == nil {
+		t.Error("expected ECDSA keys not using P-256 to be rejected")
+	}
+}
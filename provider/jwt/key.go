@@ -0,0 +1,163 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"gopkg.in/square/go-jose.v2"
+	"io/ioutil"
+)
+
+// Signing algorithms supported for issued tokens
+const (
+	RS256 = string(jose.RS256)
+	ES256 = string(jose.ES256)
+	EdDSA = string(jose.EdDSA)
+)
+
+// Key represents a private key tokens are signed with
+type Key struct {
+	// ID is published as kid, it is the base64url encoded SHA-256 JWK thumbprint of the key
+	ID string
+	// Algorithm is the signing algorithm used with the key: RS256, ES256 or EdDSA
+	Algorithm string
+	// Signer holds the private key
+	Signer crypto.Signer
+}
+
+// Public returns the public key tokens are verified with
+func (k *Key) Public() crypto.PublicKey {
+	return k.Signer.Public()
+}
+
+// KeySet provides the keys tokens are signed and verified with
+type KeySet interface {
+	// SigningKey returns the key new tokens are signed with
+	SigningKey() (*Key, error)
+	// VerificationKey returns the key with the given ID, nil if the key is unknown
+	VerificationKey(id string) *Key
+	// VerificationKeys returns all keys tokens may still be signed with, they are published as JWKS
+	VerificationKeys() []*Key
+}
+
+// staticKeySet is a KeySet consisting of a single key
+type staticKeySet struct {
+	key *Key
+}
+
+// NewStaticKeySet returns a KeySet signing and verifying tokens with a single key
+func NewStaticKeySet(key *Key) KeySet {
+	return &staticKeySet{key: key}
+}
+
+func (s *staticKeySet) SigningKey() (*Key, error) {
+	return s.key, nil
+}
+
+func (s *staticKeySet) VerificationKey(id string) *Key {
+	if id != s.key.ID {
+		return nil
+	}
+	return s.key
+}
+
+func (s *staticKeySet) VerificationKeys() []*Key {
+	return []*Key{s.key}
+}
+
+// LoadKey reads a PEM encoded private key from a file
+func LoadKey(path string) (*Key, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %v", err)
+	}
+
+	key, err := ParseKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("key file %s: %v", path, err)
+	}
+	return key, nil
+}
+
+// ParseKey parses a PEM encoded RSA, ECDSA P-256 or Ed25519 private key.
+// PKCS #8, PKCS #1 and SEC 1 encodings are supported.
+func ParseKey(b []byte) (*Key, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("no PEM encoded key found")
+	}
+
+	var (
+		priv interface{}
+		err  error
+	)
+	switch block.Type {
+	case "PRIVATE KEY":
+		priv, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		priv, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		priv, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+
+	return NewKey(priv)
+}
+
+// NewKey returns a key for an *rsa.PrivateKey, a P-256 *ecdsa.PrivateKey or an ed25519.PrivateKey.
+// The algorithm is chosen by the type of the key.
+func NewKey(priv interface{}) (*Key, error) {
+	var key Key
+
+	switch k := priv.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA keys must have at least 2048 bits, got %d", k.N.BitLen())
+		}
+		key = Key{Algorithm: RS256, Signer: k}
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("ECDSA keys must use the P-256 curve, got %s", k.Curve.Params().Name)
+		}
+		key = Key{Algorithm: ES256, Signer: k}
+	case ed25519.PrivateKey:
+		key = Key{Algorithm: EdDSA, Signer: k}
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", priv)
+	}
+
+	thumbprint, err := (&jose.JSONWebKey{Key: key.Public()}).Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute key id: %v", err)
+	}
+	key.ID = base64.RawURLEncoding.EncodeToString(thumbprint)
+
+	return &key, nil
+}
@@ -0,0 +1,394 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// KeyState represents the state of a managed signing key
+type KeyState string
+
+const (
+	// KeyPending keys are published but not used for signing yet
+	KeyPending KeyState = "pending"
+	// KeyActive is the key new tokens are signed with
+	KeyActive KeyState = "active"
+	// KeyRetired keys are only used to verify tokens issued before they were retired
+	KeyRetired KeyState = "retired"
+)
+
+// ErrNoPendingKey is returned when rotating without a pending key to promote
+var ErrNoPendingKey = errors.New("no pending key to promote")
+
+// ManagerConfig represents the key manager configuration
+type ManagerConfig struct {
+	// Dir holds the PEM encoded private keys, one per *.pem file
+	Dir string
+	// RotationInterval is the time after which the next pending key is promoted, 0 disables scheduled rotation
+	RotationInterval time.Duration
+	// RetentionPeriod is the time retired keys are kept to verify tokens,
+	// it should be the longest token lifetime plus the clock skew (default: 1h1m)
+	RetentionPeriod time.Duration
+	// ReloadInterval is the interval the directory is checked for new keys, 0 disables reloading
+	ReloadInterval time.Duration
+	// Logger is used to report rotations and failed reloads (optional)
+	Logger log.Logger
+}
+
+// KeyStatus represents the state of a managed key
+type KeyStatus struct {
+	ID          string     `json:"kid"`
+	Algorithm   string     `json:"alg"`
+	State       KeyState   `json:"state"`
+	File        string     `json:"file"`
+	ActivatedAt *time.Time `json:"activatedAt,omitempty"`
+	RetiredAt   *time.Time `json:"retiredAt,omitempty"`
+}
+
+type managedKey struct {
+	key       *Key
+	file      string
+	state     KeyState
+	activated time.Time
+	retired   time.Time
+}
+
+// Manager implements KeySet with pending, active and retired keys loaded from a directory.
+// On startup the key with the lexicographically last file name is active and all others are retired.
+// Keys added to the directory later are pending until they are promoted by Rotate or Promote.
+type Manager struct {
+	config ManagerConfig
+	now    func() time.Time
+
+	mu   sync.RWMutex
+	keys []*managedKey
+
+	done chan struct{}
+	once sync.Once
+}
+
+// NewManager returns a new key manager for the keys in the directory of the config.
+// The directory is reloaded and keys are rotated in the background until Close is called.
+func NewManager(cfg ManagerConfig) (*Manager, error) {
+	if cfg.Logger == nil {
+		cfg.Logger = log.NewNopLogger()
+	}
+	if cfg.RetentionPeriod <= 0 {
+		cfg.RetentionPeriod = DefaultLifetime + DefaultClockSkew
+	}
+
+	m := &Manager{
+		config: cfg,
+		now:    time.Now,
+		done:   make(chan struct{}),
+	}
+
+	keys, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys found in %s", cfg.Dir)
+	}
+
+	now := m.now()
+	for i, k := range keys {
+		if i == len(keys)-1 {
+			k.state, k.activated = KeyActive, now
+		} else {
+			// keys of previous runs still verify the tokens they issued
+			k.state, k.retired = KeyRetired, now
+		}
+	}
+	m.keys = keys
+
+	if cfg.ReloadInterval > 0 || cfg.RotationInterval > 0 {
+		go m.run()
+	}
+
+	return m, nil
+}
+
+// SigningKey returns the active key
+func (m *Manager) SigningKey() (*Key, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, k := range m.keys {
+		if k.state == KeyActive {
+			return k.key, nil
+		}
+	}
+	return nil, errors.New("no active signing key")
+}
+
+// VerificationKey returns the active or a retired key with the given ID, nil if the key is unknown or expired
+func (m *Manager) VerificationKey(id string) *Key {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := m.now()
+	for _, k := range m.keys {
+		if k.key.ID == id && k.state != KeyPending && !m.expired(k, now) {
+			return k.key
+		}
+	}
+	return nil
+}
+
+// VerificationKeys returns the active and retired keys which are not expired.
+// Pending keys are included as well, so clients caching the JWKS know them before they are promoted.
+func (m *Manager) VerificationKeys() []*Key {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := m.now()
+	var keys []*Key
+	for _, k := range m.keys {
+		if !m.expired(k, now) {
+			keys = append(keys, k.key)
+		}
+	}
+	return keys
+}
+
+// Status returns the state of all keys
+func (m *Manager) Status() []KeyStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := m.now()
+	status := []KeyStatus{}
+	for _, k := range m.keys {
+		if m.expired(k, now) {
+			continue
+		}
+
+		s := KeyStatus{ID: k.key.ID, Algorithm: k.key.Algorithm, State: k.state, File: k.file}
+		if !k.activated.IsZero() {
+			activated := k.activated
+			s.ActivatedAt = &activated
+		}
+		if !k.retired.IsZero() {
+			retired := k.retired
+			s.RetiredAt = &retired
+		}
+		status = append(status, s)
+	}
+	return status
+}
+
+// Rotate retires the active key and promotes the pending key with the lexicographically first file name
+func (m *Manager) Rotate() error {
+	return m.Promote("")
+}
+
+// Promote retires the active key and promotes the pending key with the given ID.
+// An empty ID promotes the pending key with the lexicographically first file name.
+func (m *Manager) Promote(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var next *managedKey
+	for _, k := range m.keys {
+		if k.state == KeyPending && (id == "" || k.key.ID == id) {
+			next = k
+			break
+		}
+	}
+	if next == nil {
+		if id != "" {
+			return fmt.Errorf("%v with id %s", ErrNoPendingKey, id)
+		}
+		return ErrNoPendingKey
+	}
+
+	now := m.now()
+	for _, k := range m.keys {
+		if k.state == KeyActive {
+			k.state, k.retired = KeyRetired, now
+		}
+	}
+	next.state, next.activated = KeyActive, now
+
+	level.Info(m.config.Logger).Log("msg", "promoted signing key", "kid", next.key.ID, "file", next.file)
+	return nil
+}
+
+// Close stops reloading and rotating keys
+func (m *Manager) Close() {
+	m.once.Do(func() { close(m.done) })
+}
+
+// run reloads the directory, rotates keys on schedule and removes expired keys
+func (m *Manager) run() {
+	interval := m.config.ReloadInterval
+	if interval <= 0 || (m.config.RotationInterval > 0 && m.config.RotationInterval < interval) {
+		interval = m.config.RotationInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			if m.config.ReloadInterval > 0 {
+				m.reload()
+			}
+			if m.config.RotationInterval > 0 && m.rotationDue() {
+				if err := m.Rotate(); err != nil {
+					level.Warn(m.config.Logger).Log("msg", "scheduled signing key rotation skipped", "err", err)
+				}
+			}
+			m.purge()
+		}
+	}
+}
+
+// rotationDue returns true if the active key has been active for the rotation interval
+func (m *Manager) rotationDue() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, k := range m.keys {
+		if k.state == KeyActive {
+			return !m.now().Before(k.activated.Add(m.config.RotationInterval))
+		}
+	}
+	return true
+}
+
+// reload adds new keys of the directory as pending and drops pending keys whose files were removed.
+// Active and retired keys are kept until they expire, even if their files were removed.
+func (m *Manager) reload() {
+	keys, err := m.load()
+	if err != nil {
+		level.Error(m.config.Logger).Log("msg", "failed to reload signing keys, keeping previous keys", "dir", m.config.Dir, "err", err)
+		return
+	}
+
+	found := map[string]bool{}
+	for _, k := range keys {
+		found[k.key.ID] = true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	known := map[string]bool{}
+	current := m.keys[:0]
+	for _, k := range m.keys {
+		if k.state == KeyPending && !found[k.key.ID] {
+			level.Info(m.config.Logger).Log("msg", "removed pending signing key", "kid", k.key.ID, "file", k.file)
+			continue
+		}
+		known[k.key.ID] = true
+		current = append(current, k)
+	}
+
+	for _, k := range keys {
+		if known[k.key.ID] {
+			continue
+		}
+		k.state = KeyPending
+		current = append(current, k)
+		level.Info(m.config.Logger).Log("msg", "added pending signing key", "kid", k.key.ID, "file", k.file)
+	}
+
+	sort.SliceStable(current, func(i, j int) bool { return current[i].file < current[j].file })
+	m.keys = current
+}
+
+// purge removes expired retired keys
+func (m *Manager) purge() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	current := m.keys[:0]
+	for _, k := range m.keys {
+		if m.expired(k, now) {
+			level.Info(m.config.Logger).Log("msg", "removed expired signing key", "kid", k.key.ID, "file", k.file)
+			continue
+		}
+		current = append(current, k)
+	}
+	m.keys = current
+}
+
+// expired returns true if a retired key is no longer needed to verify tokens
+func (m *Manager) expired(k *managedKey, now time.Time) bool {
+	return k.state == KeyRetired && !now.Before(k.retired.Add(m.config.RetentionPeriod))
+}
+
+// load reads all keys of the directory sorted by file name
+func (m *Manager) load() ([]*managedKey, error) {
+	files, err := filepath.Glob(filepath.Join(m.config.Dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list key directory: %v", err)
+	}
+	sort.Strings(files)
+
+	var keys []*managedKey
+	seen := map[string]bool{}
+	for _, file := range files {
+		key, err := LoadKey(file)
+		if err != nil {
+			return nil, err
+		}
+		if seen[key.ID] {
+			return nil, fmt.Errorf("key file %s: duplicate key %s", file, key.ID)
+		}
+		seen[key.ID] = true
+
+		keys = append(keys, &managedKey{key: key, file: filepath.Base(file)})
+	}
+	return keys, nil
+}
+
+// AdminHandler returns a handler listing the keys on GET. It never changes keys as it is served unauthenticated,
+// keys are rotated by Rotate or Promote.
+func (m *Manager) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		b, err := json.Marshal(m.Status())
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	})
+}
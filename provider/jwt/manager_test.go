@@ -0,0 +1,163 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/fake"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeKey(t *testing.T, path string) *Key {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := ioutil.WriteFile(path, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParseKey(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func keyIDs(keys []*Key) map[string]bool {
+	ids := map[string]bool{}
+	for _, k := range keys {
+		ids[k.ID] = true
+	}
+	return ids
+}
+
+func TestManagerRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	old := writeKey(t, filepath.Join(dir, "01.pem"))
+	current := writeKey(t, filepath.Join(dir, "02.pem"))
+
+	m, err := NewManager(ManagerConfig{Dir: dir, RetentionPeriod: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	if key, err := m.SigningKey(); err != nil || key.ID != current.ID {
+		t.Fatalf("expected the last key to be active, got %v, %v", key, err)
+	}
+	if m.VerificationKey(old.ID) == nil {
+		t.Error("expected keys of previous runs to be retired and verify tokens")
+	}
+	if err := m.Rotate(); err != ErrNoPendingKey {
+		t.Errorf("expected rotation without pending keys to fail, got %v", err)
+	}
+
+	issuer, err := NewIssuer(Config{Provider: provider.NewContextAdapter(fake.NewFakeProvider()), Keys: m})
+	if err != nil {
+		t.Fatal(err)
+	}
+	login, err := issuer.Login(context.Background(), provider.LoginRequest{Username: "foo", Password: "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// new keys are published but not used for signing
+	next := writeKey(t, filepath.Join(dir, "03.pem"))
+	m.reload()
+	if !keyIDs(m.VerificationKeys())[next.ID] {
+		t.Error("expected pending key to be published")
+	}
+	if m.VerificationKey(next.ID) != nil {
+		t.Error("expected pending key not to verify tokens")
+	}
+
+	rec := httptest.NewRecorder()
+	m.AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/keys", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected the admin handler not to rotate keys, got %d", rec.Code)
+	}
+
+	if err := m.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	m.AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/keys", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected keys to be listed, got %d: %s", rec.Code, rec.Body)
+	}
+	var status []KeyStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	states := map[string]KeyState{}
+	for _, s := range status {
+		states[s.ID] = s.State
+	}
+	if states[old.ID] != KeyRetired || states[current.ID] != KeyRetired || states[next.ID] != KeyActive {
+		t.Errorf("unexpected key states %+v", status)
+	}
+
+	// tokens of the retired key stay valid until the retention period passed
+	if trr, _ := issuer.Authenticate(context.Background(), provider.AuthenticateRequest{Token: login.Spec.Token}); !trr.Status.Authenticated {
+		t.Error("expected token of retired key to be authenticated")
+	}
+
+	now = now.Add(time.Hour)
+	m.purge()
+	if trr, _ := issuer.Authenticate(context.Background(), provider.AuthenticateRequest{Token: login.Spec.Token}); trr.Status.Authenticated {
+		t.Error("expected token of expired key not to be authenticated")
+	}
+	if ids := keyIDs(m.VerificationKeys()); len(ids) != 1 || !ids[next.ID] {
+		t.Errorf("expected only the active key to be published, got %v", ids)
+	}
+}
+
+func TestManagerScheduledRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeKey(t, filepath.Join(dir, "01.pem"))
+
+	m, err := NewManager(ManagerConfig{Dir: dir, RotationInterval: 20 * time.Millisecond, ReloadInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	next := writeKey(t, filepath.Join(dir, "02.pem"))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if key, _ := m.SigningKey(); key.ID == next.ID {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("expected the pending key to be promoted on schedule")
+}
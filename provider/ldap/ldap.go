@@ -0,0 +1,506 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package ldap implements a provider checking login credentials against an LDAP directory.
+// Users are searched with a service account and their password is checked by binding as the user.
+// Tokens are issued through a pluggable token store after successful logins.
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/tokenstore"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/ldap.v3"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultUserFilter is the filter users are searched with if none is configured
+	DefaultUserFilter = "(uid=%s)"
+	// DefaultUsernameAttribute is the attribute used as username if none is configured
+	DefaultUsernameAttribute = "uid"
+	// DefaultGroupsAttribute is the attribute of users holding the DNs of their groups if none is configured
+	DefaultGroupsAttribute = "memberOf"
+	// DefaultGroupNameAttribute is the attribute used as group name if none is configured
+	DefaultGroupNameAttribute = "cn"
+	// DefaultPoolSize is the number of idle connections kept if none is configured
+	DefaultPoolSize = 4
+	// DefaultTimeout is the timeout of connecting and requests if none is configured
+	DefaultTimeout = 10 * time.Second
+)
+
+// Config represents the LDAP provider configuration
+type Config struct {
+	// URL of the LDAP server, ldap://host:389 or ldaps://host:636
+	URL string
+	// StartTLS upgrades ldap:// connections to TLS before binding
+	StartTLS bool
+	// CAFile holds the certificates the server certificate is verified with, the system roots are used if unset
+	CAFile string
+	// InsecureSkipVerify disables the verification of the server certificate
+	InsecureSkipVerify bool
+	// TLSConfig is used for LDAPS and StartTLS instead of CAFile and InsecureSkipVerify (optional)
+	TLSConfig *tls.Config
+
+	// BindDN and BindPassword of the service account searching users and groups, the search is anonymous if unset
+	BindDN       string
+	BindPassword string
+
+	// BaseDN users are searched in
+	BaseDN string
+	// UserFilter finds a user, %s is replaced by the escaped username (default: (uid=%s))
+	UserFilter string
+	// UsernameAttribute of users returned as username (default: uid)
+	UsernameAttribute string
+	// UIDAttribute of users returned as uid, e.g. uidNumber or entryUUID (optional)
+	UIDAttribute string
+	// ExtraAttributes of users returned as extra of the user (optional)
+	ExtraAttributes []string
+
+	// GroupsAttribute of users holding the DNs of their groups (default: memberOf), unused if GroupFilter is set
+	GroupsAttribute string
+	// GroupFilter searches the groups of a user, %s is replaced by the escaped DN of the user, e.g. (member=%s) (optional)
+	GroupFilter string
+	// GroupBaseDN groups are searched in (default: BaseDN)
+	GroupBaseDN string
+	// GroupNameAttribute of groups returned as group name (default: cn)
+	GroupNameAttribute string
+
+	// PoolSize is the number of idle connections kept open (default: 4)
+	PoolSize int
+	// Timeout of connecting to the server and of requests (default: 10s)
+	Timeout time.Duration
+
+	// TokenStore issues tokens after successful logins (default: in-memory store)
+	TokenStore tokenstore.Store
+	// TokenTTL is the lifetime of tokens issued by the default in-memory store
+	TokenTTL time.Duration
+	// Logger is used to report unexpected directory contents (optional)
+	Logger log.Logger
+}
+
+// Provider implements provider.ContextProvider using an LDAP directory
+type Provider struct {
+	config Config
+	addr   string
+	tls    bool
+	pool   *pool
+}
+
+// NewProvider returns a new provider for the LDAP server of the config.
+// Connections are opened on demand, Close closes the idle ones.
+func NewProvider(cfg Config) (*Provider, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ldap url: %v", err)
+	}
+
+	p := &Provider{addr: u.Host}
+
+	switch u.Scheme {
+	case "ldap":
+		if _, _, err := net.SplitHostPort(p.addr); err != nil {
+			p.addr = net.JoinHostPort(p.addr, "389")
+		}
+	case "ldaps":
+		if cfg.StartTLS {
+			return nil, fmt.Errorf("StartTLS can't be used with ldaps")
+		}
+		if _, _, err := net.SplitHostPort(p.addr); err != nil {
+			p.addr = net.JoinHostPort(p.addr, "636")
+		}
+		p.tls = true
+	default:
+		return nil, fmt.Errorf("invalid ldap url: unsupported scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid ldap url: missing host")
+	}
+	if cfg.BaseDN == "" {
+		return nil, fmt.Errorf("no base dn configured")
+	}
+
+	if cfg.TLSConfig == nil {
+		cfg.TLSConfig = &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+
+		if cfg.CAFile != "" {
+			pem, err := ioutil.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, err
+			}
+			roots := x509.NewCertPool()
+			if !roots.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+			}
+			cfg.TLSConfig.RootCAs = roots
+		}
+	}
+	if cfg.TLSConfig.ServerName == "" {
+		cfg.TLSConfig = cfg.TLSConfig.Clone()
+		cfg.TLSConfig.ServerName = u.Hostname()
+	}
+
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = DefaultUserFilter
+	}
+	if cfg.UsernameAttribute == "" {
+		cfg.UsernameAttribute = DefaultUsernameAttribute
+	}
+	if cfg.GroupsAttribute == "" {
+		cfg.GroupsAttribute = DefaultGroupsAttribute
+	}
+	if cfg.GroupBaseDN == "" {
+		cfg.GroupBaseDN = cfg.BaseDN
+	}
+	if cfg.GroupNameAttribute == "" {
+		cfg.GroupNameAttribute = DefaultGroupNameAttribute
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = DefaultPoolSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.NewNopLogger()
+	}
+	if cfg.TokenStore == nil {
+		cfg.TokenStore = tokenstore.NewMemoryStore(cfg.TokenTTL)
+	}
+
+	p.config = cfg
+	p.pool = newPool(cfg.PoolSize, p.dial)
+
+	return p, nil
+}
+
+// Login searches the user, checks the password by binding as the user and issues a token on success
+func (p *Provider) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	// an empty password would result in an anonymous bind which always succeeds
+	username, password := req.Username, req.Password
+	if username == "" || password == "" {
+		return provider.Unauthenticated(), nil
+	}
+
+	var info *models.UserInfo
+	err := p.withConn(ctx, func(conn *ldap.Conn) error {
+		entry, err := p.searchUser(conn, username)
+		if err != nil || entry == nil {
+			return err
+		}
+
+		if err := conn.Bind(entry.DN, password); err != nil {
+			if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+				return p.bindService(conn)
+			}
+			return err
+		}
+
+		// search groups with the service account, users often aren't allowed to
+		if err := p.bindService(conn); err != nil {
+			return err
+		}
+
+		groups, err := p.groupsOf(conn, entry)
+		if err != nil {
+			return err
+		}
+
+		info = p.user(entry, groups)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return provider.Unauthenticated(), nil
+	}
+
+	token, err := p.config.TokenStore.Issue(info)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenReviewRequest{
+		APIVersion: "authentication.k8s.io/v1beta1",
+		Kind:       "TokenReview",
+		Spec: &models.TokenReviewSpec{
+			Token: token,
+		},
+		Status: &models.TokenReviewStatus{
+			Authenticated: true,
+			User:          info,
+		},
+	}, nil
+}
+
+// Authenticate looks up the bearer token in the token store.
+// The user and groups are the ones found in the directory at login
+func (p *Provider) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	info, err := p.config.TokenStore.Lookup(req.Token)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return provider.Unauthenticated(), nil
+	}
+
+	return &models.TokenReviewRequest{
+		APIVersion: "authentication.k8s.io/v1beta1",
+		Kind:       "TokenReview",
+		Status: &models.TokenReviewStatus{
+			Authenticated: true,
+			User:          info,
+		},
+	}, nil
+}
+
+// Close closes the idle connections to the server
+func (p *Provider) Close() {
+	p.pool.close()
+}
+
+// withConn calls fn with a connection bound as the service account.
+// Idle connections which turn out to be broken are replaced by a new connection once.
+// The connection is closed if the context is done before fn returns, aborting its requests
+func (p *Provider) withConn(ctx context.Context, fn func(conn *ldap.Conn) error) error {
+	for {
+		conn, pooled, err := p.pool.get(ctx)
+		if err != nil {
+			return errors.NewServiceUnavailable(fmt.Errorf("failed to connect to ldap server: %v", err))
+		}
+
+		stop := closeWhenDone(ctx, conn)
+		err = fn(conn)
+		stop()
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			conn.Close()
+			return errors.NewServiceUnavailable(fmt.Errorf("ldap request aborted: %v", ctxErr))
+		}
+		if err == nil {
+			p.pool.put(conn)
+			return nil
+		}
+
+		// the connection is closing if it was closed by the server or the network failed
+		broken := conn.IsClosing() || ldap.IsErrorWithCode(err, ldap.ErrorNetwork)
+		conn.Close()
+
+		if broken && pooled {
+			continue
+		}
+		if broken || ldap.IsErrorWithCode(err, ldap.LDAPResultUnavailable) || ldap.IsErrorWithCode(err, ldap.LDAPResultBusy) {
+			return errors.NewServiceUnavailable(fmt.Errorf("ldap request failed: %v", err))
+		}
+		return errors.NewInternalError(fmt.Errorf("ldap request failed: %v", err))
+	}
+}
+
+// dial opens a new connection bound as the service account until the context is done
+func (p *Provider) dial(ctx context.Context) (*ldap.Conn, error) {
+	dialer := &net.Dialer{Timeout: p.config.Timeout}
+
+	c, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tls {
+		deadline := time.Now().Add(p.config.Timeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		tc := tls.Client(c, p.config.TLSConfig)
+		_ = c.SetDeadline(deadline)
+		if err := tc.Handshake(); err != nil {
+			c.Close()
+			return nil, err
+		}
+		_ = c.SetDeadline(time.Time{})
+		c = tc
+	}
+
+	conn := ldap.NewConn(c, p.tls)
+	conn.Start()
+	conn.SetTimeout(p.config.Timeout)
+
+	stop := closeWhenDone(ctx, conn)
+	defer stop()
+
+	if p.config.StartTLS {
+		if err := conn.StartTLS(p.config.TLSConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	if err := p.bindService(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// closeWhenDone closes the connection once the context is done until stop is called.
+// The ldap client doesn't take a context, closing the connection aborts its pending requests
+func closeWhenDone(ctx context.Context, conn *ldap.Conn) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// bindService binds the connection as the service account or anonymously if none is configured
+func (p *Provider) bindService(conn *ldap.Conn) error {
+	if p.config.BindDN == "" {
+		return conn.UnauthenticatedBind("")
+	}
+	return conn.Bind(p.config.BindDN, p.config.BindPassword)
+}
+
+// searchUser returns the entry of the user or nil if there is no unique user with the name
+func (p *Provider) searchUser(conn *ldap.Conn, username string) (*ldap.Entry, error) {
+	attributes := []string{p.config.UsernameAttribute}
+	if p.config.UIDAttribute != "" {
+		attributes = append(attributes, p.config.UIDAttribute)
+	}
+	if p.config.GroupFilter == "" {
+		attributes = append(attributes, p.config.GroupsAttribute)
+	}
+	attributes = append(attributes, p.config.ExtraAttributes...)
+
+	res, err := conn.Search(ldap.NewSearchRequest(
+		p.config.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		filter(p.config.UserFilter, username), attributes, nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, err
+	}
+
+	switch {
+	case res == nil || len(res.Entries) == 0:
+		return nil, nil
+	case len(res.Entries) > 1:
+		level.Warn(p.config.Logger).Log("msg", "user filter matches more than one entry, rejecting login", "username", username)
+		return nil, nil
+	}
+
+	return res.Entries[0], nil
+}
+
+// groupsOf returns the group names of the user, found by the group filter or the groups attribute
+func (p *Provider) groupsOf(conn *ldap.Conn, entry *ldap.Entry) ([]string, error) {
+	if p.config.GroupFilter == "" {
+		var groups []string
+		for _, dn := range entry.GetAttributeValues(p.config.GroupsAttribute) {
+			name, err := rdnValue(dn, p.config.GroupNameAttribute)
+			if err != nil {
+				level.Warn(p.config.Logger).Log("msg", "ignoring invalid group dn", "dn", dn, "err", err)
+				continue
+			}
+			groups = append(groups, name)
+		}
+		return groups, nil
+	}
+
+	res, err := conn.Search(ldap.NewSearchRequest(
+		p.config.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		filter(p.config.GroupFilter, entry.DN), []string{p.config.GroupNameAttribute}, nil,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []string
+	for _, group := range res.Entries {
+		if name := group.GetAttributeValue(p.config.GroupNameAttribute); name != "" {
+			groups = append(groups, name)
+		}
+	}
+	return groups, nil
+}
+
+// user maps the attributes of a user entry to the user info
+func (p *Provider) user(entry *ldap.Entry, groups []string) *models.UserInfo {
+	info := &models.UserInfo{
+		Username: entry.GetAttributeValue(p.config.UsernameAttribute),
+		Groups:   groups,
+	}
+	if p.config.UIDAttribute != "" {
+		info.UID = entry.GetAttributeValue(p.config.UIDAttribute)
+	}
+
+	extra := map[string]interface{}{}
+	for _, attribute := range p.config.ExtraAttributes {
+		if values := entry.GetAttributeValues(attribute); len(values) > 0 {
+			extra[attribute] = values
+		}
+	}
+	if len(extra) > 0 {
+		info.Extra = extra
+	}
+
+	return info
+}
+
+// filter replaces %s in the filter by the escaped value
+func filter(f, value string) string {
+	return strings.Replace(f, "%s", ldap.EscapeFilter(value), -1)
+}
+
+// rdnValue returns the value of the attribute in the first RDN of the DN, e.g. admins for cn=admins,ou=groups,dc=example,dc=org
+func rdnValue(dn, attribute string) (string, error) {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return "", err
+	}
+	if len(parsed.RDNs) > 0 {
+		for _, attr := range parsed.RDNs[0].Attributes {
+			if strings.EqualFold(attr.Type, attribute) {
+				return attr.Value, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("first rdn has no %s attribute", attribute)
+}
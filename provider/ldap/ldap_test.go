@@ -0,0 +1,322 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/provider"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var directory = []entry{
+	{dn: "cn=authproxy,ou=services,dc=example,dc=org", password: "service"},
+	{
+		dn:       "uid=alice,ou=people,dc=example,dc=org",
+		password: "wonderland",
+		attributes: map[string][]string{
+			"uid":       {"alice"},
+			"uidNumber": {"1000"},
+			"mail":      {"alice@example.org"},
+			"memberOf":  {"cn=admins,ou=groups,dc=example,dc=org", "cn=developers,ou=groups,dc=example,dc=org"},
+		},
+	},
+	{
+		dn:         "uid=bob,ou=people,dc=example,dc=org",
+		password:   "builder",
+		attributes: map[string][]string{"uid": {"bob"}, "uidNumber": {"1001"}},
+	},
+	{
+		dn:         "cn=admins,ou=groups,dc=example,dc=org",
+		attributes: map[string][]string{"cn": {"admins"}, "member": {"uid=alice,ou=people,dc=example,dc=org"}},
+	},
+	{
+		dn:         "cn=builders,ou=groups,dc=example,dc=org",
+		attributes: map[string][]string{"cn": {"builders"}, "member": {"uid=alice,ou=people,dc=example,dc=org", "uid=bob,ou=people,dc=example,dc=org"}},
+	},
+}
+
+func testConfig(s *server) Config {
+	return Config{
+		URL:             "ldap://" + s.addr(),
+		BindDN:          "cn=authproxy,ou=services,dc=example,dc=org",
+		BindPassword:    "service",
+		BaseDN:          "ou=people,dc=example,dc=org",
+		UIDAttribute:    "uidNumber",
+		ExtraAttributes: []string{"mail"},
+		Timeout:         time.Second,
+	}
+}
+
+// clientTLSConfig trusts the certificate of the test server
+func clientTLSConfig(t *testing.T, s *server) *tls.Config {
+	cert, err := x509.ParseCertificate(s.tls.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	return &tls.Config{RootCAs: roots}
+}
+
+func TestLogin(t *testing.T) {
+	s := newServer(t, false, directory...)
+	defer s.close()
+
+	p, err := NewProvider(testConfig(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	tests := []struct {
+		username      string
+		password      string
+		authenticated bool
+	}{
+		{username: "alice", password: "wonderland", authenticated: true},
+		{username: "alice", password: "wrong"},
+		{username: "alice", password: ""},
+		{username: "mallory", password: "wonderland"},
+		{username: "*", password: "wonderland"},
+		{username: "alice)(uid=*", password: "wonderland"},
+	}
+
+	for _, tt := range tests {
+		trr, err := p.Login(context.Background(), provider.LoginRequest{Username: tt.username, Password: tt.password})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.username, err)
+		}
+		if trr.Status.Authenticated != tt.authenticated {
+			t.Errorf("%s/%s: expected authenticated %v", tt.username, tt.password, tt.authenticated)
+		}
+	}
+
+	trr, err := p.Login(context.Background(), provider.LoginRequest{Username: "alice", Password: "wonderland"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := trr.Status.User
+	if user.Username != "alice" || user.UID != "1000" {
+		t.Errorf("unexpected user %+v", user)
+	}
+	if !reflect.DeepEqual(user.Groups, []string{"admins", "developers"}) {
+		t.Errorf("expected groups from memberOf, got %v", user.Groups)
+	}
+	if extra := user.Extra.(map[string]interface{}); !reflect.DeepEqual(extra["mail"], []string{"alice@example.org"}) {
+		t.Errorf("expected mail in extra, got %v", extra)
+	}
+
+	auth, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: trr.Spec.Token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !auth.Status.Authenticated || auth.Status.User.Username != "alice" {
+		t.Errorf("expected issued token to authenticate alice, got %+v", auth.Status)
+	}
+
+	auth, err = p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "invalid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.Status.Authenticated {
+		t.Error("expected unknown token to be unauthenticated")
+	}
+}
+
+func TestLoginGroupFilter(t *testing.T) {
+	s := newServer(t, false, directory...)
+	defer s.close()
+
+	cfg := testConfig(s)
+	cfg.GroupBaseDN = "ou=groups,dc=example,dc=org"
+	cfg.GroupFilter = "(&(cn=*)(member=%s))"
+
+	p, err := NewProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	trr, err := p.Login(context.Background(), provider.LoginRequest{Username: "bob", Password: "builder"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(trr.Status.User.Groups, []string{"builders"}) {
+		t.Errorf("expected groups from group search, got %v", trr.Status.User.Groups)
+	}
+}
+
+func TestTLS(t *testing.T) {
+	ldaps := newServer(t, true, directory...)
+	defer ldaps.close()
+	plain := newServer(t, false, directory...)
+	defer plain.close()
+
+	tests := []struct {
+		name string
+		cfg  func() Config
+		err  bool
+	}{
+		{
+			name: "ldaps",
+			cfg: func() Config {
+				cfg := testConfig(ldaps)
+				cfg.URL = "ldaps://" + ldaps.addr()
+				cfg.TLSConfig = clientTLSConfig(t, ldaps)
+				return cfg
+			},
+		},
+		{
+			name: "starttls",
+			cfg: func() Config {
+				cfg := testConfig(plain)
+				cfg.StartTLS = true
+				cfg.TLSConfig = clientTLSConfig(t, plain)
+				return cfg
+			},
+		},
+		{
+			name: "untrusted certificate",
+			cfg: func() Config {
+				cfg := testConfig(plain)
+				cfg.StartTLS = true
+				return cfg
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		p, err := NewProvider(tt.cfg())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		trr, err := p.Login(context.Background(), provider.LoginRequest{Username: "alice", Password: "wonderland"})
+		p.Close()
+
+		if tt.err {
+			if errors.ReasonForError(err) != http.StatusServiceUnavailable {
+				t.Errorf("%s: expected service unavailable error, got %v", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !trr.Status.Authenticated {
+			t.Errorf("%s: expected login to succeed", tt.name)
+		}
+	}
+}
+
+func TestConnectionPool(t *testing.T) {
+	s := newServer(t, false, directory...)
+	defer s.close()
+
+	p, err := NewProvider(testConfig(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := p.Login(context.Background(), provider.LoginRequest{Username: "alice", Password: "wonderland"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if dials := atomic.LoadInt64(&s.dials); dials != 1 {
+		t.Errorf("expected connection to be reused, got %d connections", dials)
+	}
+
+	// broken idle connections are replaced
+	s.dropConnections()
+
+	trr, err := p.Login(context.Background(), provider.LoginRequest{Username: "alice", Password: "wonderland"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !trr.Status.Authenticated {
+		t.Error("expected login to succeed after reconnecting")
+	}
+}
+
+func TestLoginContext(t *testing.T) {
+	s := newServer(t, false, directory...)
+	defer s.close()
+	s.searchDelay = 2 * time.Second
+
+	cfg := testConfig(s)
+	cfg.Timeout = 10 * time.Second
+
+	p, err := NewProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := p.Login(ctx, provider.LoginRequest{Username: "alice", Password: "wonderland"}); !errors.IsServiceUnavailable(err) {
+		t.Errorf("expected aborted login to fail with service unavailable, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected login to be aborted after 200ms, took %s", elapsed)
+	}
+}
+
+func TestServiceBindFailure(t *testing.T) {
+	s := newServer(t, false, directory...)
+	defer s.close()
+
+	cfg := testConfig(s)
+	cfg.BindPassword = "wrong"
+
+	p, err := NewProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if _, err := p.Login(context.Background(), provider.LoginRequest{Username: "alice", Password: "wonderland"}); errors.ReasonForError(err) != http.StatusServiceUnavailable {
+		t.Errorf("expected service unavailable error, got %v", err)
+	}
+}
+
+func TestNewProviderInvalidConfig(t *testing.T) {
+	for _, cfg := range []Config{
+		{URL: "http://localhost", BaseDN: "dc=example,dc=org"},
+		{URL: "ldaps://localhost", BaseDN: "dc=example,dc=org", StartTLS: true},
+		{URL: "ldap://", BaseDN: "dc=example,dc=org"},
+		{URL: "ldap://localhost"},
+	} {
+		if _, err := NewProvider(cfg); err == nil {
+			t.Errorf("expected config %+v to be invalid", cfg)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package ldap
+
+import (
+	"context"
+	"gopkg.in/ldap.v3"
+	"sync"
+)
+
+// pool keeps idle connections bound as the service account for reuse
+type pool struct {
+	dial func(ctx context.Context) (*ldap.Conn, error)
+
+	mu     sync.Mutex
+	idle   []*ldap.Conn
+	size   int
+	closed bool
+}
+
+func newPool(size int, dial func(ctx context.Context) (*ldap.Conn, error)) *pool {
+	return &pool{dial: dial, size: size}
+}
+
+// get returns an idle connection or dials a new one until the context is done
+func (p *pool) get(ctx context.Context) (conn *ldap.Conn, pooled bool, err error) {
+	p.mu.Lock()
+	for len(p.idle) > 0 {
+		conn = p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+
+		// connections closed by the server are dropped
+		if !conn.IsClosing() {
+			p.mu.Unlock()
+			return conn, true, nil
+		}
+	}
+	p.mu.Unlock()
+
+	conn, err = p.dial(ctx)
+	return conn, false, err
+}
+
+// put returns a connection to the pool, it is closed if the pool is full or closed
+func (p *pool) put(conn *ldap.Conn) {
+	if conn.IsClosing() {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed || len(p.idle) >= p.size {
+		conn.Close()
+		return
+	}
+	p.idle = append(p.idle, conn)
+}
+
+// close closes all idle connections, connections put back later are closed as well
+func (p *pool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, conn := range p.idle {
+		conn.Close()
+	}
+	p.idle = nil
+	p.closed = true
+}
@@ -0,0 +1,241 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/square/go-jose.v2"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keySet provides the public keys of the issuer by key id
+type keySet interface {
+	// keys returns the keys with the given id, an empty id returns all keys
+	keys(ctx context.Context, id string) ([]jose.JSONWebKey, error)
+}
+
+// remoteKeySet fetches the JWKS from a URL and caches it. Concurrent refreshes share one fetch which runs
+// outside the lock, after a failed fetch the previous keys are served until the next attempt after minRefresh
+type remoteKeySet struct {
+	// discover returns the JWKS URL, it is called once before the first fetch
+	discover   func(ctx context.Context) (string, error)
+	client     *http.Client
+	ttl        time.Duration
+	minRefresh time.Duration
+	now        func() time.Time
+
+	mu         sync.Mutex
+	url        string
+	set        jose.JSONWebKeySet
+	fetched    time.Time
+	attempted  time.Time
+	err        error
+	refreshing *refresh
+}
+
+// refresh is a fetch of the JWKS shared by all concurrent callers
+type refresh struct {
+	done chan struct{}
+	err  error
+}
+
+func (r *remoteKeySet) keys(ctx context.Context, id string) ([]jose.JSONWebKey, error) {
+	r.mu.Lock()
+	expired := r.fetched.IsZero() || r.now().Sub(r.fetched) >= r.ttl
+	r.mu.Unlock()
+
+	if expired {
+		if err := r.refresh(ctx); err != nil && !r.cached() {
+			return nil, err
+		}
+	}
+
+	keys := find(r.current(), id)
+
+	// the issuer might have rotated its keys, refetch at most once per minRefresh
+	if len(keys) == 0 {
+		if err := r.refresh(ctx); err != nil {
+			return nil, err
+		}
+		keys = find(r.current(), id)
+	}
+
+	return keys, nil
+}
+
+// current returns the cached JWKS
+func (r *remoteKeySet) current() jose.JSONWebKeySet {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.set
+}
+
+// cached reports whether the JWKS has been fetched successfully before
+func (r *remoteKeySet) cached() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return !r.fetched.IsZero()
+}
+
+// refresh fetches the JWKS or joins a running fetch, it returns the error of the last fetch
+// if the previous attempt was less than minRefresh ago
+func (r *remoteKeySet) refresh(ctx context.Context) error {
+	r.mu.Lock()
+	f := r.refreshing
+	if f == nil {
+		if !r.attempted.IsZero() && r.now().Sub(r.attempted) < r.minRefresh {
+			err := r.err
+			r.mu.Unlock()
+			return err
+		}
+		f = &refresh{done: make(chan struct{})}
+		r.refreshing = f
+		go r.fetch(f, r.url)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// fetch runs a shared refresh, it is not canceled with the context of a caller as all callers share its result
+func (r *remoteKeySet) fetch(f *refresh, url string) {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+
+	var set jose.JSONWebKeySet
+	if url == "" {
+		url, f.err = r.discover(ctx)
+	}
+	if f.err == nil {
+		if err := getJSON(ctx, r.client, url, &set); err != nil {
+			f.err = fmt.Errorf("failed to fetch JWKS: %v", err)
+		}
+	}
+
+	r.mu.Lock()
+	now := r.now()
+	r.attempted, r.err = now, f.err
+	if f.err == nil {
+		r.url, r.set, r.fetched = url, set, now
+	}
+	r.refreshing = nil
+	r.mu.Unlock()
+
+	close(f.done)
+}
+
+// fileKeySet holds a JWKS read from a file, it is replaced on reloads
+type fileKeySet struct {
+	mu  sync.RWMutex
+	set jose.JSONWebKeySet
+}
+
+func (f *fileKeySet) keys(ctx context.Context, id string) ([]jose.JSONWebKey, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return find(f.set, id), nil
+}
+
+func (f *fileKeySet) store(set jose.JSONWebKeySet) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.set = set
+}
+
+// LoadJWKS reads a JWKS from a file
+func LoadJWKS(path string) (jose.JSONWebKeySet, error) {
+	var set jose.JSONWebKeySet
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return set, fmt.Errorf("failed to read JWKS file: %v", err)
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return set, fmt.Errorf("failed to parse JWKS file: %v", err)
+	}
+	return set, nil
+}
+
+// find returns the public keys with the given id, an empty id returns all keys
+func find(set jose.JSONWebKeySet, id string) []jose.JSONWebKey {
+	var keys []jose.JSONWebKey
+	for _, key := range set.Keys {
+		if id != "" && key.KeyID != id || key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		// symmetric keys have no public part and are never accepted
+		if public := key.Public(); public.Key != nil {
+			keys = append(keys, public)
+		}
+	}
+	return keys
+}
+
+// discovery represents the parts of the OpenID provider metadata used to find the JWKS
+type discovery struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+// discoverJWKS returns the JWKS URL from the OpenID provider metadata of the issuer
+func discoverJWKS(ctx context.Context, client *http.Client, issuer string) (string, error) {
+	var d discovery
+	if err := getJSON(ctx, client, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", &d); err != nil {
+		return "", fmt.Errorf("failed to discover OpenID provider: %v", err)
+	}
+	if d.Issuer != issuer {
+		return "", fmt.Errorf("discovered issuer %q does not match %q", d.Issuer, issuer)
+	}
+	if d.JWKSURI == "" {
+		return "", fmt.Errorf("OpenID provider %s does not publish a JWKS URI", issuer)
+	}
+	return d.JWKSURI, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status %s", url, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
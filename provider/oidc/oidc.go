@@ -0,0 +1,357 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package oidc implements a provider authenticating ID tokens issued by an external OpenID provider.
+package oidc
+
+import (
+	"context"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/internal/watch"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/square/go-jose.v2"
+	josejwt "gopkg.in/square/go-jose.v2/jwt"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultUsernameClaim is the claim used as username if none is configured
+	DefaultUsernameClaim = "sub"
+	// DefaultCacheTTL is the time a fetched JWKS is cached if none is configured
+	DefaultCacheTTL = time.Hour
+	// DefaultClockSkew is the clock skew tolerated when validating tokens if none is configured
+	DefaultClockSkew = time.Minute
+
+	// minRefreshInterval limits refetching the JWKS for tokens with unknown key ids and after failed fetches
+	minRefreshInterval = 10 * time.Second
+	// fetchTimeout bounds a JWKS fetch, which is shared by all callers and not canceled with their contexts
+	fetchTimeout = 30 * time.Second
+)
+
+// Config represents the OIDC provider configuration
+type Config struct {
+	// IssuerURL must match the iss claim of tokens, it is used to discover the JWKS URL
+	IssuerURL string
+	// Audiences of which at least one must be contained in the aud claim of tokens, usually the client id.
+	// At least one audience is required, otherwise ID tokens issued to any client would be accepted
+	Audiences []string
+
+	// JWKSURL overrides the JWKS URL discovered from the issuer (optional)
+	JWKSURL string
+	// JWKSFile is a local JWKS file used instead of fetching the JWKS (optional)
+	JWKSFile string
+	// CacheTTL is the time a fetched JWKS is cached (default: 1h)
+	CacheTTL time.Duration
+	// ReloadInterval is the interval the JWKS file is checked for changes, 0 disables reloading
+	ReloadInterval time.Duration
+	// HTTPClient is used to fetch the JWKS (default: http.Client with a 10s timeout)
+	HTTPClient *http.Client
+
+	// SigningAlgorithms accepted for tokens (default: RS256)
+	SigningAlgorithms []string
+	// ClockSkew tolerated when validating the expiry of tokens (default: 1m)
+	ClockSkew time.Duration
+
+	// UsernameClaim is the claim used as username (default: sub)
+	UsernameClaim string
+	// UsernamePrefix is prepended to usernames. If empty, usernames other than email are prefixed
+	// with the issuer URL and #, the value - disables prefixing (like kube-apiserver --oidc-username-prefix)
+	UsernamePrefix string
+	// UIDClaim is the claim used as uid (optional)
+	UIDClaim string
+	// GroupsClaim is the claim holding a string or a list of groups (optional)
+	GroupsClaim string
+	// GroupsPrefix is prepended to groups (optional)
+	GroupsPrefix string
+	// ExtraClaims maps extra keys to the claims providing their values (optional)
+	ExtraClaims map[string]string
+
+	// Logger is used to report failed reloads (optional)
+	Logger log.Logger
+}
+
+// Provider implements provider.ContextProvider validating JWTs of an OpenID provider
+type Provider struct {
+	config Config
+	keys   keySet
+	stop   func()
+	now    func() time.Time
+}
+
+// NewProvider returns a new OIDC provider for the config
+func NewProvider(cfg Config) (*Provider, error) {
+	if cfg.IssuerURL == "" {
+		return nil, fmt.Errorf("oidc provider requires an issuer URL")
+	}
+	if len(cfg.Audiences) == 0 {
+		return nil, fmt.Errorf("oidc provider requires at least one audience")
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.NewNopLogger()
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = DefaultCacheTTL
+	}
+	if cfg.ClockSkew <= 0 {
+		cfg.ClockSkew = DefaultClockSkew
+	}
+	if len(cfg.SigningAlgorithms) == 0 {
+		cfg.SigningAlgorithms = []string{string(jose.RS256)}
+	}
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = DefaultUsernameClaim
+	}
+
+	switch cfg.UsernamePrefix {
+	case "-":
+		cfg.UsernamePrefix = ""
+	case "":
+		if cfg.UsernameClaim != "email" {
+			cfg.UsernamePrefix = cfg.IssuerURL + "#"
+		}
+	}
+
+	p := &Provider{
+		config: cfg,
+		stop:   func() {},
+		now:    time.Now,
+	}
+
+	if cfg.JWKSFile != "" {
+		set, err := LoadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+
+		keys := &fileKeySet{set: set}
+		if cfg.ReloadInterval > 0 {
+			p.stop = watch.Poll(cfg.JWKSFile, cfg.ReloadInterval, func() {
+				set, err := LoadJWKS(cfg.JWKSFile)
+				if err != nil {
+					level.Error(cfg.Logger).Log("msg", "failed to reload JWKS file, keeping previous keys", "path", cfg.JWKSFile, "err", err)
+					return
+				}
+				keys.store(set)
+				level.Info(cfg.Logger).Log("msg", "reloaded JWKS file", "path", cfg.JWKSFile, "keys", len(set.Keys))
+			})
+		}
+		p.keys = keys
+	} else {
+		p.keys = &remoteKeySet{
+			discover: func(ctx context.Context) (string, error) {
+				if cfg.JWKSURL != "" {
+					return cfg.JWKSURL, nil
+				}
+				return discoverJWKS(ctx, cfg.HTTPClient, cfg.IssuerURL)
+			},
+			client:     cfg.HTTPClient,
+			ttl:        cfg.CacheTTL,
+			minRefresh: minRefreshInterval,
+			now:        p.now,
+		}
+	}
+
+	return p, nil
+}
+
+// Login is not supported, ID tokens are obtained from the OpenID provider
+func (p *Provider) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	return provider.Unauthenticated(), nil
+}
+
+// Authenticate validates the ID token and maps its claims to the user
+func (p *Provider) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	tok, err := josejwt.ParseSigned(req.Token)
+	if err != nil || len(tok.Headers) != 1 {
+		return provider.Unauthenticated(), nil
+	}
+
+	header := tok.Headers[0]
+	if !contains(p.config.SigningAlgorithms, header.Algorithm) {
+		return provider.Unauthenticated(), nil
+	}
+
+	keys, err := p.keys.keys(ctx, header.KeyID)
+	if err != nil {
+		level.Warn(p.config.Logger).Log("msg", "failed to get keys of OpenID provider", "err", err)
+		return nil, errors.NewServiceUnavailable(err)
+	}
+
+	var (
+		claims josejwt.Claims
+		raw    map[string]interface{}
+		valid  bool
+	)
+	for _, key := range keys {
+		if key.Algorithm != "" && key.Algorithm != header.Algorithm {
+			continue
+		}
+		if err := tok.Claims(key.Key, &claims, &raw); err == nil {
+			valid = true
+			break
+		}
+	}
+	if !valid || claims.Expiry == nil {
+		return provider.Unauthenticated(), nil
+	}
+
+	expected := josejwt.Expected{Issuer: p.config.IssuerURL, Time: p.now()}
+	if err := claims.ValidateWithLeeway(expected, p.config.ClockSkew); err != nil {
+		return provider.Unauthenticated(), nil
+	}
+	if !containsAny(claims.Audience, p.config.Audiences) {
+		return provider.Unauthenticated(), nil
+	}
+
+	user, err := p.user(raw)
+	if err != nil {
+		level.Debug(p.config.Logger).Log("msg", "rejected ID token", "err", err)
+		return provider.Unauthenticated(), nil
+	}
+
+	return &models.TokenReviewRequest{
+		APIVersion: "authentication.k8s.io/v1beta1",
+		Kind:       "TokenReview",
+		Status: &models.TokenReviewStatus{
+			Authenticated: true,
+			User:          user,
+			Audiences:     claims.Audience,
+			Expiration:    claims.Expiry.Time().Unix(),
+		},
+	}, nil
+}
+
+// Close stops reloading the JWKS file
+func (p *Provider) Close() {
+	p.stop()
+}
+
+// user maps the claims of a token to the user
+func (p *Provider) user(claims map[string]interface{}) (*models.UserInfo, error) {
+	username, ok := claims[p.config.UsernameClaim].(string)
+	if !ok || username == "" {
+		return nil, fmt.Errorf("claim %s is missing or not a string", p.config.UsernameClaim)
+	}
+
+	// like the apiserver, only accept verified email addresses
+	if p.config.UsernameClaim == "email" {
+		if verified, ok := claims["email_verified"]; ok && verified != true {
+			return nil, fmt.Errorf("email %s is not verified", username)
+		}
+	}
+
+	user := &models.UserInfo{
+		Username: p.config.UsernamePrefix + username,
+		Groups:   []string{},
+	}
+
+	if p.config.UIDClaim != "" {
+		uid, err := stringClaim(claims, p.config.UIDClaim)
+		if err != nil {
+			return nil, err
+		}
+		user.UID = uid
+	}
+
+	if p.config.GroupsClaim != "" {
+		groups, err := listClaim(claims, p.config.GroupsClaim)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			user.Groups = append(user.Groups, p.config.GroupsPrefix+group)
+		}
+	}
+
+	if len(p.config.ExtraClaims) > 0 {
+		extra := map[string][]string{}
+		for key, claim := range p.config.ExtraClaims {
+			values, err := listClaim(claims, claim)
+			if err != nil {
+				return nil, err
+			}
+			if len(values) > 0 {
+				extra[key] = values
+			}
+		}
+		if len(extra) > 0 {
+			user.Extra = extra
+		}
+	}
+
+	return user, nil
+}
+
+// stringClaim returns a string claim, missing claims are empty
+func stringClaim(claims map[string]interface{}, name string) (string, error) {
+	switch v := claims[name].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	default:
+		return "", fmt.Errorf("claim %s is not a string", name)
+	}
+}
+
+// listClaim returns a claim holding a string or a list of strings as list, missing claims are empty
+func listClaim(claims map[string]interface{}, name string) ([]string, error) {
+	switch v := claims[name].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, value := range v {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("claim %s is not a list of strings", name)
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("claim %s is neither a string nor a list of strings", name)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAny returns true if a contains at least one element of b
+func containsAny(a, b []string) bool {
+	for _, x := range a {
+		if contains(b, x) {
+			return true
+		}
+	}
+	return false
+}
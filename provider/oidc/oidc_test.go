@@ -0,0 +1,340 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"github.com/cbrgm/authproxy/provider"
+	"gopkg.in/square/go-jose.v2"
+	josejwt "gopkg.in/square/go-jose.v2/jwt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testIssuer struct {
+	server *httptest.Server
+
+	mu      sync.Mutex
+	keys    []jose.JSONWebKey
+	fetches int
+	fail    bool
+	delay   time.Duration
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	ti := &testIssuer{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   ti.server.URL,
+			"jwks_uri": ti.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		ti.mu.Lock()
+		defer ti.mu.Unlock()
+
+		ti.fetches++
+		time.Sleep(ti.delay)
+		if ti.fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		set := jose.JSONWebKeySet{}
+		for _, key := range ti.keys {
+			set.Keys = append(set.Keys, key.Public())
+		}
+		json.NewEncoder(w).Encode(set)
+	})
+	ti.server = httptest.NewServer(mux)
+
+	return ti
+}
+
+// addKey generates a new signing key and publishes it
+func (ti *testIssuer) addKey(t *testing.T, id string) jose.JSONWebKey {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := jose.JSONWebKey{Key: priv, KeyID: id, Algorithm: string(jose.RS256), Use: "sig"}
+
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	ti.keys = append(ti.keys, key)
+
+	return key
+}
+
+func sign(t *testing.T, key jose.JSONWebKey, claims ...interface{}) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := josejwt.Signed(signer)
+	for _, c := range claims {
+		builder = builder.Claims(c)
+	}
+	token, err := builder.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestAuthenticate(t *testing.T) {
+	ti := newTestIssuer(t)
+	defer ti.server.Close()
+
+	key := ti.addKey(t, "1")
+
+	p, err := NewProvider(Config{
+		IssuerURL:     ti.server.URL,
+		Audiences:     []string{"authproxy"},
+		UsernameClaim: "email",
+		UIDClaim:      "sub",
+		GroupsClaim:   "groups",
+		GroupsPrefix:  "oidc:",
+		ExtraClaims:   map[string]string{"example.com/tenant": "tenant"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	now := time.Now()
+	standard := josejwt.Claims{
+		Issuer:   ti.server.URL,
+		Subject:  "1234",
+		Audience: josejwt.Audience{"authproxy"},
+		Expiry:   josejwt.NewNumericDate(now.Add(time.Hour)),
+	}
+	custom := map[string]interface{}{
+		"email":          "jane@example.com",
+		"email_verified": true,
+		"groups":         []string{"developers", "admins"},
+		"tenant":         "acme",
+	}
+
+	trr, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: sign(t, key, standard, custom)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := trr.Status.User
+	if !trr.Status.Authenticated || user.Username != "jane@example.com" || user.UID != "1234" {
+		t.Fatalf("unexpected authentication result %+v", trr.Status)
+	}
+	if !reflect.DeepEqual(user.Groups, []string{"oidc:developers", "oidc:admins"}) {
+		t.Errorf("unexpected groups %v", user.Groups)
+	}
+	if !reflect.DeepEqual(user.Extra, map[string][]string{"example.com/tenant": {"acme"}}) {
+		t.Errorf("unexpected extra %v", user.Extra)
+	}
+	if !reflect.DeepEqual(trr.Status.Audiences, []string{"authproxy"}) {
+		t.Errorf("expected audiences of the token, got %v", trr.Status.Audiences)
+	}
+
+	invalid := map[string]josejwt.Claims{}
+	for name, modify := range map[string]func(c *josejwt.Claims){
+		"other issuer":   func(c *josejwt.Claims) { c.Issuer = "https://example.com" },
+		"other audience": func(c *josejwt.Claims) { c.Audience = josejwt.Audience{"other"} },
+		"expired":        func(c *josejwt.Claims) { c.Expiry = josejwt.NewNumericDate(now.Add(-time.Hour)) },
+		"no expiry":      func(c *josejwt.Claims) { c.Expiry = nil },
+	} {
+		c := standard
+		modify(&c)
+		invalid[name] = c
+	}
+	for name, c := range invalid {
+		if trr, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: sign(t, key, c, custom)}); err != nil || trr.Status.Authenticated {
+			t.Errorf("expected token with %s not to be authenticated, got %v", name, err)
+		}
+	}
+
+	unverified := map[string]interface{}{"email": "jane@example.com", "email_verified": false}
+	if trr, _ := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: sign(t, key, standard, unverified)}); trr.Status.Authenticated {
+		t.Error("expected token with unverified email not to be authenticated")
+	}
+
+	// tokens signed by unpublished keys are rejected
+	foreign := jose.JSONWebKey{Key: key.Key, KeyID: "unknown"}
+	if trr, _ := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: sign(t, foreign, standard, custom)}); trr.Status.Authenticated {
+		t.Error("expected token of unknown key not to be authenticated")
+	}
+}
+
+func TestAuthenticateKeyRotation(t *testing.T) {
+	ti := newTestIssuer(t)
+	defer ti.server.Close()
+
+	key := ti.addKey(t, "1")
+
+	p, err := NewProvider(Config{IssuerURL: ti.server.URL, Audiences: []string{"authproxy"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	claims := josejwt.Claims{
+		Issuer:   ti.server.URL,
+		Subject:  "1234",
+		Audience: josejwt.Audience{"authproxy"},
+		Expiry:   josejwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+
+	for i := 0; i < 3; i++ {
+		trr, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: sign(t, key, claims)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !trr.Status.Authenticated || trr.Status.User.Username != ti.server.URL+"#1234" {
+			t.Fatalf("unexpected authentication result %+v", trr.Status)
+		}
+	}
+	if ti.fetches != 1 {
+		t.Errorf("expected the JWKS to be cached, fetched %d times", ti.fetches)
+	}
+
+	// a new key of the issuer is fetched on first use
+	rotated := ti.addKey(t, "2")
+	p.keys.(*remoteKeySet).minRefresh = 0
+
+	if trr, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: sign(t, rotated, claims)}); err != nil || !trr.Status.Authenticated {
+		t.Errorf("expected token of rotated key to be authenticated, got %v", err)
+	}
+}
+
+func TestAuthenticateJWKSFile(t *testing.T) {
+	ti := newTestIssuer(t)
+	ti.server.Close()
+
+	key := ti.addKey(t, "1")
+
+	dir, err := ioutil.TempDir("", "oidc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key.Public()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "jwks.json")
+	if err := ioutil.WriteFile(path, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewProvider(Config{IssuerURL: "https://issuer.example.com", Audiences: []string{"authproxy"}, JWKSFile: path, UsernamePrefix: "-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	claims := josejwt.Claims{
+		Issuer:   "https://issuer.example.com",
+		Subject:  "1234",
+		Audience: josejwt.Audience{"authproxy"},
+		Expiry:   josejwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+	trr, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: sign(t, key, claims)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !trr.Status.Authenticated || trr.Status.User.Username != "1234" {
+		t.Errorf("unexpected authentication result %+v", trr.Status)
+	}
+}
+
+func TestRequiresAudience(t *testing.T) {
+	if _, err := NewProvider(Config{IssuerURL: "https://issuer.example.com"}); err == nil {
+		t.Error("expected provider without audiences to be refused")
+	}
+}
+
+func TestRemoteKeySetRefresh(t *testing.T) {
+	ti := newTestIssuer(t)
+	defer ti.server.Close()
+
+	key := ti.addKey(t, "1")
+
+	now := time.Unix(1500000000, 0)
+	r := &remoteKeySet{
+		discover:   func(ctx context.Context) (string, error) { return ti.server.URL + "/keys", nil },
+		client:     ti.server.Client(),
+		ttl:        time.Hour,
+		minRefresh: 10 * time.Second,
+		now:        func() time.Time { return now },
+	}
+
+	// concurrent callers share one fetch
+	ti.delay = 100 * time.Millisecond
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if keys, err := r.keys(context.Background(), key.KeyID); err != nil || len(keys) != 1 {
+				t.Errorf("expected key, got %d keys: %v", len(keys), err)
+			}
+		}()
+	}
+	wg.Wait()
+	ti.delay = 0
+	if ti.fetches != 1 {
+		t.Errorf("expected one shared fetch, got %d", ti.fetches)
+	}
+
+	// stale keys are served if a refresh fails
+	ti.fail = true
+	now = now.Add(2 * time.Hour)
+	if keys, err := r.keys(context.Background(), key.KeyID); err != nil || len(keys) != 1 {
+		t.Errorf("expected stale key, got %d keys: %v", len(keys), err)
+	}
+
+	// failed fetches are not retried before minRefresh passed
+	if _, err := r.keys(context.Background(), "unknown"); err == nil {
+		t.Error("expected error of failed fetch for unknown key")
+	}
+	if ti.fetches != 2 {
+		t.Errorf("expected no fetch during backoff, got %d fetches", ti.fetches)
+	}
+
+	ti.fail = false
+	now = now.Add(10 * time.Second)
+	if _, err := r.keys(context.Background(), key.KeyID); err != nil {
+		t.Error(err)
+	}
+	if ti.fetches != 3 {
+		t.Errorf("expected fetch after backoff, got %d fetches", ti.fetches)
+	}
+}
+
+func TestRemoteKeySetCanceled(t *testing.T) {
+	ti := newTestIssuer(t)
+	defer ti.server.Close()
+
+	ti.delay = time.Second
+	r := &remoteKeySet{
+		discover:   func(ctx context.Context) (string, error) { return ti.server.URL + "/keys", nil },
+		client:     ti.server.Client(),
+		ttl:        time.Hour,
+		minRefresh: 10 * time.Second,
+		now:        time.Now,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := r.keys(ctx, ""); err != context.DeadlineExceeded {
+		t.Errorf("expected caller to give up on the fetch, got %v", err)
+	}
+}
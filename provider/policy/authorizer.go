@@ -0,0 +1,101 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package policy
+
+import (
+	"context"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/internal/watch"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"sync"
+	"time"
+)
+
+// Config represents the policy authorizer configuration
+type Config struct {
+	// Path of the YAML or JSON policy file
+	Path string
+	// ReloadInterval is the interval the policy file is checked for changes, 0 disables reloading
+	ReloadInterval time.Duration
+	// Logger is used to report failed reloads (optional)
+	Logger log.Logger
+}
+
+// Authorizer implements provider.Authorizer using a policy file
+type Authorizer struct {
+	config Config
+	stop   func()
+
+	mu     sync.RWMutex
+	policy *Policy
+}
+
+// NewAuthorizer returns a new authorizer for the policy file of the config.
+// The policy file is reloaded on changes until Close is called.
+func NewAuthorizer(cfg Config) (*Authorizer, error) {
+	p, err := LoadFile(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Logger == nil {
+		cfg.Logger = log.NewNopLogger()
+	}
+
+	a := &Authorizer{
+		config: cfg,
+		policy: p,
+		stop:   func() {},
+	}
+
+	if cfg.ReloadInterval > 0 {
+		a.stop = watch.Poll(cfg.Path, cfg.ReloadInterval, a.reload)
+	}
+
+	return a, nil
+}
+
+// Authorize implements provider.Authorizer
+func (a *Authorizer) Authorize(ctx context.Context, req provider.AuthorizeRequest) (*models.SubjectAccessReviewStatus, error) {
+	a.mu.RLock()
+	p := a.policy
+	a.mu.RUnlock()
+
+	return p.Authorize(req.Spec), nil
+}
+
+// Close stops reloading the policy file
+func (a *Authorizer) Close() {
+	a.stop()
+}
+
+// reload replaces the policy by the current policy file, the old policy is kept on errors
+func (a *Authorizer) reload() {
+	p, err := LoadFile(a.config.Path)
+	if err != nil {
+		level.Error(a.config.Logger).Log("msg", "failed to reload policy, keeping previous policy", "path", a.config.Path, "err", err)
+		return
+	}
+
+	a.mu.Lock()
+	a.policy = p
+	a.mu.Unlock()
+
+	level.Info(a.config.Logger).Log("msg", "reloaded policy", "path", a.config.Path, "rules", len(p.Rules))
+}
@@ -0,0 +1,198 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"strings"
+)
+
+const (
+	// EffectAllow allows requests matching a rule
+	EffectAllow = "allow"
+	// EffectDeny denies requests matching a rule
+	EffectDeny = "deny"
+
+	// wildcard matches every value
+	wildcard = "*"
+)
+
+// Policy represents a list of rules, the first rule matching a request decides about it
+type Policy struct {
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// Rule represents a single policy rule.
+// A rule matches a request if the user or one of the groups matches and all attributes of the request match.
+// A rule applies either to resource requests (resources) or to non resource requests (nonResourceURLs).
+type Rule struct {
+	// Name identifies the rule in decisions
+	Name string `json:"name" yaml:"name"`
+	// Effect is either allow or deny (default: allow)
+	Effect string `json:"effect" yaml:"effect"`
+
+	Users  []string `json:"users" yaml:"users"`
+	Groups []string `json:"groups" yaml:"groups"`
+	Verbs  []string `json:"verbs" yaml:"verbs"`
+
+	// APIGroups, Resources and Namespaces match resource requests.
+	// Resources may be given as resource/subresource. Empty namespaces match all namespaces
+	APIGroups  []string `json:"apiGroups" yaml:"apiGroups"`
+	Resources  []string `json:"resources" yaml:"resources"`
+	Namespaces []string `json:"namespaces" yaml:"namespaces"`
+
+	// NonResourceURLs match non resource requests, a trailing * matches every path with the given prefix
+	NonResourceURLs []string `json:"nonResourceURLs" yaml:"nonResourceURLs"`
+}
+
+// LoadFile reads and validates a policy from a YAML or JSON file
+func LoadFile(path string) (*Policy, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %v", err)
+	}
+	return Parse(b)
+}
+
+// Parse parses and validates a policy in YAML or JSON format
+func Parse(b []byte) (*Policy, error) {
+	var p Policy
+	if err := yaml.UnmarshalStrict(b, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %v", err)
+	}
+
+	for i := range p.Rules {
+		if err := p.Rules[i].validate(); err != nil {
+			return nil, fmt.Errorf("invalid rule %d (%s): %v", i, p.Rules[i].Name, err)
+		}
+	}
+
+	return &p, nil
+}
+
+func (r *Rule) validate() error {
+	switch r.Effect {
+	case "":
+		r.Effect = EffectAllow
+	case EffectAllow, EffectDeny:
+	default:
+		return fmt.Errorf("unknown effect %q", r.Effect)
+	}
+
+	if len(r.Users) == 0 && len(r.Groups) == 0 {
+		return errors.New("at least one user or group is required")
+	}
+	if len(r.Verbs) == 0 {
+		return errors.New("at least one verb is required")
+	}
+	if (len(r.Resources) == 0) == (len(r.NonResourceURLs) == 0) {
+		return errors.New("exactly one of resources and nonResourceURLs is required")
+	}
+	if len(r.Resources) > 0 && len(r.APIGroups) == 0 {
+		return errors.New("apiGroups are required for resources, use \"\" for the core group")
+	}
+	return nil
+}
+
+// Authorize returns the decision of the first rule matching the request.
+// Without a matching rule, the returned status has no opinion.
+func (p *Policy) Authorize(spec *models.SubjectAccessReviewSpec) *models.SubjectAccessReviewStatus {
+	for i, r := range p.Rules {
+		if !r.matches(spec) {
+			continue
+		}
+
+		status := &models.SubjectAccessReviewStatus{
+			Reason: fmt.Sprintf("rule %d (%s) matched with effect %s", i, r.Name, r.Effect),
+		}
+		if r.Effect == EffectDeny {
+			status.Denied = true
+		} else {
+			status.Allowed = true
+		}
+		return status
+	}
+
+	return &models.SubjectAccessReviewStatus{
+		Reason: "no policy rule matched",
+	}
+}
+
+func (r *Rule) matches(spec *models.SubjectAccessReviewSpec) bool {
+	if !r.matchesSubject(spec) {
+		return false
+	}
+
+	if ra := spec.ResourceAttributes; ra != nil {
+		if len(r.Resources) == 0 {
+			return false
+		}
+		resource := ra.Resource
+		if ra.Subresource != "" {
+			resource = resource + "/" + ra.Subresource
+		}
+		return contains(r.Verbs, ra.Verb) &&
+			contains(r.APIGroups, ra.Group) &&
+			contains(r.Resources, resource) &&
+			(len(r.Namespaces) == 0 || contains(r.Namespaces, ra.Namespace))
+	}
+
+	if nra := spec.NonResourceAttributes; nra != nil {
+		return contains(r.Verbs, nra.Verb) && matchesPath(r.NonResourceURLs, nra.Path)
+	}
+
+	return false
+}
+
+func (r *Rule) matchesSubject(spec *models.SubjectAccessReviewSpec) bool {
+	if contains(r.Users, spec.User) {
+		return true
+	}
+	for _, g := range spec.Groups {
+		if contains(r.Groups, g) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains checks whether values contains v or the wildcard
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v || x == wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPath checks whether one of the urls matches path, urls ending with * match by prefix
+func matchesPath(urls []string, path string) bool {
+	for _, u := range urls {
+		if u == path || u == wildcard {
+			return true
+		}
+		if strings.HasSuffix(u, wildcard) && strings.HasPrefix(path, strings.TrimSuffix(u, wildcard)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package policy
+
+import (
+	"context"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testPolicy = `
+rules:
+  - name: deny-secrets
+    effect: deny
+    groups: ["developers"]
+    verbs: ["*"]
+    apiGroups: [""]
+    resources: ["secrets"]
+  - name: developers-read
+    groups: ["developers"]
+    verbs: ["get", "list", "watch"]
+    apiGroups: ["", "apps"]
+    resources: ["pods", "pods/log", "deployments"]
+    namespaces: ["default"]
+  - name: healthz
+    users: ["*"]
+    verbs: ["get"]
+    nonResourceURLs: ["/healthz", "/api/*"]
+`
+
+func TestPolicyAuthorize(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dev := func(ra *models.ResourceAttributes) *models.SubjectAccessReviewSpec {
+		return &models.SubjectAccessReviewSpec{User: "foo", Groups: []string{"developers"}, ResourceAttributes: ra}
+	}
+
+	tests := []struct {
+		name    string
+		spec    *models.SubjectAccessReviewSpec
+		allowed bool
+		denied  bool
+	}{
+		{name: "read pods", spec: dev(&models.ResourceAttributes{Verb: "get", Resource: "pods", Namespace: "default"}), allowed: true},
+		{name: "read pod logs", spec: dev(&models.ResourceAttributes{Verb: "get", Resource: "pods", Subresource: "log", Namespace: "default"}), allowed: true},
+		{name: "exec into pods", spec: dev(&models.ResourceAttributes{Verb: "create", Resource: "pods", Subresource: "exec", Namespace: "default"})},
+		{name: "read deployments", spec: dev(&models.ResourceAttributes{Verb: "list", Group: "apps", Resource: "deployments", Namespace: "default"}), allowed: true},
+		{name: "other namespace", spec: dev(&models.ResourceAttributes{Verb: "get", Resource: "pods", Namespace: "kube-system"})},
+		{name: "read secrets", spec: dev(&models.ResourceAttributes{Verb: "get", Resource: "secrets", Namespace: "default"}), denied: true},
+		{name: "healthz", spec: &models.SubjectAccessReviewSpec{User: "bar", NonResourceAttributes: &models.NonResourceAttributes{Verb: "get", Path: "/healthz"}}, allowed: true},
+		{name: "api prefix", spec: &models.SubjectAccessReviewSpec{User: "bar", NonResourceAttributes: &models.NonResourceAttributes{Verb: "get", Path: "/api/v1"}}, allowed: true},
+		{name: "metrics", spec: &models.SubjectAccessReviewSpec{User: "bar", NonResourceAttributes: &models.NonResourceAttributes{Verb: "get", Path: "/metrics"}}},
+	}
+
+	for _, tt := range tests {
+		status := p.Authorize(tt.spec)
+		if status.Allowed != tt.allowed || status.Denied != tt.denied {
+			t.Errorf("%s: expected allowed=%v denied=%v, got allowed=%v denied=%v (%s)",
+				tt.name, tt.allowed, tt.denied, status.Allowed, status.Denied, status.Reason)
+		}
+	}
+}
+
+func TestParseInvalidPolicy(t *testing.T) {
+	invalid := []string{
+		`rules: [{name: a, verbs: ["get"], apiGroups: [""], resources: ["pods"]}]`,
+		`rules: [{name: a, users: ["foo"], apiGroups: [""], resources: ["pods"]}]`,
+		`rules: [{name: a, users: ["foo"], verbs: ["get"], resources: ["pods"]}]`,
+		`rules: [{name: a, users: ["foo"], verbs: ["get"], apiGroups: [""], resources: ["pods"], nonResourceURLs: ["/"]}]`,
+		`rules: [{name: a, users: ["foo"], verbs: ["get"], nonResourceURLs: ["/"], effect: maybe}]`,
+		`rulez: []`,
+	}
+
+	for _, p := range invalid {
+		if _, err := Parse([]byte(p)); err == nil {
+			t.Errorf("expected policy to be invalid: %s", p)
+		}
+	}
+}
+
+func TestAuthorizerReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "policy.json")
+	if err := ioutil.WriteFile(path, []byte(`{"rules": []}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewAuthorizer(Config{Path: path, ReloadInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	req := provider.AuthorizeRequest{
+		Spec: &models.SubjectAccessReviewSpec{User: "foo", NonResourceAttributes: &models.NonResourceAttributes{Verb: "get", Path: "/healthz"}},
+	}
+
+	status, _ := a.Authorize(context.Background(), req)
+	if status.Allowed {
+		t.Fatal("expected empty policy to have no opinion")
+	}
+
+	updated := `{"rules": [{"name": "healthz", "users": ["foo"], "verbs": ["get"], "nonResourceURLs": ["/healthz"]}]}`
+	if err := ioutil.WriteFile(path, []byte(updated), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if status, _ := a.Authorize(context.Background(), req); status.Allowed {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("expected policy to be reloaded")
+}
@@ -16,7 +16,11 @@
 
 package provider
 
-import "github.com/cbrgm/authproxy/api/v1/models"
+import (
+	"context"
+	"crypto/x509"
+	"github.com/cbrgm/authproxy/api/v1/models"
+)
 
 // Provider is an interface representing an identity provider.
 // The identity provider is responsible for issuing bearer tokens for clients (login) and offers authentication of the tokens (authenticate)
@@ -31,3 +35,96 @@ type Provider interface {
 	// In response, a TokenReviewRequest is sent to the client.
 	Authenticate(bearerToken string) (*models.TokenReviewRequest, error)
 }
+
+// ContextProvider is an identity provider which honours request cancellation and deadlines
+// and has access to metadata about the client request.
+type ContextProvider interface {
+
+	// Login issues bearer tokens for a client, see Provider.Login.
+	Login(ctx context.Context, req LoginRequest) (*models.TokenReviewRequest, error)
+
+	// Authenticate authenticates a client by a bearer token, see Provider.Authenticate.
+	Authenticate(ctx context.Context, req AuthenticateRequest) (*models.TokenReviewRequest, error)
+}
+
+// Authorizer is an optional interface an identity provider can implement to authorize actions of users.
+// It decides about the SubjectAccessReviews sent to authproxy
+type Authorizer interface {
+
+	// Authorize decides whether the user of the request may perform the requested action.
+	// Returning neither allowed nor denied lets other authorizers of the apiserver decide
+	Authorize(ctx context.Context, req AuthorizeRequest) (*models.SubjectAccessReviewStatus, error)
+}
+
+// RequestMetadata contains information about the client request
+type RequestMetadata struct {
+	// RequestID uniquely identifies the request
+	RequestID string
+	// ClientIP is the remote address of the client without the port
+	ClientIP string
+	// UserAgent is the user agent sent by the client
+	UserAgent string
+	// PeerCertificates holds the verified tls client certificates, if any
+	PeerCertificates []*x509.Certificate
+}
+
+// LoginRequest represents a login attempt of a client
+type LoginRequest struct {
+	Username string
+	Password string
+	Metadata RequestMetadata
+}
+
+// AuthenticateRequest represents a bearer token to be authenticated
+type AuthenticateRequest struct {
+	Token string
+	// Audiences the token is presented to, the provider should only
+	// authenticate the token for these audiences and return the matching ones in the status
+	Audiences []string
+	Metadata  RequestMetadata
+}
+
+// AuthorizeRequest represents an action of a user to be authorized
+type AuthorizeRequest struct {
+	Spec     *models.SubjectAccessReviewSpec
+	Metadata RequestMetadata
+}
+
+// Unauthenticated returns a TokenReview rejecting a token or login.
+// Providers return it without an error if the credentials are unknown or invalid.
+func Unauthenticated() *models.TokenReviewRequest {
+	return &models.TokenReviewRequest{
+		APIVersion: "authentication.k8s.io/v1beta1",
+		Kind:       "TokenReview",
+		Status: &models.TokenReviewStatus{
+			Authenticated: false,
+		},
+	}
+}
+
+// contextAdapter wraps a Provider to satisfy the ContextProvider interface
+type contextAdapter struct {
+	provider Provider
+}
+
+// NewContextAdapter returns a ContextProvider calling the given Provider.
+// The request metadata is dropped and the context is only checked before calling the provider.
+func NewContextAdapter(prv Provider) ContextProvider {
+	return &contextAdapter{provider: prv}
+}
+
+// Login calls the wrapped provider's login implementation
+func (a *contextAdapter) Login(ctx context.Context, req LoginRequest) (*models.TokenReviewRequest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.provider.Login(req.Username, req.Password)
+}
+
+// Authenticate calls the wrapped provider's authentication implementation
+func (a *contextAdapter) Authenticate(ctx context.Context, req AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.provider.Authenticate(req.Token)
+}
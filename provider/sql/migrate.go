@@ -0,0 +1,119 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Migration is a versioned change of the reference schema
+type Migration struct {
+	Version     int
+	Description string
+	Statements  []string
+}
+
+// Migrations create and evolve the reference schema, schema.sql holds the resulting schema.
+// The statements are kept to SQL understood by PostgreSQL, MySQL and SQLite, which rules out
+// CREATE INDEX IF NOT EXISTS, so the first migration requires a schema without the authproxy tables.
+var Migrations = []Migration{
+	{
+		Version:     1,
+		Description: "create users, group members and sessions",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS authproxy_users (
+	username VARCHAR(255) NOT NULL PRIMARY KEY,
+	uid VARCHAR(255) NOT NULL DEFAULT '',
+	password_hash VARCHAR(255) NOT NULL,
+	disabled BOOLEAN NOT NULL DEFAULT FALSE
+)`,
+			`CREATE TABLE IF NOT EXISTS authproxy_group_members (
+	group_name VARCHAR(255) NOT NULL,
+	username VARCHAR(255) NOT NULL,
+	PRIMARY KEY (group_name, username),
+	FOREIGN KEY (username) REFERENCES authproxy_users (username) ON DELETE CASCADE
+)`,
+			`CREATE INDEX authproxy_group_members_username ON authproxy_group_members (username)`,
+			`CREATE TABLE IF NOT EXISTS authproxy_sessions (
+	token_hash CHAR(64) NOT NULL PRIMARY KEY,
+	username VARCHAR(255) NOT NULL,
+	user_info TEXT NOT NULL,
+	expires_at BIGINT NOT NULL
+)`,
+			`CREATE INDEX authproxy_sessions_expires_at ON authproxy_sessions (expires_at)`,
+		},
+	},
+}
+
+// Migrate applies all migrations not yet recorded in the authproxy_schema_migrations table
+// and returns how many were applied. Each migration is applied in its own transaction,
+// databases with implicit commits of DDL statements like MySQL may need manual cleanup if one fails.
+// Without recorded migrations the schema must not contain the authproxy tables yet, a schema created
+// from schema.sql or by hand has to record its version in authproxy_schema_migrations.
+func Migrate(ctx context.Context, db *sql.DB) (int, error) {
+	if _, err := db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS authproxy_schema_migrations (version INTEGER NOT NULL PRIMARY KEY)"); err != nil {
+		return 0, fmt.Errorf("failed to create migrations table: %v", err)
+	}
+
+	var current int
+	if err := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM authproxy_schema_migrations").Scan(&current); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %v", err)
+	}
+
+	// the indexes of the first migration can't be created twice, refuse instead of failing halfway
+	if current == 0 {
+		if _, err := db.ExecContext(ctx, "SELECT 1 FROM authproxy_users WHERE 1 = 0"); err == nil {
+			return 0, fmt.Errorf("table authproxy_users exists but no migration is recorded, record the version of the existing schema in authproxy_schema_migrations")
+		}
+	}
+
+	applied := 0
+	for _, m := range Migrations {
+		if m.Version <= current {
+			continue
+		}
+		if err := migrate(ctx, db, m); err != nil {
+			return applied, fmt.Errorf("failed to apply migration %d (%s): %v", m.Version, m.Description, err)
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+// migrate applies a migration and records its version in a transaction
+func migrate(ctx context.Context, db *sql.DB, m Migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range m.Statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	// versions are integers, so formatting them avoids depending on the placeholder style of the driver
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO authproxy_schema_migrations (version) VALUES (%d)", m.Version)); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
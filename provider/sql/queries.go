@@ -0,0 +1,103 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package sql
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Queries are the statements the provider runs, they can be replaced to use an existing schema.
+// The default queries use the tables of the reference schema applied by Migrate.
+type Queries struct {
+	// User selects username, uid and password hash of an enabled user, the argument is the username.
+	// Password hashes may use any format supported by htpasswd files: bcrypt, SHA, APR1 or argon2id.
+	User string
+	// Groups selects the group names of a user, the argument is the username
+	Groups string
+	// InsertSession stores a session, the arguments are the token hash, the username,
+	// the JSON encoded user info and the expiry as unix time in seconds
+	InsertSession string
+	// SelectSession selects the JSON encoded user info of a session,
+	// the arguments are the token hash and the current unix time in seconds
+	SelectSession string
+	// DeleteSession deletes a session, the argument is the token hash
+	DeleteSession string
+	// DeleteExpiredSessions deletes all expired sessions, the argument is the current unix time in seconds
+	DeleteExpiredSessions string
+}
+
+// defaultQueries use ? placeholders, they are rewritten for drivers using numbered placeholders
+var defaultQueries = Queries{
+	User:                  "SELECT username, uid, password_hash FROM authproxy_users WHERE username = ? AND NOT disabled",
+	Groups:                "SELECT group_name FROM authproxy_group_members WHERE username = ? ORDER BY group_name",
+	InsertSession:         "INSERT INTO authproxy_sessions (token_hash, username, user_info, expires_at) VALUES (?, ?, ?, ?)",
+	SelectSession:         "SELECT user_info FROM authproxy_sessions WHERE token_hash = ? AND expires_at > ?",
+	DeleteSession:         "DELETE FROM authproxy_sessions WHERE token_hash = ?",
+	DeleteExpiredSessions: "DELETE FROM authproxy_sessions WHERE expires_at <= ?",
+}
+
+// DefaultQueries returns the queries for the reference schema in the placeholder style of the driver:
+// $1, $2, ... for postgres and pgx, ? for all others
+func DefaultQueries(driver string) Queries {
+	switch driver {
+	case "postgres", "pgx":
+		return Queries{
+			User:                  numbered(defaultQueries.User),
+			Groups:                numbered(defaultQueries.Groups),
+			InsertSession:         numbered(defaultQueries.InsertSession),
+			SelectSession:         numbered(defaultQueries.SelectSession),
+			DeleteSession:         numbered(defaultQueries.DeleteSession),
+			DeleteExpiredSessions: numbered(defaultQueries.DeleteExpiredSessions),
+		}
+	default:
+		return defaultQueries
+	}
+}
+
+// withDefaults returns the queries with unset queries replaced by the defaults
+func (q Queries) withDefaults(defaults Queries) Queries {
+	for _, f := range []struct{ query, def *string }{
+		{&q.User, &defaults.User},
+		{&q.Groups, &defaults.Groups},
+		{&q.InsertSession, &defaults.InsertSession},
+		{&q.SelectSession, &defaults.SelectSession},
+		{&q.DeleteSession, &defaults.DeleteSession},
+		{&q.DeleteExpiredSessions, &defaults.DeleteExpiredSessions},
+	} {
+		if *f.query == "" {
+			*f.query = *f.def
+		}
+	}
+	return q
+}
+
+// numbered replaces the ? placeholders of a query with $1, $2, ...
+// The default queries contain no string literals, so every ? is a placeholder.
+func numbered(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+		n++
+		b.WriteString("$" + strconv.Itoa(n))
+	}
+	return b.String()
+}
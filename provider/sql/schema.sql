@@ -0,0 +1,40 @@
+-- Reference schema of the authproxy SQL provider, as created by the migrations in migrate.go.
+-- It is understood by PostgreSQL, MySQL and SQLite. Run authproxy with --sql-migrate to create
+-- it, or create it yourself and record version 1 in authproxy_schema_migrations.
+
+CREATE TABLE IF NOT EXISTS authproxy_schema_migrations (
+	version INTEGER NOT NULL PRIMARY KEY
+);
+
+-- Users logging in with their password. password_hash may use any format supported by
+-- htpasswd files: bcrypt, SHA, APR1 or argon2id. Disabled users can neither log in
+-- nor use tokens issued before.
+CREATE TABLE IF NOT EXISTS authproxy_users (
+	username VARCHAR(255) NOT NULL PRIMARY KEY,
+	uid VARCHAR(255) NOT NULL DEFAULT '',
+	password_hash VARCHAR(255) NOT NULL,
+	disabled BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- Group memberships, resolved on every request.
+CREATE TABLE IF NOT EXISTS authproxy_group_members (
+	group_name VARCHAR(255) NOT NULL,
+	username VARCHAR(255) NOT NULL,
+	PRIMARY KEY (group_name, username),
+	FOREIGN KEY (username) REFERENCES authproxy_users (username) ON DELETE CASCADE
+);
+
+CREATE INDEX authproxy_group_members_username ON authproxy_group_members (username);
+
+-- Sessions of issued bearer tokens. Only SHA-256 hashes of tokens are stored, user_info holds
+-- the JSON encoded user and expires_at the expiry as unix time in seconds.
+CREATE TABLE IF NOT EXISTS authproxy_sessions (
+	token_hash CHAR(64) NOT NULL PRIMARY KEY,
+	username VARCHAR(255) NOT NULL,
+	user_info TEXT NOT NULL,
+	expires_at BIGINT NOT NULL
+);
+
+CREATE INDEX authproxy_sessions_expires_at ON authproxy_sessions (expires_at);
+
+INSERT INTO authproxy_schema_migrations (version) VALUES (1);
@@ -0,0 +1,355 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package sql implements a provider checking login credentials against users, password hashes
+// and group memberships stored in a SQL database accessed through database/sql.
+// Tokens are stored in a sessions table by default so several authproxy replicas can share them.
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	stderrors "errors"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/htpasswd"
+	"github.com/cbrgm/authproxy/provider/tokenstore"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"net"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the timeout of each query if none is configured
+	DefaultTimeout = 5 * time.Second
+	// DefaultCleanupInterval is the interval expired sessions are deleted at if none is configured
+	DefaultCleanupInterval = 10 * time.Minute
+)
+
+// Config represents the SQL provider configuration
+type Config struct {
+	// DB is the database users are authenticated against, it is opened with Driver and DSN if unset
+	DB *sql.DB
+	// Driver is the name of a registered database/sql driver, e.g. postgres, mysql or sqlite3.
+	// It selects the placeholder style of the default queries, also if DB is set.
+	Driver string
+	// DSN is the data source name the database is opened with
+	DSN string
+	// Queries replaces the default queries, unset queries keep their default (optional)
+	Queries Queries
+	// Migrate applies the migrations of the reference schema when the provider is created
+	Migrate bool
+	// Timeout of each query (default: 5s)
+	Timeout time.Duration
+
+	// TokenStore issues tokens after successful logins (default: session store on the database)
+	TokenStore tokenstore.Store
+	// TokenTTL is the lifetime of tokens issued by the default session store
+	TokenTTL time.Duration
+	// CleanupInterval is the interval expired sessions are deleted at by the default session store (default: 10m)
+	CleanupInterval time.Duration
+
+	// Logger is used to report failed queries (optional)
+	Logger log.Logger
+}
+
+// Provider implements provider.ContextProvider using a SQL database
+type Provider struct {
+	config   Config
+	queries  Queries
+	ownsDB   bool
+	sessions *SessionStore
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// user is a row returned by the user query
+type user struct {
+	username     string
+	uid          string
+	passwordHash string
+}
+
+// NewProvider returns a new SQL provider for the config.
+// Expired sessions are deleted periodically until Close is called.
+func NewProvider(cfg Config) (*Provider, error) {
+	if cfg.Logger == nil {
+		cfg.Logger = log.NewNopLogger()
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.CleanupInterval <= 0 {
+		cfg.CleanupInterval = DefaultCleanupInterval
+	}
+
+	p := &Provider{
+		config:  cfg,
+		queries: cfg.Queries.withDefaults(DefaultQueries(cfg.Driver)),
+	}
+
+	if cfg.DB == nil {
+		if cfg.Driver == "" || cfg.DSN == "" {
+			return nil, fmt.Errorf("sql provider requires a database or a driver and a DSN")
+		}
+		db, err := sql.Open(cfg.Driver, cfg.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database: %v", err)
+		}
+		p.config.DB = db
+		p.ownsDB = true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+
+	if err := p.config.DB.PingContext(ctx); err != nil {
+		p.closeDB()
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
+
+	if cfg.Migrate {
+		applied, err := Migrate(ctx, p.config.DB)
+		if err != nil {
+			p.closeDB()
+			return nil, err
+		}
+		if applied > 0 {
+			level.Info(cfg.Logger).Log("msg", "applied database migrations", "count", applied)
+		}
+	}
+
+	if p.config.TokenStore == nil {
+		p.sessions = NewSessionStore(p.config.DB, p.queries, cfg.TokenTTL)
+		p.sessions.timeout = cfg.Timeout
+		p.config.TokenStore = p.sessions
+
+		p.stop = make(chan struct{})
+		p.done = make(chan struct{})
+		go p.cleanup()
+	}
+
+	return p, nil
+}
+
+// Login checks the password of the user against the stored hash and issues a token
+func (p *Provider) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	password := req.Password
+	if req.Username == "" || password == "" {
+		return provider.Unauthenticated(), nil
+	}
+
+	u, err := p.user(ctx, req.Username)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		htpasswd.VerifyDummy(password)
+		return provider.Unauthenticated(), nil
+	}
+	if err := htpasswd.CheckHash(u.passwordHash); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("user %s has an invalid password hash: %v", u.username, err))
+	}
+	if !htpasswd.Verify(u.passwordHash, password) {
+		return provider.Unauthenticated(), nil
+	}
+
+	groups, err := p.groups(ctx, u.username)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &models.UserInfo{
+		Username: u.username,
+		UID:      u.uid,
+		Groups:   groups,
+	}
+
+	token, err := p.issue(ctx, info)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenReviewRequest{
+		APIVersion: "authentication.k8s.io/v1beta1",
+		Kind:       "TokenReview",
+		Spec: &models.TokenReviewSpec{
+			Token: token,
+		},
+		Status: &models.TokenReviewStatus{
+			Authenticated: true,
+			User:          info,
+		},
+	}, nil
+}
+
+// Authenticate looks up the bearer token in the token store
+func (p *Provider) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	if req.Token == "" {
+		return provider.Unauthenticated(), nil
+	}
+
+	info, err := p.lookup(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return provider.Unauthenticated(), nil
+	}
+
+	// deleted or disabled users lose access before their tokens expire
+	u, err := p.user(ctx, info.Username)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return provider.Unauthenticated(), nil
+	}
+
+	// groups are resolved on every request so membership changes apply to issued tokens
+	groups, err := p.groups(ctx, u.username)
+	if err != nil {
+		return nil, err
+	}
+	info.UID = u.uid
+	info.Groups = groups
+
+	return &models.TokenReviewRequest{
+		APIVersion: "authentication.k8s.io/v1beta1",
+		Kind:       "TokenReview",
+		Status: &models.TokenReviewStatus{
+			Authenticated: true,
+			User:          info,
+		},
+	}, nil
+}
+
+// Close stops deleting expired sessions and closes the database if it was opened by the provider
+func (p *Provider) Close() {
+	if p.stop != nil {
+		close(p.stop)
+		<-p.done
+	}
+	p.closeDB()
+}
+
+func (p *Provider) closeDB() {
+	if p.ownsDB {
+		p.config.DB.Close()
+	}
+}
+
+// issue issues a token for the user, sessions stored by the provider honour the context
+func (p *Provider) issue(ctx context.Context, info *models.UserInfo) (string, error) {
+	if p.sessions != nil {
+		return p.sessions.issue(ctx, info)
+	}
+	return p.config.TokenStore.Issue(info)
+}
+
+// lookup returns the user a token was issued for, sessions stored by the provider honour the context
+func (p *Provider) lookup(ctx context.Context, token string) (*models.UserInfo, error) {
+	if p.sessions != nil {
+		return p.sessions.lookup(ctx, token)
+	}
+	return p.config.TokenStore.Lookup(token)
+}
+
+// user returns the enabled user with the username, nil if there is none
+func (p *Provider) user(ctx context.Context, username string) (*user, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	var u user
+	err := p.config.DB.QueryRowContext(ctx, p.queries.User, username).Scan(&u.username, &u.uid, &u.passwordHash)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, p.queryError("user", err)
+	}
+	return &u, nil
+}
+
+// groups returns the names of the groups the user is a member of
+func (p *Provider) groups(ctx context.Context, username string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	rows, err := p.config.DB.QueryContext(ctx, p.queries.Groups, username)
+	if err != nil {
+		return nil, p.queryError("groups", err)
+	}
+	defer rows.Close()
+
+	groups := []string{}
+	for rows.Next() {
+		var group string
+		if err := rows.Scan(&group); err != nil {
+			return nil, p.queryError("groups", err)
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, p.queryError("groups", err)
+	}
+	return groups, nil
+}
+
+// cleanup deletes expired sessions every cleanup interval until the provider is closed
+func (p *Provider) cleanup() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.config.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			deleted, err := p.sessions.Expire()
+			if err != nil {
+				level.Warn(p.config.Logger).Log("msg", "failed to delete expired sessions", "err", err)
+				continue
+			}
+			if deleted > 0 {
+				level.Debug(p.config.Logger).Log("msg", "deleted expired sessions", "count", deleted)
+			}
+		}
+	}
+}
+
+// queryError logs a failed query and returns it as an API error
+func (p *Provider) queryError(query string, err error) error {
+	level.Warn(p.config.Logger).Log("msg", "database query failed", "query", query, "err", err)
+	return dbError(fmt.Errorf("%s query failed: %v", query, err), err)
+}
+
+// dbError returns err as service unavailable if the database couldn't be reached or the query was aborted
+// and as internal error otherwise
+func dbError(err, cause error) error {
+	var netErr net.Error
+	if stderrors.As(cause, &netErr) || stderrors.Is(cause, driver.ErrBadConn) ||
+		stderrors.Is(cause, context.DeadlineExceeded) || stderrors.Is(cause, context.Canceled) {
+		return errors.NewServiceUnavailable(err)
+	}
+	return errors.NewInternalError(err)
+}
@@ -0,0 +1,363 @@
+//go:build cgo
+// +build cgo
+
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/htpasswd"
+	_ "github.com/mattn/go-sqlite3"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// openDB returns an empty in-memory SQLite database
+func openDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection to :memory: opens a new database
+	db.SetMaxOpenConns(1)
+	return db
+}
+
+// seed inserts the user foo with the password bar into the reference schema
+func seed(t *testing.T, db *sql.DB) {
+	hash, err := htpasswd.HashArgon2id("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, stmt := range []string{
+		"INSERT INTO authproxy_users (username, uid, password_hash) VALUES ('foo', '1', '" + hash + "')",
+		"INSERT INTO authproxy_users (username, uid, password_hash, disabled) VALUES ('disabled', '2', '" + hash + "', TRUE)",
+		"INSERT INTO authproxy_group_members (group_name, username) VALUES ('developers', 'foo'), ('admins', 'foo')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	applied, err := Migrate(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied != len(Migrations) {
+		t.Errorf("expected %d migrations to be applied, got %d", len(Migrations), applied)
+	}
+
+	if applied, err = Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	if applied != 0 {
+		t.Errorf("expected no migrations to be applied again, got %d", applied)
+	}
+
+	// tables without recorded migrations are refused instead of failing on the indexes
+	if _, err := db.Exec("DELETE FROM authproxy_schema_migrations"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Migrate(context.Background(), db); err == nil || !strings.Contains(err.Error(), "no migration is recorded") {
+		t.Errorf("expected existing tables without recorded migrations to be refused, got %v", err)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	schema, err := ioutil.ReadFile("schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(string(schema)); err != nil {
+		t.Fatalf("failed to create reference schema: %v", err)
+	}
+
+	applied, err := Migrate(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied != 0 {
+		t.Errorf("expected reference schema to be up to date, %d migrations were applied", applied)
+	}
+
+	seed(t, db)
+}
+
+func TestProvider(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	p, err := NewProvider(Config{DB: db, Driver: "sqlite3", Migrate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	seed(t, db)
+
+	for _, c := range []struct{ username, password string }{
+		{"foo", "baz"},
+		{"unknown", "bar"},
+		{"disabled", "bar"},
+		{"foo", ""},
+	} {
+		review, err := p.Login(context.Background(), provider.LoginRequest{Username: c.username, Password: c.password})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if review.Status.Authenticated {
+			t.Errorf("expected login of %q with password %q to fail", c.username, c.password)
+		}
+	}
+
+	login, err := p.Login(context.Background(), provider.LoginRequest{Username: "foo", Password: "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !login.Status.Authenticated || login.Spec == nil || login.Spec.Token == "" {
+		t.Fatalf("expected login to return a token, got %+v", login)
+	}
+	if groups := login.Status.User.Groups; !reflect.DeepEqual(groups, []string{"admins", "developers"}) {
+		t.Errorf("expected groups admins and developers, got %v", groups)
+	}
+
+	var hash string
+	if err := db.QueryRow("SELECT token_hash FROM authproxy_sessions WHERE username = 'foo'").Scan(&hash); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(hash, login.Spec.Token) {
+		t.Error("expected the sessions table to store token hashes only")
+	}
+
+	review, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: login.Spec.Token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !review.Status.Authenticated || review.Status.User.Username != "foo" || review.Status.User.UID != "1" {
+		t.Fatalf("expected token to authenticate foo, got %+v", review.Status)
+	}
+
+	// group memberships apply to issued tokens
+	if _, err := db.Exec("DELETE FROM authproxy_group_members WHERE group_name = 'admins'"); err != nil {
+		t.Fatal(err)
+	}
+	if review, err = p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: login.Spec.Token}); err != nil {
+		t.Fatal(err)
+	}
+	if groups := review.Status.User.Groups; !reflect.DeepEqual(groups, []string{"developers"}) {
+		t.Errorf("expected groups developers, got %v", groups)
+	}
+
+	// disabled users lose access before their tokens expire
+	if _, err := db.Exec("UPDATE authproxy_users SET disabled = TRUE WHERE username = 'foo'"); err != nil {
+		t.Fatal(err)
+	}
+	if review, err = p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: login.Spec.Token}); err != nil {
+		t.Fatal(err)
+	}
+	if review.Status.Authenticated {
+		t.Error("expected token of disabled user to be rejected")
+	}
+
+	if review, err = p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "unknown"}); err != nil {
+		t.Fatal(err)
+	}
+	if review.Status.Authenticated {
+		t.Error("expected unknown token to be rejected")
+	}
+}
+
+func TestSessionStore(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	if _, err := Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1500000000, 0)
+	s := NewSessionStore(db, Queries{}, time.Hour)
+	s.now = func() time.Time { return now }
+
+	token, err := s.Issue(&testUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := s.Issue(&testUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := s.Lookup(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user == nil || !reflect.DeepEqual(*user, testUser) {
+		t.Fatalf("expected token to belong to %+v, got %+v", testUser, user)
+	}
+
+	if err := s.Revoke(revoked); err != nil {
+		t.Fatal(err)
+	}
+	if user, err = s.Lookup(revoked); err != nil || user != nil {
+		t.Errorf("expected revoked token to be unknown, got %+v, %v", user, err)
+	}
+
+	now = now.Add(time.Hour)
+	if user, err = s.Lookup(token); err != nil || user != nil {
+		t.Errorf("expected expired token to be unknown, got %+v, %v", user, err)
+	}
+
+	deleted, err := s.Expire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 expired session to be deleted, got %d", deleted)
+	}
+}
+
+func TestCustomQueries(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	if _, err := Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := htpasswd.HashArgon2id("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE accounts (id INTEGER PRIMARY KEY, login TEXT, secret TEXT, team TEXT)",
+		"INSERT INTO accounts (id, login, secret, team) VALUES (42, 'foo', '" + hash + "', 'developers')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, err := NewProvider(Config{
+		DB: db,
+		Queries: Queries{
+			User:   "SELECT login, CAST(id AS TEXT), secret FROM accounts WHERE login = ?",
+			Groups: "SELECT team FROM accounts WHERE login = ?",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	login, err := p.Login(context.Background(), provider.LoginRequest{Username: "foo", Password: "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !login.Status.Authenticated {
+		t.Fatal("expected login to succeed")
+	}
+	if user := login.Status.User; user.UID != "42" || !reflect.DeepEqual(user.Groups, []string{"developers"}) {
+		t.Errorf("expected uid 42 and group developers, got %+v", user)
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	// without migrations the tables don't exist
+	p, err := NewProvider(Config{DB: db})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if _, err := p.Login(context.Background(), provider.LoginRequest{Username: "foo", Password: "bar"}); !errors.IsInternalError(err) {
+		t.Errorf("expected internal error, got %v", err)
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	p, err := NewProvider(Config{DB: db, Driver: "sqlite3", Migrate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	seed(t, db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := p.Login(ctx, provider.LoginRequest{Username: "foo", Password: "bar"}); !errors.IsServiceUnavailable(err) {
+		t.Errorf("expected login with a cancelled context to be unavailable, got %v", err)
+	}
+	if _, err := p.Authenticate(ctx, provider.AuthenticateRequest{Token: "token"}); err == nil {
+		t.Error("expected authentication with a cancelled context to fail")
+	}
+}
+
+func TestInvalidHash(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	p, err := NewProvider(Config{DB: db, Migrate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	hash := "$argon2id$v=19$m=4294967295,t=0,p=0$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+	if _, err := db.Exec("INSERT INTO authproxy_users (username, uid, password_hash) VALUES ('foo', '1', '" + hash + "')"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Login(context.Background(), provider.LoginRequest{Username: "foo", Password: "bar"}); !errors.IsInternalError(err) {
+		t.Errorf("expected internal error for an invalid hash, got %v", err)
+	}
+}
+
+func TestDefaultQueries(t *testing.T) {
+	queries := DefaultQueries("postgres")
+	if queries.InsertSession != "INSERT INTO authproxy_sessions (token_hash, username, user_info, expires_at) VALUES ($1, $2, $3, $4)" {
+		t.Errorf("expected numbered placeholders, got %q", queries.InsertSession)
+	}
+	if queries = DefaultQueries("mysql"); queries != defaultQueries {
+		t.Errorf("expected ? placeholders, got %+v", queries)
+	}
+}
+
+var testUser = models.UserInfo{Username: "foo", UID: "1", Groups: []string{"developers"}}
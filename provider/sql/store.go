@@ -0,0 +1,134 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider/tokenstore"
+	"time"
+)
+
+// SessionStore implements tokenstore.Store using a sessions table.
+// Only hashes of tokens are stored, so a leaked table doesn't leak usable tokens.
+type SessionStore struct {
+	db      *sql.DB
+	queries Queries
+	ttl     time.Duration
+	timeout time.Duration
+	now     func() time.Time
+}
+
+// NewSessionStore returns a new store issuing tokens valid for ttl using the session queries.
+// Unset queries use the defaults for the reference schema with ? placeholders.
+func NewSessionStore(db *sql.DB, queries Queries, ttl time.Duration) *SessionStore {
+	if ttl <= 0 {
+		ttl = tokenstore.DefaultTTL
+	}
+	return &SessionStore{
+		db:      db,
+		queries: queries.withDefaults(defaultQueries),
+		ttl:     ttl,
+		timeout: DefaultTimeout,
+		now:     time.Now,
+	}
+}
+
+// Issue returns a new bearer token for the user
+func (s *SessionStore) Issue(user *models.UserInfo) (string, error) {
+	return s.issue(context.Background(), user)
+}
+
+// issue stores a session for a new bearer token until the context is done or the timeout expired
+func (s *SessionStore) issue(ctx context.Context, user *models.UserInfo) (string, error) {
+	token, err := tokenstore.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	info, err := json.Marshal(user)
+	if err != nil {
+		return "", errors.NewInternalError(fmt.Errorf("failed to encode user: %v", err))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	expires := s.now().Add(s.ttl).Unix()
+	if _, err := s.db.ExecContext(ctx, s.queries.InsertSession, tokenstore.Hash(token), user.Username, string(info), expires); err != nil {
+		return "", dbError(fmt.Errorf("failed to store session: %v", err), err)
+	}
+
+	return token, nil
+}
+
+// Lookup returns the user a token was issued for, nil if the token is unknown or expired
+func (s *SessionStore) Lookup(token string) (*models.UserInfo, error) {
+	return s.lookup(context.Background(), token)
+}
+
+// lookup returns the user of the session of a token until the context is done or the timeout expired
+func (s *SessionStore) lookup(ctx context.Context, token string) (*models.UserInfo, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var info string
+	err := s.db.QueryRowContext(ctx, s.queries.SelectSession, tokenstore.Hash(token), s.now().Unix()).Scan(&info)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, dbError(fmt.Errorf("failed to look up session: %v", err), err)
+	}
+
+	var user models.UserInfo
+	if err := json.Unmarshal([]byte(info), &user); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("failed to decode session: %v", err))
+	}
+	return &user, nil
+}
+
+// Revoke deletes the session of a token
+func (s *SessionStore) Revoke(token string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, s.queries.DeleteSession, tokenstore.Hash(token)); err != nil {
+		return dbError(fmt.Errorf("failed to delete session: %v", err), err)
+	}
+	return nil
+}
+
+// Expire deletes all expired sessions and returns how many were deleted
+func (s *SessionStore) Expire() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, s.queries.DeleteExpiredSessions, s.now().Unix())
+	if err != nil {
+		return 0, dbError(fmt.Errorf("failed to delete expired sessions: %v", err), err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package tokenfile implements a static token provider compatible with the kube-apiserver --token-auth-file format.
+package tokenfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/internal/watch"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Config represents the token file provider configuration
+type Config struct {
+	// Path of the CSV token file
+	Path string
+	// ReloadInterval is the interval the token file is checked for changes, 0 disables reloading
+	ReloadInterval time.Duration
+	// Logger is used to report failed reloads (optional)
+	Logger log.Logger
+}
+
+// Provider implements provider.Provider using a static token file.
+// Each line of the file has the format: token,user,uid,"group1,group2"
+type Provider struct {
+	config Config
+	stop   func()
+
+	// tokens holds a map[string]*models.UserInfo which is replaced atomically on reloads
+	tokens atomic.Value
+}
+
+// NewProvider returns a new provider for the token file of the config.
+// The token file is reloaded on changes until Close is called.
+func NewProvider(cfg Config) (*Provider, error) {
+	tokens, err := LoadFile(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Logger == nil {
+		cfg.Logger = log.NewNopLogger()
+	}
+
+	p := &Provider{
+		config: cfg,
+		stop:   func() {},
+	}
+	p.tokens.Store(tokens)
+
+	if cfg.ReloadInterval > 0 {
+		p.stop = watch.Poll(cfg.Path, cfg.ReloadInterval, p.reload)
+	}
+
+	return p, nil
+}
+
+// Login is not supported by static tokens, every login attempt is unauthenticated
+func (p *Provider) Login(username, password string) (*models.TokenReviewRequest, error) {
+	return provider.Unauthenticated(), nil
+}
+
+// Authenticate looks up the bearer token in the token file
+func (p *Provider) Authenticate(bearerToken string) (*models.TokenReviewRequest, error) {
+	tokens := p.tokens.Load().(map[string]*models.UserInfo)
+
+	user, ok := tokens[bearerToken]
+	if !ok || bearerToken == "" {
+		return provider.Unauthenticated(), nil
+	}
+
+	// copy the user so callers can't modify the token file contents
+	info := *user
+	info.Groups = append([]string(nil), user.Groups...)
+
+	return &models.TokenReviewRequest{
+		APIVersion: "authentication.k8s.io/v1beta1",
+		Kind:       "TokenReview",
+		Status: &models.TokenReviewStatus{
+			Authenticated: true,
+			User:          &info,
+		},
+	}, nil
+}
+
+// Close stops reloading the token file
+func (p *Provider) Close() {
+	p.stop()
+}
+
+// reload replaces the tokens by the current token file, the old tokens are kept on errors
+func (p *Provider) reload() {
+	tokens, err := LoadFile(p.config.Path)
+	if err != nil {
+		level.Error(p.config.Logger).Log("msg", "failed to reload token file, keeping previous tokens", "path", p.config.Path, "err", err)
+		return
+	}
+
+	p.tokens.Store(tokens)
+	level.Info(p.config.Logger).Log("msg", "reloaded token file", "path", p.config.Path, "tokens", len(tokens))
+}
+
+// LoadFile reads a token file and returns the users by token
+func LoadFile(path string) (map[string]*models.UserInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token file: %v", err)
+	}
+	defer f.Close()
+
+	return Parse(f)
+}
+
+// Parse parses a token file and returns the users by token
+func Parse(r io.Reader) (map[string]*models.UserInfo, error) {
+	// strip a byte order mark at the beginning of the file
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		br.Discard(3)
+	}
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	tokens := map[string]*models.UserInfo{}
+
+	for n := 1; ; n++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse token file: %v", err)
+		}
+
+		if len(record) < 3 {
+			return nil, fmt.Errorf("token file record %d: expected at least 3 columns, got %d", n, len(record))
+		}
+
+		token := strings.TrimSpace(record[0])
+		if token == "" {
+			return nil, fmt.Errorf("token file record %d: token is empty", n)
+		}
+		if _, exists := tokens[token]; exists {
+			return nil, fmt.Errorf("token file record %d: duplicate token", n)
+		}
+
+		user := &models.UserInfo{
+			Username: strings.TrimSpace(record[1]),
+			UID:      strings.TrimSpace(record[2]),
+			Groups:   []string{},
+		}
+		if user.Username == "" {
+			return nil, fmt.Errorf("token file record %d: user is empty", n)
+		}
+
+		if len(record) >= 4 {
+			for _, group := range strings.Split(record[3], ",") {
+				if group = strings.TrimSpace(group); group != "" {
+					user.Groups = append(user.Groups, group)
+				}
+			}
+		}
+
+		tokens[token] = user
+	}
+
+	return tokens, nil
+}
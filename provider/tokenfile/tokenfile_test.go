@@ -0,0 +1,88 @@
+package tokenfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	file := "\ufeff" + `# static tokens
+31ada4fd-adec-460c-809a-9e56ceb75269,foo,1,"developers,admins"
+f0a8f2c1-2b4e-4c1a-9d5e-6b6f4b7a1f3e,bar,2
+`
+
+	tokens, err := Parse(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tokens) != 2 {
+		t.Fatalf("expected 2 tokens, got %d", len(tokens))
+	}
+
+	foo := tokens["31ada4fd-adec-460c-809a-9e56ceb75269"]
+	if foo == nil || foo.Username != "foo" || foo.UID != "1" || !reflect.DeepEqual(foo.Groups, []string{"developers", "admins"}) {
+		t.Errorf("unexpected user %+v", foo)
+	}
+
+	bar := tokens["f0a8f2c1-2b4e-4c1a-9d5e-6b6f4b7a1f3e"]
+	if bar == nil || bar.Username != "bar" || len(bar.Groups) != 0 {
+		t.Errorf("unexpected user %+v", bar)
+	}
+
+	invalid := []string{
+		"token,foo\n",
+		",foo,1\n",
+		"token,,1\n",
+		"token,foo,1\ntoken,bar,2\n",
+	}
+	for _, f := range invalid {
+		if _, err := Parse(strings.NewReader(f)); err == nil {
+			t.Errorf("expected token file to be invalid: %q", f)
+		}
+	}
+}
+
+func TestProviderReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tokenfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "tokens.csv")
+	if err := ioutil.WriteFile(path, []byte("old-token,foo,1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewProvider(Config{Path: path, ReloadInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if trr, _ := p.Authenticate("old-token"); !trr.Status.Authenticated || trr.Status.User.Username != "foo" {
+		t.Fatal("expected old token to be authenticated")
+	}
+
+	if err := ioutil.WriteFile(path, []byte("new-token,foo,1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if trr, _ := p.Authenticate("new-token"); trr.Status.Authenticated {
+			if trr, _ := p.Authenticate("old-token"); trr.Status.Authenticated {
+				t.Fatal("expected old token to be revoked")
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("expected token file to be reloaded")
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package tokenstore issues opaque bearer tokens for users which logged in through a provider.
+package tokenstore
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"sync"
+	"time"
+)
+
+// DefaultTTL is the lifetime of tokens issued by a MemoryStore without a configured TTL
+const DefaultTTL = 12 * time.Hour
+
+// Store issues bearer tokens for authenticated users and looks them up again.
+// Implementations backed by shared storage allow running several authproxy replicas.
+type Store interface {
+	// Issue returns a new bearer token for the user
+	Issue(user *models.UserInfo) (string, error)
+	// Lookup returns the user a token was issued for, nil if the token is unknown or expired
+	Lookup(token string) (*models.UserInfo, error)
+}
+
+// NewToken returns a random bearer token with 256 bits of entropy
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Hash returns the hex encoded SHA-256 hash of a token.
+// Stores should only keep hashes so leaked storage doesn't leak usable tokens.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("%x", sum)
+}
+
+// MemoryStore implements Store in memory, tokens are lost on restarts
+type MemoryStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu     sync.Mutex
+	tokens map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	user    models.UserInfo
+	expires time.Time
+}
+
+// NewMemoryStore returns a new in-memory store issuing tokens valid for ttl
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &MemoryStore{
+		ttl:    ttl,
+		now:    time.Now,
+		tokens: map[string]memoryEntry{},
+	}
+}
+
+// Issue returns a new bearer token for the user
+func (s *MemoryStore) Issue(user *models.UserInfo) (string, error) {
+	token, err := NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.expire(now)
+	s.tokens[Hash(token)] = memoryEntry{user: copyUser(user), expires: now.Add(s.ttl)}
+
+	return token, nil
+}
+
+// Lookup returns the user a token was issued for, nil if the token is unknown or expired
+func (s *MemoryStore) Lookup(token string) (*models.UserInfo, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.tokens[Hash(token)]
+	if !ok || !s.now().Before(entry.expires) {
+		return nil, nil
+	}
+
+	user := copyUser(&entry.user)
+	return &user, nil
+}
+
+// Revoke removes a token from the store
+func (s *MemoryStore) Revoke(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tokens, Hash(token))
+}
+
+// expire removes all expired tokens, the caller must hold the lock
+func (s *MemoryStore) expire(now time.Time) {
+	for hash, entry := range s.tokens {
+		if !now.Before(entry.expires) {
+			delete(s.tokens, hash)
+		}
+	}
+}
+
+// copyUser returns a copy of the user not sharing the groups with the original
+func copyUser(user *models.UserInfo) models.UserInfo {
+	c := *user
+	c.Groups = append([]string(nil), user.Groups...)
+	return c
+}
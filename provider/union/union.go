@@ -0,0 +1,191 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package union implements a provider chaining multiple providers like the union authenticator of Kubernetes.
+// The providers are tried in order and the first authenticated result is returned.
+package union
+
+import (
+	"context"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"net/http"
+)
+
+// DefaultProviderExtraKey is the extra key the name of the authenticating provider is recorded in if none is configured
+const DefaultProviderExtraKey = "provider"
+
+// Mode decides how internal errors of providers are handled
+type Mode int
+
+const (
+	// ContinueOnError skips providers failing with an internal error and tries the next ones.
+	// The first internal error is returned if no provider authenticates the user
+	ContinueOnError Mode = iota
+	// FailOnError stops the chain at the first provider failing with an internal error
+	FailOnError
+)
+
+// Member is a named provider of the union, providers implementing only
+// provider.Provider can be added with provider.NewContextAdapter
+type Member struct {
+	// Name identifies the provider in the extra of users and in logs
+	Name     string
+	Provider provider.ContextProvider
+}
+
+// Config represents the union provider configuration
+type Config struct {
+	// Providers are tried in order
+	Providers []Member
+	// Mode decides whether internal errors of providers stop the chain (default: ContinueOnError)
+	Mode Mode
+	// ProviderExtraKey is the extra key the name of the authenticating provider is recorded in (default: provider)
+	ProviderExtraKey string
+	// Logger is used to report skipped provider errors (optional)
+	Logger log.Logger
+}
+
+// Provider implements provider.ContextProvider by chaining providers
+type Provider struct {
+	config Config
+}
+
+// NewProvider returns a new provider trying the providers of the config in order
+func NewProvider(cfg Config) (*Provider, error) {
+	if len(cfg.Providers) == 0 {
+		return nil, fmt.Errorf("no providers configured")
+	}
+
+	names := map[string]bool{}
+	for _, member := range cfg.Providers {
+		if member.Name == "" || member.Provider == nil {
+			return nil, fmt.Errorf("providers need a name and an implementation")
+		}
+		if names[member.Name] {
+			return nil, fmt.Errorf("duplicate provider %q", member.Name)
+		}
+		names[member.Name] = true
+	}
+
+	if cfg.ProviderExtraKey == "" {
+		cfg.ProviderExtraKey = DefaultProviderExtraKey
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.NewNopLogger()
+	}
+
+	return &Provider{config: cfg}, nil
+}
+
+// Login tries to log in the user with every provider until one succeeds
+func (p *Provider) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	return p.first(ctx, "login", func(prv provider.ContextProvider) (*models.TokenReviewRequest, error) {
+		return prv.Login(ctx, req)
+	})
+}
+
+// Authenticate tries to authenticate the token with every provider until one succeeds
+func (p *Provider) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	return p.first(ctx, "authenticate", func(prv provider.ContextProvider) (*models.TokenReviewRequest, error) {
+		return prv.Authenticate(ctx, req)
+	})
+}
+
+// first returns the first authenticated result of the providers.
+// Providers rejecting the request with a client error are skipped, internal errors are handled according to the mode.
+// If no provider authenticates the user, the first internal error, the first client error or an unauthenticated result is returned.
+// The remaining providers are skipped once the context is done
+func (p *Provider) first(ctx context.Context, op string, fn func(prv provider.ContextProvider) (*models.TokenReviewRequest, error)) (*models.TokenReviewRequest, error) {
+	var internalErr, clientErr error
+
+	for _, member := range p.config.Providers {
+		if err := ctx.Err(); err != nil {
+			return nil, errors.NewServiceUnavailable(err)
+		}
+
+		trr, err := fn(member.Provider)
+		if err != nil {
+			if clientError(err) {
+				if clientErr == nil {
+					clientErr = err
+				}
+				continue
+			}
+			if p.config.Mode == FailOnError {
+				return nil, err
+			}
+
+			level.Warn(p.config.Logger).Log("msg", "skipping failed provider", "op", op, "provider", member.Name, "err", err)
+			if internalErr == nil {
+				internalErr = err
+			}
+			continue
+		}
+
+		if trr != nil && trr.Status != nil && trr.Status.Authenticated {
+			if trr.Status.User != nil {
+				trr.Status.User.Extra = withProvider(trr.Status.User.Extra, p.config.ProviderExtraKey, member.Name)
+			}
+			return trr, nil
+		}
+	}
+
+	if internalErr != nil {
+		return nil, internalErr
+	}
+	if clientErr != nil {
+		return nil, clientErr
+	}
+	return provider.Unauthenticated(), nil
+}
+
+// clientError returns true for errors rejecting the request, they don't indicate a failure of the provider
+func clientError(err error) bool {
+	switch errors.ReasonForError(err) {
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return true
+	}
+	return false
+}
+
+// withProvider returns a copy of the extra with the provider name recorded under key.
+// Extras of unknown types are returned unchanged
+func withProvider(extra interface{}, key, name string) interface{} {
+	switch e := extra.(type) {
+	case nil:
+		return map[string][]string{key: {name}}
+	case map[string][]string:
+		out := make(map[string][]string, len(e)+1)
+		for k, v := range e {
+			out[k] = v
+		}
+		out[key] = []string{name}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(e)+1)
+		for k, v := range e {
+			out[k] = v
+		}
+		out[key] = []string{name}
+		return out
+	}
+	return extra
+}
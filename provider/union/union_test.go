@@ -0,0 +1,232 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package union
+
+import (
+	"context"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/fake"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// stub authenticates a single token and fails with err if set
+type stub struct {
+	token string
+	user  *models.UserInfo
+	err   error
+	calls int
+}
+
+func (s *stub) Login(ctx context.Context, req provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	return s.Authenticate(ctx, provider.AuthenticateRequest{Token: req.Password})
+}
+
+func (s *stub) Authenticate(ctx context.Context, req provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	if req.Token != s.token {
+		return provider.Unauthenticated(), nil
+	}
+	user := *s.user
+	return &models.TokenReviewRequest{Status: &models.TokenReviewStatus{Authenticated: true, User: &user}}, nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	static := &stub{token: "static", user: &models.UserInfo{Username: "static", Extra: map[string][]string{"team": {"a"}}}}
+	ldap := &stub{token: "ldap", user: &models.UserInfo{Username: "ldap"}}
+
+	p, err := NewProvider(Config{Providers: []Member{
+		{Name: "tokenfile", Provider: static},
+		{Name: "ldap", Provider: ldap},
+		{Name: "fake", Provider: provider.NewContextAdapter(fake.NewFakeProvider())},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		token    string
+		username string
+		provider string
+	}{
+		{token: "static", username: "static", provider: "tokenfile"},
+		{token: "ldap", username: "ldap", provider: "ldap"},
+		{token: "AbCdEf123456", provider: "fake"},
+		{token: "invalid"},
+	}
+
+	for _, tt := range tests {
+		trr, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: tt.token})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.token, err)
+		}
+		if trr.Status.Authenticated != (tt.provider != "") {
+			t.Errorf("%s: expected authenticated %v", tt.token, tt.provider != "")
+			continue
+		}
+		if tt.provider == "" || trr.Status.User == nil {
+			continue
+		}
+		if trr.Status.User.Username != tt.username {
+			t.Errorf("%s: expected user %s, got %s", tt.token, tt.username, trr.Status.User.Username)
+		}
+		if extra := trr.Status.User.Extra.(map[string][]string); !reflect.DeepEqual(extra[DefaultProviderExtraKey], []string{tt.provider}) {
+			t.Errorf("%s: expected provider %s in extra, got %v", tt.token, tt.provider, extra)
+		}
+	}
+
+	if extra := static.user.Extra.(map[string][]string); len(extra) != 1 {
+		t.Errorf("expected extra of provider not to be modified, got %v", extra)
+	}
+
+	// the chain stops at the first authenticated result
+	ldap.calls = 0
+	if _, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "static"}); err != nil {
+		t.Fatal(err)
+	}
+	if ldap.calls != 0 {
+		t.Error("expected providers after the authenticating one not to be called")
+	}
+}
+
+func TestLogin(t *testing.T) {
+	p, err := NewProvider(Config{Providers: []Member{
+		{Name: "broken", Provider: &stub{err: errors.NewServiceUnavailable(fmt.Errorf("ldap down"))}},
+		{Name: "fake", Provider: provider.NewContextAdapter(fake.NewFakeProvider())},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trr, err := p.Login(context.Background(), provider.LoginRequest{Username: "foo", Password: "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !trr.Status.Authenticated || trr.Spec.Token == "" {
+		t.Errorf("expected login to succeed with the fake provider, got %+v", trr.Status)
+	}
+}
+
+func TestContext(t *testing.T) {
+	first := &stub{token: "first", user: &models.UserInfo{Username: "first"}}
+	second := &stub{token: "second", user: &models.UserInfo{Username: "second"}}
+
+	p, err := NewProvider(Config{Providers: []Member{{Name: "first", Provider: first}, {Name: "second", Provider: second}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := p.Authenticate(ctx, provider.AuthenticateRequest{Token: "second"}); !errors.IsServiceUnavailable(err) {
+		t.Errorf("expected cancelled request to fail with service unavailable, got %v", err)
+	}
+	if first.calls != 0 || second.calls != 0 {
+		t.Error("expected no provider to be called after the context is done")
+	}
+}
+
+func TestErrorModes(t *testing.T) {
+	internal := errors.NewServiceUnavailable(fmt.Errorf("ldap down"))
+
+	tests := []struct {
+		name   string
+		mode   Mode
+		chain  []provider.ContextProvider
+		token  string
+		status int
+		authed bool
+	}{
+		{
+			name:   "continue skips internal errors",
+			mode:   ContinueOnError,
+			chain:  []provider.ContextProvider{&stub{err: internal}, &stub{token: "ok", user: &models.UserInfo{Username: "foo"}}},
+			token:  "ok",
+			authed: true,
+		},
+		{
+			name:   "continue returns internal error if nobody authenticates",
+			mode:   ContinueOnError,
+			chain:  []provider.ContextProvider{&stub{err: internal}, &stub{token: "ok", user: &models.UserInfo{Username: "foo"}}},
+			token:  "invalid",
+			status: http.StatusServiceUnavailable,
+		},
+		{
+			name:   "fail stops at internal errors",
+			mode:   FailOnError,
+			chain:  []provider.ContextProvider{&stub{err: internal}, &stub{token: "ok", user: &models.UserInfo{Username: "foo"}}},
+			token:  "ok",
+			status: http.StatusServiceUnavailable,
+		},
+		{
+			name:   "fail skips client errors",
+			mode:   FailOnError,
+			chain:  []provider.ContextProvider{&stub{err: errors.NewUnauthorized("token expired")}, &stub{token: "ok", user: &models.UserInfo{Username: "foo"}}},
+			token:  "ok",
+			authed: true,
+		},
+		{
+			name:   "client errors are returned if nobody authenticates",
+			mode:   FailOnError,
+			chain:  []provider.ContextProvider{&stub{err: errors.NewUnauthorized("token expired")}, &stub{token: "ok", user: &models.UserInfo{Username: "foo"}}},
+			token:  "invalid",
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		var members []Member
+		for i, prv := range tt.chain {
+			members = append(members, Member{Name: fmt.Sprintf("provider-%d", i), Provider: prv})
+		}
+
+		p, err := NewProvider(Config{Providers: members, Mode: tt.mode})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		trr, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: tt.token})
+		if status := errors.ReasonForError(err); status != tt.status {
+			t.Errorf("%s: expected status %d, got %d (%v)", tt.name, tt.status, status, err)
+			continue
+		}
+		if err == nil && trr.Status.Authenticated != tt.authed {
+			t.Errorf("%s: expected authenticated %v", tt.name, tt.authed)
+		}
+	}
+}
+
+func TestNewProviderInvalidConfig(t *testing.T) {
+	for _, cfg := range []Config{
+		{},
+		{Providers: []Member{{Name: "fake"}}},
+		{Providers: []Member{{Provider: provider.NewContextAdapter(fake.NewFakeProvider())}}},
+		{Providers: []Member{{Name: "fake", Provider: provider.NewContextAdapter(fake.NewFakeProvider())}, {Name: "fake", Provider: provider.NewContextAdapter(fake.NewFakeProvider())}}},
+	} {
+		if _, err := NewProvider(cfg); err == nil {
+			t.Errorf("expected config %+v to be invalid", cfg)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package webhook implements a provider delegating logins and authentication to another webhook
+// implementing the Kubernetes TokenReview contract, e.g. another authproxy.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultAPIVersion is the apiVersion of TokenReviews sent to the webhook if none is configured
+	DefaultAPIVersion = "authentication.k8s.io/v1beta1"
+	// DefaultTimeout is the timeout of requests to the webhook if none is configured
+	DefaultTimeout = 10 * time.Second
+
+	// maxResponseSize limits the size of responses read from the webhook
+	maxResponseSize = 1 << 20
+)
+
+// Config represents the webhook provider configuration
+type Config struct {
+	// URL of the webhook TokenReviews are posted to, e.g. https://authproxy:6660/v1/authenticate
+	URL string
+	// LoginURL receives logins with HTTP basic auth like /v1/login of authproxy, logins are rejected if unset
+	LoginURL string
+	// TLSCert and TLSKey hold the client certificate presented to the webhook (optional)
+	TLSCert string
+	TLSKey  string
+	// TLSCA holds the certificates the webhook is verified with, the system roots are used if unset
+	TLSCA string
+	// APIVersion of the TokenReviews sent to the webhook (default: authentication.k8s.io/v1beta1)
+	APIVersion string
+	// Timeout of requests to the webhook (default: 10s)
+	Timeout time.Duration
+	// HTTPClient is used for requests instead of a client built from the TLS configuration (optional)
+	HTTPClient *http.Client
+}
+
+// Provider implements provider.ContextProvider by calling a webhook
+type Provider struct {
+	config Config
+	client *http.Client
+}
+
+// NewProvider returns a new provider for the webhook of the config
+func NewProvider(cfg Config) (*Provider, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("no webhook url configured")
+	}
+	if cfg.APIVersion == "" {
+		cfg.APIVersion = DefaultAPIVersion
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	client := cfg.HTTPClient
+	if client == nil {
+		tlsConfig, err := clientTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		client = &http.Client{Transport: transport, Timeout: cfg.Timeout}
+	}
+
+	return &Provider{config: cfg, client: client}, nil
+}
+
+// Login forwards the credentials to the login url of the webhook
+func (p *Provider) Login(ctx context.Context, login provider.LoginRequest) (*models.TokenReviewRequest, error) {
+	if p.config.LoginURL == "" {
+		return provider.Unauthenticated(), nil
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.config.LoginURL, nil)
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+	req.SetBasicAuth(login.Username, login.Password)
+
+	return p.do(ctx, req, login.Metadata)
+}
+
+// Authenticate posts a TokenReview for the bearer token and audiences to the webhook
+func (p *Provider) Authenticate(ctx context.Context, auth provider.AuthenticateRequest) (*models.TokenReviewRequest, error) {
+	body, err := json.Marshal(&models.TokenReviewRequest{
+		APIVersion: p.config.APIVersion,
+		Kind:       "TokenReview",
+		Spec:       &models.TokenReviewSpec{Token: auth.Token, Audiences: auth.Audiences},
+	})
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return p.do(ctx, req, auth.Metadata)
+}
+
+// do sends the request and maps the response of the webhook to a TokenReview or a typed error.
+// The request id of the client request is passed on to correlate the logs of both services
+func (p *Provider) do(ctx context.Context, req *http.Request, md provider.RequestMetadata) (*models.TokenReviewRequest, error) {
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if md.RequestID != "" {
+		req.Header.Set("X-Request-Id", md.RequestID)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, errors.NewServiceUnavailable(fmt.Errorf("webhook request failed: %v", err))
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return nil, errors.NewServiceUnavailable(fmt.Errorf("failed to read webhook response: %v", err))
+	}
+
+	var trr models.TokenReviewRequest
+	decodeErr := json.Unmarshal(body, &trr)
+
+	// the webhook's reason is passed on for errors clients may see
+	reason := http.StatusText(res.StatusCode)
+	if decodeErr == nil && trr.Status != nil && trr.Status.Error != "" {
+		reason = trr.Status.Error
+	}
+
+	switch {
+	case res.StatusCode == http.StatusOK:
+	case res.StatusCode == http.StatusBadRequest:
+		return nil, errors.NewBadRequest(reason)
+	case res.StatusCode == http.StatusUnauthorized:
+		return nil, errors.NewUnauthorized(reason)
+	case res.StatusCode == http.StatusForbidden:
+		return nil, errors.NewForbidden(reason)
+	case res.StatusCode == http.StatusTooManyRequests:
+		seconds, _ := strconv.Atoi(res.Header.Get("Retry-After"))
+		return nil, errors.NewTooManyRequests(reason, seconds)
+	case res.StatusCode == http.StatusBadGateway, res.StatusCode == http.StatusServiceUnavailable, res.StatusCode == http.StatusGatewayTimeout:
+		return nil, errors.NewServiceUnavailable(fmt.Errorf("webhook returned %s", res.Status))
+	default:
+		return nil, errors.NewInternalError(fmt.Errorf("webhook returned %s", res.Status))
+	}
+
+	if decodeErr != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("failed to decode webhook response: %v", decodeErr))
+	}
+	if trr.Status == nil {
+		return nil, errors.NewInternalError(fmt.Errorf("webhook response has no status"))
+	}
+
+	return &trr, nil
+}
+
+// clientTLSConfig returns the tls config verifying the webhook and presenting the client certificate, if any
+func clientTLSConfig(cfg Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.TLSCA != "" {
+		pem, err := ioutil.ReadFile(cfg.TLSCA)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA file: %v", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("failed to parse CA file %s", cfg.TLSCA)
+		}
+		tlsConfig.RootCAs = roots
+	}
+
+	if cfg.TLSCert != "" || cfg.TLSKey != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing tls certificate file: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
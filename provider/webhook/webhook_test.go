@@ -0,0 +1,267 @@
+/*
+ * Copyright 2019, authproxy authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package webhook
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/cbrgm/authproxy/api"
+	"github.com/cbrgm/authproxy/api/errors"
+	"github.com/cbrgm/authproxy/api/v1/models"
+	"github.com/cbrgm/authproxy/provider"
+	"github.com/cbrgm/authproxy/provider/fake"
+	"github.com/go-kit/kit/log"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAuthproxy delegates to another authproxy requiring client certificates
+func TestAuthproxy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webhook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	clientCert := writeClientCertificate(t, dir)
+
+	router, err := api.NewV1(provider.NewContextAdapter(fake.NewFakeProvider()), api.Config{}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(router)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	serverCA := filepath.Join(dir, "ca.crt")
+	writePEM(t, serverCA, "CERTIFICATE", server.Certificate().Raw)
+
+	p, err := NewProvider(Config{
+		URL:      server.URL + "/v1/authenticate",
+		LoginURL: server.URL + "/v1/login",
+		TLSCert:  filepath.Join(dir, "client.crt"),
+		TLSKey:   filepath.Join(dir, "client.key"),
+		TLSCA:    serverCA,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login, err := p.Login(context.Background(), provider.LoginRequest{Username: "foo", Password: "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !login.Status.Authenticated || login.Spec == nil || login.Spec.Token == "" {
+		t.Errorf("expected login to return a token, got %+v", login)
+	}
+
+	denied, err := p.Login(context.Background(), provider.LoginRequest{Username: "foo", Password: "wrong"})
+	if err == nil && denied.Status.Authenticated {
+		t.Error("expected wrong password to be rejected")
+	}
+
+	auth, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "AbCdEf123456"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !auth.Status.Authenticated {
+		t.Error("expected token to be authenticated")
+	}
+
+	invalid, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "invalid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invalid.Status.Authenticated {
+		t.Error("expected invalid token to be rejected")
+	}
+
+	// the webhook rejects clients without certificate
+	anonymous, err := NewProvider(Config{URL: server.URL + "/v1/authenticate", TLSCA: serverCA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := anonymous.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "AbCdEf123456"}); !errors.IsServiceUnavailable(err) {
+		t.Errorf("expected request without client certificate to fail, got %v", err)
+	}
+}
+
+func TestErrorMapping(t *testing.T) {
+	tests := []struct {
+		status     int
+		body       string
+		want       int
+		reason     string
+		retryAfter int
+	}{
+		{status: http.StatusOK, body: `{"status":{"authenticated":true,"user":{"username":"foo"}}}`},
+		{status: http.StatusOK, body: `not json`, want: http.StatusInternalServerError},
+		{status: http.StatusOK, body: `{}`, want: http.StatusInternalServerError},
+		{status: http.StatusBadRequest, body: `{"status":{"error":"malformed token"}}`, want: http.StatusBadRequest, reason: "malformed token"},
+		{status: http.StatusUnauthorized, body: `{"status":{"error":"token expired"}}`, want: http.StatusUnauthorized, reason: "token expired"},
+		{status: http.StatusForbidden, body: ``, want: http.StatusForbidden, reason: "Forbidden"},
+		{status: http.StatusTooManyRequests, body: `{"status":{"error":"slow down"}}`, want: http.StatusTooManyRequests, reason: "slow down", retryAfter: 5},
+		{status: http.StatusBadGateway, want: http.StatusServiceUnavailable},
+		{status: http.StatusInternalServerError, want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "5")
+			w.WriteHeader(tt.status)
+			w.Write([]byte(tt.body))
+		}))
+
+		p, err := NewProvider(Config{URL: server.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		trr, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{Token: "token"})
+		server.Close()
+
+		if status := errors.ReasonForError(err); status != tt.want {
+			t.Errorf("%d %s: expected error status %d, got %d (%v)", tt.status, tt.body, tt.want, status, err)
+			continue
+		}
+		if err == nil {
+			if !trr.Status.Authenticated || trr.Status.User.Username != "foo" {
+				t.Errorf("unexpected status %+v", trr.Status)
+			}
+			continue
+		}
+		if tt.reason != "" && errors.SafeMessage(err) != tt.reason {
+			t.Errorf("%d: expected reason %q, got %q", tt.status, tt.reason, errors.SafeMessage(err))
+		}
+		if seconds := errors.RetryAfterSeconds(err); seconds != tt.retryAfter {
+			t.Errorf("%d: expected retry after %d, got %d", tt.status, tt.retryAfter, seconds)
+		}
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var trr models.TokenReviewRequest
+		_ = json.NewDecoder(r.Body).Decode(&trr)
+		if trr.Spec.Token == "slow" {
+			<-release
+		}
+		w.Write([]byte(`{"status":{"authenticated":true,"user":{"username":"` + r.Header.Get("X-Request-Id") + `"},"audiences":["` + strings.Join(trr.Spec.Audiences, `","`) + `"]}}`))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	p, err := NewProvider(Config{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trr, err := p.Authenticate(context.Background(), provider.AuthenticateRequest{
+		Token:     "token",
+		Audiences: []string{"api"},
+		Metadata:  provider.RequestMetadata{RequestID: "abc"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trr.Status.User.Username != "abc" || !reflect.DeepEqual(trr.Status.Audiences, []string{"api"}) {
+		t.Errorf("expected request id and audiences to be sent to the webhook, got %+v", trr.Status)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := p.Authenticate(ctx, provider.AuthenticateRequest{Token: "slow"}); !errors.IsServiceUnavailable(err) {
+		t.Errorf("expected cancelled request to fail with service unavailable, got %v", err)
+	}
+}
+
+func TestLoginWithoutLoginURL(t *testing.T) {
+	p, err := NewProvider(Config{URL: "https://authproxy.example.com/v1/authenticate"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trr, err := p.Login(context.Background(), provider.LoginRequest{Username: "foo", Password: "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trr.Status.Authenticated {
+		t.Error("expected login to be rejected without login url")
+	}
+}
+
+// writeClientCertificate writes a self-signed client.crt and client.key to dir
+func writeClientCertificate(t *testing.T, dir string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "authproxy"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writePEM(t, filepath.Join(dir, "client.crt"), "CERTIFICATE", der)
+	writePEM(t, filepath.Join(dir, "client.key"), "EC PRIVATE KEY", keyDER)
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}